@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/r3volut1oner/go-karbo/config"
+	"github.com/r3volut1oner/go-karbo/cryptonote"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+)
+
+// bootstrapProgressInterval is the number of the blocks between the progress messages
+const bootstrapProgressInterval = 1000
+
+var exportTo uint32
+
+var importTrusted bool
+
+// exportCmd saves the main chain blocks to the bootstrap files
+var exportCmd = &cobra.Command{
+	Use:   "export <dir>",
+	Short: "Export blockchain to the blocks.dat and blockindexes.dat files.",
+	Long: `Export blockchain to the blocks.dat and blockindexes.dat files in the directory.
+Files have the format of the C++ implementation. Export continues when the files already exist.`,
+	Args:         cobra.ExactArgs(1),
+	RunE:         handleExportCommand,
+	SilenceUsage: true,
+}
+
+// importCmd adds the blocks from the bootstrap files to the blockchain
+var importCmd = &cobra.Command{
+	Use:   "import <dir>",
+	Short: "Import blockchain from the blocks.dat and blockindexes.dat files.",
+	Long: `Import blockchain from the blocks.dat and blockindexes.dat files in the directory.
+Blocks are validated as received from the network, import continues after the blocks already in the blockchain.`,
+	Args:         cobra.ExactArgs(1),
+	RunE:         handleImportCommand,
+	SilenceUsage: true,
+}
+
+func init() {
+	exportCmd.Flags().Uint32Var(&exportTo, "to", 0, "index of the last exported block (default is the top block)")
+	importCmd.Flags().BoolVar(&importTrusted, "trusted", false, "trust the files: last block is used as the checkpoint, so proof of work and signatures are not checked")
+
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+}
+
+func handleExportCommand(cmd *cobra.Command, args []string) error {
+	bc, storage, err := openBlockchain(config.MainNet(), bootstrapLogger())
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	to := bc.TopBlock().Index()
+	if cmd.Flags().Changed("to") {
+		if exportTo > to {
+			return fmt.Errorf("block %d is above the top block %d", exportTo, to)
+		}
+
+		to = exportTo
+	}
+
+	b, err := cryptonote.CreateBootstrap(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open bootstrap files: %w", err)
+	}
+	defer b.Close()
+
+	if b.Count() > 0 {
+		fmt.Printf("Continuing export after %d blocks.\n", b.Count())
+	}
+
+	err = bc.ExportBlocks(interruptListener(), b, to, func(index uint32) {
+		printBootstrapProgress("Exported", index, to)
+	})
+
+	return finishBootstrap("export", b.Count(), err)
+}
+
+func handleImportCommand(cmd *cobra.Command, args []string) error {
+	bc, storage, err := openBlockchain(config.MainNet(), bootstrapLogger())
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	b, err := cryptonote.OpenBootstrap(args[0])
+	if err != nil {
+		return fmt.Errorf("failed to open bootstrap files: %w", err)
+	}
+	defer b.Close()
+
+	if b.Count() == 0 {
+		return errors.New("bootstrap files have no blocks")
+	}
+
+	last := b.Count() - 1
+
+	if importTrusted {
+		block, _, err := b.ReadBlock(last)
+		if err != nil {
+			return fmt.Errorf("failed to read last block: %w", err)
+		}
+
+		err = bc.Checkpoints.AddCheckpoints([]config.Checkpoint{{Index: last, Hash: *block.Hash()}})
+		if err != nil {
+			return err
+		}
+	}
+
+	if height := bc.Height(); height > 1 {
+		fmt.Printf("Continuing import after %d blocks.\n", height)
+	}
+
+	err = bc.ImportBlocks(interruptListener(), b, func(index uint32) {
+		printBootstrapProgress("Imported", index, last)
+	})
+
+	return finishBootstrap("import", bc.Height(), err)
+}
+
+// bootstrapLogger returns logger showing only the problems, so the progress is visible
+func bootstrapLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.Out = os.Stdout
+	logger.Level = logrus.WarnLevel
+
+	return logger
+}
+
+func printBootstrapProgress(action string, index, last uint32) {
+	if index%bootstrapProgressInterval == 0 || index == last {
+		fmt.Printf("%s block %d of %d.\n", action, index, last)
+	}
+}
+
+// finishBootstrap reports the result of the export or import, interrupted one is continued on the next run
+func finishBootstrap(action string, count uint32, err error) error {
+	if errors.Is(err, context.Canceled) {
+		fmt.Printf("Interrupted after %d blocks, run %s again to continue.\n", count, action)
+		return nil
+	}
+
+	if err != nil {
+		return fmt.Errorf("%s failed: %w", action, err)
+	}
+
+	fmt.Printf("Finished %s, %d blocks.\n", action, count)
+
+	return nil
+}
@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/r3volut1oner/go-karbo/config"
+	"github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"os"
+	"strconv"
+)
+
+// rollbackCmd removes the main chain blocks above the index, so they are downloaded again on the next start
+var rollbackCmd = &cobra.Command{
+	Use:   "rollback <index>",
+	Short: "Remove blocks above the index from the blockchain.",
+	Long: `Remove blocks above the index from the blockchain.
+Removed blocks are downloaded and validated again on the next node start.`,
+	Args:         cobra.ExactArgs(1),
+	RunE:         handleRollbackCommand,
+	SilenceUsage: true,
+}
+
+func init() {
+	rootCmd.AddCommand(rollbackCmd)
+}
+
+func handleRollbackCommand(cmd *cobra.Command, args []string) error {
+	index, err := strconv.ParseUint(args[0], 10, 32)
+	if err != nil {
+		return fmt.Errorf("failed to parse block index: %w", err)
+	}
+
+	logger := logrus.New()
+	logger.Out = os.Stdout
+	logger.Level = logrus.WarnLevel
+
+	bc, storage, err := openBlockchain(config.MainNet(), logger)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	topIndex := bc.TopBlock().Index()
+	if err := bc.Rollback(uint32(index)); err != nil {
+		return fmt.Errorf("failed to rollback from block %d: %w", topIndex, err)
+	}
+
+	fmt.Printf("Removed %d blocks, top block is %d.\n", topIndex-uint32(index), index)
+
+	return nil
+}
@@ -2,22 +2,46 @@ package cmd
 
 import (
 	"context"
+	"encoding/hex"
+	"errors"
 	"fmt"
 	"github.com/r3volut1oner/go-karbo/config"
+	"github.com/r3volut1oner/go-karbo/crypto"
 	"github.com/r3volut1oner/go-karbo/cryptonote"
 	"github.com/r3volut1oner/go-karbo/p2p"
+	"github.com/r3volut1oner/go-karbo/rpc"
 	"github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 	"go.uber.org/zap"
+	"net"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"time"
 
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/viper"
 )
 
+// checkpointsDNSTimeout limits resolving of the DNS checkpoints on start
+const checkpointsDNSTimeout = 10 * time.Second
+
 var cfgFile string
 
+var dataDir string
+
+var rpcBindAddr string
+
+var trustedKey string
+
+var maxIncoming int
+
+var maxIncomingPerIP int
+
+var checkpointsFile string
+
+var checkpointsDNS []string
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:     "krbd",
@@ -25,6 +49,9 @@ var rootCmd = &cobra.Command{
 	Long:    `Karbo node daemon.`,
 	Version: "0.0.1",
 	Run:     handleCommand,
+
+	// Errors are printed by Execute
+	SilenceErrors: true,
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
@@ -44,6 +71,13 @@ func init() {
 	// will be global for your application.
 
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is $HOME/.krbd.yml)")
+	rootCmd.PersistentFlags().StringVar(&dataDir, "datadir", "", "blockchain data directory (default is $HOME/.krbd)")
+	rootCmd.PersistentFlags().StringVar(&rpcBindAddr, "rpc-bind", "127.0.0.1:32348", "address for listening RPC requests")
+	rootCmd.PersistentFlags().StringVar(&trustedKey, "p2p-trusted-key", "", "hex public key allowed to query node statistics")
+	rootCmd.PersistentFlags().IntVar(&maxIncoming, "p2p-max-incoming", p2p.DefaultIncomingConnectionsCount, "max number of incoming connections")
+	rootCmd.PersistentFlags().IntVar(&maxIncomingPerIP, "p2p-max-incoming-per-ip", p2p.DefaultIncomingConnectionsPerIP, "max number of incoming connections from the same IP")
+	rootCmd.PersistentFlags().StringVar(&checkpointsFile, "checkpoints-file", "", "CSV file with the \"index,hash\" checkpoints")
+	rootCmd.PersistentFlags().StringSliceVar(&checkpointsDNS, "checkpoints-dns", nil, "domains with the \"index:hash\" TXT records of the checkpoints")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -79,23 +113,34 @@ func initConfig() {
 func handleCommand(cmd *cobra.Command, args []string) {
 	mainnet := config.MainNet()
 
-	// Initialize blockchain storage
-	storage := cryptonote.NewMemoryStorage()
-
 	logrusLogger := logrus.New()
 	logrusLogger.Out = os.Stdout
 	logrusLogger.Level = logrus.TraceLevel
 
-	bc := cryptonote.NewBlockChain(mainnet, storage, logrusLogger)
-
-	if err := bc.Init(); err != nil {
-		panic(fmt.Errorf("failed to init blockchain: %w", err))
+	bc, storage, err := openBlockchain(mainnet, logrusLogger)
+	if err != nil {
+		panic(err)
 	}
+	defer storage.Close()
 
 	ctx := interruptListener()
 	cfg := p2p.HostConfig{
-		BindAddr: "127.0.0.1:32447",
-		Network:  mainnet,
+		BindAddr:      "127.0.0.1:32447",
+		Network:       mainnet,
+		PeerStorePath: filepath.Join(dataDirPath(), "p2pstate.bin"),
+		Version:       cmd.Root().Version,
+
+		MaxIncomingConnections:      maxIncoming,
+		MaxIncomingConnectionsPerIP: maxIncomingPerIP,
+	}
+
+	if trustedKey != "" {
+		key, err := parsePublicKey(trustedKey)
+		if err != nil {
+			panic(fmt.Errorf("failed to parse trusted key: %w", err))
+		}
+
+		cfg.TrustedPublicKey = key
 	}
 
 	zapLogger, err := zap.NewDevelopment()
@@ -103,7 +148,16 @@ func handleCommand(cmd *cobra.Command, args []string) {
 		panic(err)
 	}
 
-	host := p2p.NewNode(bc, cfg, zapLogger)
+	pool := cryptonote.NewTxPool(bc, logrusLogger)
+
+	host := p2p.NewNode(bc, pool, cfg, zapLogger)
+
+	rpcServer := rpc.NewServer(bc, pool, &host, zapLogger)
+	go func() {
+		if err := rpcServer.Run(ctx, rpcBindAddr); err != nil {
+			zapLogger.Sugar().Errorf("rpc server failed: %s", err)
+		}
+	}()
 
 	fmt.Println("Server started.")
 
@@ -114,6 +168,101 @@ func handleCommand(cmd *cobra.Command, args []string) {
 	fmt.Println("Server stopped.")
 }
 
+// openBlockchain opens the blockchain saved in the data directory, storage must be closed by the caller
+func openBlockchain(network *config.Network, logger *logrus.Logger) (*cryptonote.BlockChain, cryptonote.Storage, error) {
+	storage, err := cryptonote.NewBadgerStorage(filepath.Join(dataDirPath(), "blockchain"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open blockchain storage: %w", err)
+	}
+
+	bc := cryptonote.NewBlockChain(network, storage, logger)
+
+	if err := loadCheckpoints(bc, logger); err != nil {
+		storage.Close()
+		return nil, nil, err
+	}
+
+	if err := bc.Init(); err != nil {
+		storage.Close()
+		return nil, nil, fmt.Errorf("failed to init blockchain: %w", err)
+	}
+
+	return bc, storage, nil
+}
+
+// loadCheckpoints adds the network, file and DNS checkpoints to the blockchain.
+// DNS is not trusted as the file, so unavailable domains and conflicting records are only logged.
+func loadCheckpoints(bc *cryptonote.BlockChain, logger *logrus.Logger) error {
+	if err := bc.Checkpoints.AddCheckpoints(bc.Network.Checkpoints()); err != nil {
+		return fmt.Errorf("failed to add network checkpoints: %w", err)
+	}
+
+	if checkpointsFile != "" {
+		list, err := config.LoadCheckpointsFile(checkpointsFile)
+		if err != nil {
+			return fmt.Errorf("failed to load checkpoints file: %w", err)
+		}
+
+		if err := bc.Checkpoints.AddCheckpoints(list); err != nil {
+			return fmt.Errorf("failed to add checkpoints from file: %w", err)
+		}
+	}
+
+	if len(checkpointsDNS) > 0 {
+		ctx, cancel := context.WithTimeout(context.Background(), checkpointsDNSTimeout)
+		defer cancel()
+
+		list, err := config.ResolveCheckpoints(ctx, net.DefaultResolver, checkpointsDNS)
+		if err != nil {
+			logger.Warnf("failed to resolve checkpoints: %s", err)
+		}
+
+		for _, checkpoint := range list {
+			if err := bc.Checkpoints.AddCheckpoints([]config.Checkpoint{checkpoint}); err != nil {
+				logger.Warnf("skipped DNS checkpoint %d: %s", checkpoint.Index, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// dataDirPath returns directory for keeping node data
+func dataDirPath() string {
+	if dataDir != "" {
+		return dataDir
+	}
+
+	home, err := homedir.Dir()
+	if err != nil {
+		fmt.Println(err)
+		os.Exit(1)
+	}
+
+	return filepath.Join(home, ".krbd")
+}
+
+// parsePublicKey decodes public key from the hex string
+func parsePublicKey(s string) (*crypto.PublicKey, error) {
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+
+	var key crypto.PublicKey
+	if len(b) != len(key) {
+		return nil, fmt.Errorf("wrong key length %d", len(b))
+	}
+
+	copy(key[:], b)
+
+	if !key.Check() {
+		return nil, errors.New("key is not a valid point")
+	}
+
+	return &key, nil
+}
+
 func interruptListener() context.Context {
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -14,12 +14,23 @@ var (
 	ErrCheckpointsTooDeepReorg        = errors.New("checkpoints too deep reorganisation")
 	ErrCheckpointsAltBeforeCheckpoint = errors.New("checkpoints alternative block before checkpoint")
 	ErrCheckpointsAltBlockGenesis     = errors.New("checkpoints alternative genesis block not allowed")
+	ErrCheckpointsConflict            = errors.New("checkpoints conflict with existing checkpoint")
 )
 
+// Checkpoint is the hash of the block on the index in the main chain
+type Checkpoint struct {
+	Index uint32
+	Hash  crypto.Hash
+}
+
 type Checkpoints interface {
 	// AddCheckpoint to the list of all the checkpoints
 	AddCheckpoint(index uint32, hash crypto.Hash) error
 
+	// AddCheckpoints adds the list of the checkpoints, checkpoints equal to the existing ones are skipped.
+	// Nothing is added when any checkpoint conflicts with the existing one.
+	AddCheckpoints(list []Checkpoint) error
+
 	// IsInCheckpointZone checks if index in in checkpoints zone
 	IsInCheckpointZone(index uint32) bool
 
@@ -72,10 +83,51 @@ func (cp *checkpoints) AddCheckpoint(index uint32, hash crypto.Hash) error {
 	return nil
 }
 
+func (cp *checkpoints) AddCheckpoints(list []Checkpoint) error {
+	cp.Lock()
+	defer cp.Unlock()
+
+	added := map[uint32]crypto.Hash{}
+	for i := range list {
+		hash, ok := cp.points[list[i].Index]
+		if !ok {
+			hash, ok = added[list[i].Index]
+		}
+
+		if ok && hash != list[i].Hash {
+			err := ErrCheckpointsConflict
+			cp.prepareLogger(list[i].Index, &list[i].Hash).WithFields(log.Fields{
+				"checkpoint_correct_hash": hash,
+			}).Error(err)
+			return err
+		}
+
+		if !ok {
+			added[list[i].Index] = list[i].Hash
+		}
+	}
+
+	for index, hash := range added {
+		cp.points[index] = hash
+		cp.pointsSorted = append(cp.pointsSorted, index)
+	}
+
+	sort.Slice(cp.pointsSorted, func(i, j int) bool {
+		return cp.pointsSorted[i] < cp.pointsSorted[j]
+	})
+
+	return nil
+}
+
 func (cp *checkpoints) IsInCheckpointZone(index uint32) bool {
 	cp.Lock()
 	defer cp.Unlock()
 
+	return cp.isInCheckpointZone(index)
+}
+
+// isInCheckpointZone is not safe for concurrent access version of IsInCheckpointZone
+func (cp *checkpoints) isInCheckpointZone(index uint32) bool {
 	// No checkpoints added
 	if len(cp.pointsSorted) == 0 {
 		return false
@@ -120,7 +172,7 @@ func (cp *checkpoints) AlternativeBlockAllowed(bcSize uint32, index uint32) erro
 	}
 
 	uw := MinedMoneyUnlockWindow
-	if index < bcSize-uw && bcSize > uw && !cp.IsInCheckpointZone(index) {
+	if index < bcSize-uw && bcSize > uw && !cp.isInCheckpointZone(index) {
 		err := ErrCheckpointsTooDeepReorg
 		logger.Error(err)
 		return err
@@ -0,0 +1,120 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrCheckpointsMalformed  = errors.New("checkpoints malformed checkpoint")
+	ErrCheckpointsDNSFailure = errors.New("checkpoints no domain resolved")
+)
+
+// TXTResolver looks up the TXT records of the domain, *net.Resolver satisfies it
+type TXTResolver interface {
+	LookupTXT(ctx context.Context, name string) ([]string, error)
+}
+
+// ReadCheckpointsCSV reads the checkpoints from the "index,hash" lines, empty lines are skipped
+func ReadCheckpointsCSV(r io.Reader) ([]Checkpoint, error) {
+	var list []Checkpoint
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+
+		parts := strings.Split(text, ",")
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("line %d: %w", line, ErrCheckpointsMalformed)
+		}
+
+		checkpoint, err := parseCheckpoint(parts[0], parts[1])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+
+		list = append(list, checkpoint)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return list, nil
+}
+
+// LoadCheckpointsFile reads the checkpoints from the CSV file
+func LoadCheckpointsFile(path string) ([]Checkpoint, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return ReadCheckpointsCSV(f)
+}
+
+// ResolveCheckpoints reads the checkpoints from the "index:hash" TXT records of the domains.
+// Other records of the domain are skipped, error is returned only when none of the domains is resolved.
+func ResolveCheckpoints(ctx context.Context, resolver TXTResolver, domains []string) ([]Checkpoint, error) {
+	var list []Checkpoint
+	resolved := false
+
+	for _, domain := range domains {
+		records, err := resolver.LookupTXT(ctx, domain)
+		if err != nil {
+			continue
+		}
+
+		resolved = true
+
+		for _, record := range records {
+			parts := strings.Split(strings.TrimSpace(record), ":")
+			if len(parts) != 2 {
+				continue
+			}
+
+			checkpoint, err := parseCheckpoint(parts[0], parts[1])
+			if err != nil {
+				continue
+			}
+
+			list = append(list, checkpoint)
+		}
+	}
+
+	if !resolved && len(domains) > 0 {
+		return nil, ErrCheckpointsDNSFailure
+	}
+
+	return list, nil
+}
+
+// parseCheckpoint decodes decimal index and hex hash of the checkpoint
+func parseCheckpoint(index, hash string) (Checkpoint, error) {
+	i, err := strconv.ParseUint(strings.TrimSpace(index), 10, 32)
+	if err != nil {
+		return Checkpoint{}, ErrCheckpointsMalformed
+	}
+
+	checkpoint := Checkpoint{Index: uint32(i)}
+
+	b, err := hex.DecodeString(strings.TrimSpace(hash))
+	if err != nil || len(b) != len(checkpoint.Hash) {
+		return Checkpoint{}, ErrCheckpointsMalformed
+	}
+
+	copy(checkpoint.Hash[:], b)
+
+	return checkpoint, nil
+}
@@ -0,0 +1,110 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"github.com/r3volut1oner/go-karbo/crypto"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const (
+	testCheckpointHash1 = "93fd06c51fd8a6fc9db100adbdb4c1de11270a5186b790b454db8a7419c5615e"
+	testCheckpointHash2 = "4cab277ce1d96569e6ec406c589f08468a490aafd729fccae3b46c7ba4cce3a7"
+)
+
+type testTXTResolver map[string][]string
+
+func (r testTXTResolver) LookupTXT(_ context.Context, name string) ([]string, error) {
+	records, ok := r[name]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+
+	return records, nil
+}
+
+func TestReadCheckpointsCSV(t *testing.T) {
+	list, err := ReadCheckpointsCSV(strings.NewReader("1," + testCheckpointHash1 + "\n\n 60000 , " + testCheckpointHash2 + " \n"))
+	assert.Nil(t, err)
+	assert.Equal(t, []Checkpoint{
+		{Index: 1, Hash: testCheckpointHashFromString(testCheckpointHash1)},
+		{Index: 60000, Hash: testCheckpointHashFromString(testCheckpointHash2)},
+	}, list)
+
+	for _, payload := range []string{
+		"1",
+		"1," + testCheckpointHash1 + ",2",
+		"-1," + testCheckpointHash1,
+		"4294967296," + testCheckpointHash1,
+		"1," + testCheckpointHash1[2:],
+		"1,zz" + testCheckpointHash1[2:],
+	} {
+		_, err := ReadCheckpointsCSV(strings.NewReader("1," + testCheckpointHash1 + "\n" + payload))
+		assert.True(t, errors.Is(err, ErrCheckpointsMalformed), payload)
+		assert.Contains(t, err.Error(), "line 2")
+	}
+}
+
+func TestLoadCheckpointsFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "checkpoints")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "checkpoints.csv")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("1,"+testCheckpointHash1+"\n"), 0644))
+
+	list, err := LoadCheckpointsFile(path)
+	assert.Nil(t, err)
+	assert.Equal(t, []Checkpoint{{Index: 1, Hash: testCheckpointHashFromString(testCheckpointHash1)}}, list)
+
+	_, err = LoadCheckpointsFile(filepath.Join(dir, "missing.csv"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestResolveCheckpoints(t *testing.T) {
+	resolver := testTXTResolver{
+		"checkpoints1.test": {"1:" + testCheckpointHash1, "v=spf1 -all", "2:" + testCheckpointHash1[2:]},
+		"checkpoints2.test": {" 60000:" + testCheckpointHash2 + " "},
+	}
+	ctx := context.Background()
+
+	list, err := ResolveCheckpoints(ctx, resolver, []string{"missing.test", "checkpoints1.test", "checkpoints2.test"})
+	assert.Nil(t, err)
+	assert.Equal(t, []Checkpoint{
+		{Index: 1, Hash: testCheckpointHashFromString(testCheckpointHash1)},
+		{Index: 60000, Hash: testCheckpointHashFromString(testCheckpointHash2)},
+	}, list)
+
+	_, err = ResolveCheckpoints(ctx, resolver, []string{"missing.test"})
+	assert.Equal(t, ErrCheckpointsDNSFailure, err)
+
+	list, err = ResolveCheckpoints(ctx, resolver, nil)
+	assert.Nil(t, err)
+	assert.Empty(t, list)
+}
+
+func TestNetwork_Checkpoints(t *testing.T) {
+	checkpoints := MainNet().Checkpoints()
+	assert.NotEmpty(t, checkpoints)
+	assert.Empty(t, TestNet().Checkpoints())
+
+	cp := NewCheckpoints(loggerDevNull())
+	assert.Nil(t, cp.AddCheckpoints(checkpoints))
+	assert.True(t, cp.IsInCheckpointZone(checkpoints[len(checkpoints)-1].Index))
+	assert.Nil(t, cp.CheckBlock(1, &checkpoints[0].Hash))
+	assert.Equal(t, testCheckpointHashFromString(testCheckpointHash1), checkpoints[0].Hash)
+}
+
+func testCheckpointHashFromString(s string) crypto.Hash {
+	checkpoint, err := parseCheckpoint("0", s)
+	if err != nil {
+		panic(err)
+	}
+
+	return checkpoint.Hash
+}
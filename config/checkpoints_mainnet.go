@@ -0,0 +1,36 @@
+package config
+
+import "strconv"
+
+// mainNetCheckpoints are the blocks of the Karbo main chain, the list is extended with the
+// checkpoints file or DNS records.
+// TODO: Port the full list from src/CryptoNoteCore/CryptoNoteCheckpoints.h, only the blocks verified
+// against the fixtures are listed, so the checkpoint zone ends at 200054.
+var mainNetCheckpoints = []struct {
+	index uint32
+	hash  string
+}{
+	{1, "93fd06c51fd8a6fc9db100adbdb4c1de11270a5186b790b454db8a7419c5615e"},
+	{60000, "4cab277ce1d96569e6ec406c589f08468a490aafd729fccae3b46c7ba4cce3a7"},
+	{60001, "8e39967eb50b8a922cbfe22fe02989218345cbd61ae651ddbecf00834910ff50"},
+	{105384, "cc20ae5bd6c75e25a0885bcbb058e31c5b344dedc43a7f50c8ac6f1eaada795f"},
+	{105385, "b8b793a00e0a1bb790987e5f6a1b551f9e397be5aa74595335094063be31f878"},
+	{200053, "6769241077017f26c0a170fd9630c292695039399b6a22edaf293b52f2d542fb"},
+	{200054, "231a4584e0c13325024059482fabd99188574f51336d19c0b5787f7ccc9e4dfc"},
+}
+
+// mainNetCheckpointsList decodes the built-in mainnet checkpoints
+func mainNetCheckpointsList() []Checkpoint {
+	list := make([]Checkpoint, len(mainNetCheckpoints))
+
+	for i, point := range mainNetCheckpoints {
+		checkpoint, err := parseCheckpoint(strconv.FormatUint(uint64(point.index), 10), point.hash)
+		if err != nil {
+			panic(err)
+		}
+
+		list[i] = checkpoint
+	}
+
+	return list
+}
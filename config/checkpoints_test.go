@@ -144,3 +144,19 @@ func TestCheckpoints_HandlesTwoCheckpoints(t *testing.T) {
 	assert.Nil(t, cp.AlternativeBlockAllowed(11, 10))
 	assert.Nil(t, cp.AlternativeBlockAllowed(11, 11))
 }
+
+func TestCheckpoints_AddCheckpoints(t *testing.T) {
+	cp := NewCheckpoints(loggerDevNull())
+	assert.Nil(t, cp.AddCheckpoint(5, crypto.Hash{5}))
+
+	assert.Equal(t, ErrCheckpointsConflict, cp.AddCheckpoints([]Checkpoint{{9, crypto.Hash{9}}, {5, crypto.Hash{1}}}))
+	assert.Equal(t, ErrCheckpointsConflict, cp.AddCheckpoints([]Checkpoint{{9, crypto.Hash{9}}, {9, crypto.Hash{1}}}))
+	assert.False(t, cp.IsInCheckpointZone(9))
+
+	assert.Nil(t, cp.AddCheckpoints([]Checkpoint{{9, crypto.Hash{9}}, {5, crypto.Hash{5}}, {9, crypto.Hash{9}}, {7, crypto.Hash{7}}}))
+	assert.True(t, cp.IsInCheckpointZone(9))
+	assert.False(t, cp.IsInCheckpointZone(10))
+	assert.Nil(t, cp.CheckBlock(7, &crypto.Hash{7}))
+	assert.Equal(t, ErrCheckpointsFailed, cp.CheckBlock(9, &crypto.Hash{7}))
+	assert.Equal(t, ErrCheckpointsAltBeforeCheckpoint, cp.AlternativeBlockAllowed(8, 7))
+}
@@ -48,6 +48,8 @@ type Network struct {
 
 	blockUpgradesMap map[byte]uint32
 
+	checkpoints []Checkpoint
+
 	allowLowDifficulty bool
 }
 
@@ -101,6 +103,8 @@ func MainNet() *Network {
 			BlockMajorVersion4: UpgradeHeightV4,
 			BlockMajorVersion5: UpgradeHeightV5,
 		},
+
+		checkpoints: mainNetCheckpointsList(),
 	}
 }
 
@@ -108,6 +112,7 @@ func TestNet() *Network {
 	testnet := MainNet()
 	testnet.GenesisNonce = 71
 	testnet.allowLowDifficulty = true
+	testnet.checkpoints = nil
 
 	return testnet
 }
@@ -117,6 +122,11 @@ func (n *Network) Timestamp() uint64 {
 	return uint64(time.Now().Unix())
 }
 
+// Checkpoints built-in checkpoints of the network
+func (n *Network) Checkpoints() []Checkpoint {
+	return n.checkpoints
+}
+
 // MaxBlockSize max block size at specific blockchain height
 func (n *Network) MaxBlockSize(h uint64) uint64 {
 	// Code just copied from the C++ code
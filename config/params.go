@@ -39,6 +39,12 @@ const (
 
 	KeyImageCheckingBlockIndex = false
 
+	// MempoolTxLiveTime is the time in seconds the transaction is kept in the memory pool
+	MempoolTxLiveTime = uint64(60 * 60 * 24)
+
+	// MempoolTxFromAltBlockLiveTime is the time in seconds the transaction returned from the popped block is kept
+	MempoolTxFromAltBlockLiveTime = uint64(60 * 60 * 24 * 7)
+
 	blockFutureTimeLimit   = DifficultyTarget * 7
 	blockFutureTimeLimitV1 = DifficultyTarget * 3
 
@@ -0,0 +1,116 @@
+package crypto
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// BLAKE-256 (final SHA-3 round version, 14 rounds) used as one of the CryptoNight final hashes
+
+var blake256IV = [8]uint32{
+	0x6a09e667, 0xbb67ae85, 0x3c6ef372, 0xa54ff53a,
+	0x510e527f, 0x9b05688c, 0x1f83d9ab, 0x5be0cd19,
+}
+
+var blake256Constants = [16]uint32{
+	0x243f6a88, 0x85a308d3, 0x13198a2e, 0x03707344,
+	0xa4093822, 0x299f31d0, 0x082efa98, 0xec4e6c89,
+	0x452821e6, 0x38d01377, 0xbe5466cf, 0x34e90c6c,
+	0xc0ac29b7, 0xc97c50dd, 0x3f84d5b5, 0xb5470917,
+}
+
+var blakeSigma = [10][16]int{
+	{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	{14, 10, 4, 8, 9, 15, 13, 6, 1, 12, 0, 2, 11, 7, 5, 3},
+	{11, 8, 12, 0, 5, 2, 15, 13, 10, 14, 3, 6, 7, 1, 9, 4},
+	{7, 9, 3, 1, 13, 12, 11, 14, 2, 6, 5, 10, 4, 0, 15, 8},
+	{9, 0, 5, 7, 2, 4, 10, 15, 14, 1, 11, 12, 6, 8, 3, 13},
+	{2, 12, 6, 10, 0, 11, 8, 3, 4, 13, 7, 5, 15, 14, 1, 9},
+	{12, 5, 1, 15, 14, 13, 4, 10, 0, 7, 6, 3, 9, 2, 8, 11},
+	{13, 11, 7, 14, 12, 1, 3, 9, 5, 0, 15, 4, 8, 6, 2, 10},
+	{6, 15, 14, 9, 11, 3, 0, 8, 12, 2, 13, 7, 1, 4, 10, 5},
+	{10, 2, 8, 4, 7, 6, 1, 5, 15, 11, 9, 14, 3, 12, 13, 0},
+}
+
+func blake256(data []byte) Hash {
+	h := blake256IV
+
+	bitsLen := uint64(len(data)) * 8
+
+	// Padding: bit 1, zeros, bit 1 and message length as 64 bits big endian integer
+	padded := append([]byte{}, data...)
+	padded = append(padded, 0x80)
+	for len(padded)%64 != 56 {
+		padded = append(padded, 0)
+	}
+	padded[len(padded)-1] |= 0x01
+
+	var length [8]byte
+	binary.BigEndian.PutUint64(length[:], bitsLen)
+	padded = append(padded, length[:]...)
+
+	for offset := 0; offset < len(padded); offset += 64 {
+		// Counter contains message bits only, block without message bits uses zero counter
+		counter := uint64(offset+64) * 8
+		if counter > bitsLen {
+			counter = bitsLen
+		}
+
+		if uint64(offset)*8 >= bitsLen {
+			counter = 0
+		}
+
+		blake256Compress(&h, padded[offset:offset+64], counter)
+	}
+
+	var hash Hash
+	for i, v := range h {
+		binary.BigEndian.PutUint32(hash[i*4:], v)
+	}
+
+	return hash
+}
+
+func blake256Compress(h *[8]uint32, block []byte, counter uint64) {
+	var m [16]uint32
+	for i := range m {
+		m[i] = binary.BigEndian.Uint32(block[i*4:])
+	}
+
+	var v [16]uint32
+	copy(v[:8], h[:])
+	copy(v[8:], blake256Constants[:8])
+
+	v[12] ^= uint32(counter)
+	v[13] ^= uint32(counter)
+	v[14] ^= uint32(counter >> 32)
+	v[15] ^= uint32(counter >> 32)
+
+	g := func(a, b, c, d, i int, s *[16]int) {
+		v[a] += v[b] + (m[s[2*i]] ^ blake256Constants[s[2*i+1]])
+		v[d] = bits.RotateLeft32(v[d]^v[a], -16)
+		v[c] += v[d]
+		v[b] = bits.RotateLeft32(v[b]^v[c], -12)
+		v[a] += v[b] + (m[s[2*i+1]] ^ blake256Constants[s[2*i]])
+		v[d] = bits.RotateLeft32(v[d]^v[a], -8)
+		v[c] += v[d]
+		v[b] = bits.RotateLeft32(v[b]^v[c], -7)
+	}
+
+	for round := 0; round < 14; round++ {
+		s := &blakeSigma[round%10]
+
+		g(0, 4, 8, 12, 0, s)
+		g(1, 5, 9, 13, 1, s)
+		g(2, 6, 10, 14, 2, s)
+		g(3, 7, 11, 15, 3, s)
+		g(0, 5, 10, 15, 4, s)
+		g(1, 6, 11, 12, 5, s)
+		g(2, 7, 8, 13, 6, s)
+		g(3, 4, 9, 14, 7, s)
+	}
+
+	for i := range h {
+		h[i] ^= v[i] ^ v[i+8]
+	}
+}
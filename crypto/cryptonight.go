@@ -0,0 +1,219 @@
+package crypto
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// CryptoNight parameters of the original algorithm
+const (
+	cryptoNightMemory     = 1 << 21 // 2 MiB scratchpad
+	cryptoNightIterations = 1 << 20
+	cryptoNightInitSize   = 128
+	aesBlockSize          = 16
+	aesRounds             = 10
+)
+
+var aesSBox [256]byte
+var aesTables [4][256]uint32
+
+func init() {
+	initAESTables()
+}
+
+// CryptoNight is the slow memory-hard hash used for the proof of work,
+// it is the "Crypto::cn_slow_hash" method (original variant) in C++ implementation
+func CryptoNight(data []byte) Hash {
+	state := keccak1600(data)
+
+	scratchpad := make([]byte, cryptoNightMemory)
+
+	// Fill the scratchpad with the AES encrypted keccak state
+	var text [cryptoNightInitSize]byte
+	copy(text[:], state[64:64+cryptoNightInitSize])
+
+	keys := aesExpandKey(state[:32])
+	for i := 0; i < cryptoNightMemory; i += cryptoNightInitSize {
+		for j := 0; j < cryptoNightInitSize; j += aesBlockSize {
+			for r := 0; r < aesRounds; r++ {
+				aesRound(text[j:j+aesBlockSize], text[j:j+aesBlockSize], keys[r][:])
+			}
+		}
+
+		copy(scratchpad[i:], text[:])
+	}
+
+	// Memory-hard loop
+	var a, b, c, d [aesBlockSize]byte
+	for i := 0; i < aesBlockSize; i++ {
+		a[i] = state[i] ^ state[32+i]
+		b[i] = state[16+i] ^ state[48+i]
+	}
+
+	for i := 0; i < cryptoNightIterations/2; i++ {
+		j := cryptoNightAddress(a[:])
+		aesRound(c[:], scratchpad[j:j+aesBlockSize], a[:])
+		xorBlocks(scratchpad[j:j+aesBlockSize], c[:], b[:])
+
+		j = cryptoNightAddress(c[:])
+		p := scratchpad[j : j+aesBlockSize]
+
+		hi, lo := bits.Mul64(binary.LittleEndian.Uint64(c[:]), binary.LittleEndian.Uint64(p))
+		binary.LittleEndian.PutUint64(d[:], binary.LittleEndian.Uint64(a[:])+hi)
+		binary.LittleEndian.PutUint64(d[8:], binary.LittleEndian.Uint64(a[8:])+lo)
+
+		xorBlocks(a[:], d[:], p)
+		copy(p, d[:])
+		b = c
+	}
+
+	// Compress the scratchpad back into the keccak state
+	copy(text[:], state[64:64+cryptoNightInitSize])
+
+	keys = aesExpandKey(state[32:64])
+	for i := 0; i < cryptoNightMemory; i += cryptoNightInitSize {
+		for j := 0; j < cryptoNightInitSize; j += aesBlockSize {
+			xorBlocks(text[j:j+aesBlockSize], text[j:j+aesBlockSize], scratchpad[i+j:i+j+aesBlockSize])
+
+			for r := 0; r < aesRounds; r++ {
+				aesRound(text[j:j+aesBlockSize], text[j:j+aesBlockSize], keys[r][:])
+			}
+		}
+	}
+
+	copy(state[64:], text[:])
+
+	var st [25]uint64
+	keccakStateFromBytes(&st, state[:])
+	keccakF1600(&st)
+	keccakStateToBytes(&st, state[:])
+
+	var hash Hash
+	switch state[0] & 3 {
+	case 0:
+		hash = blake256(state[:])
+	case 1:
+		hash = groestl256(state[:])
+	case 2:
+		hash = jh256(state[:])
+	case 3:
+		hash = skein512256(state[:])
+	}
+
+	return hash
+}
+
+// cryptoNightAddress converts the block to the scratchpad offset, it is the "e2i" method in C++ implementation
+func cryptoNightAddress(block []byte) int {
+	return int(binary.LittleEndian.Uint64(block) & (cryptoNightMemory - aesBlockSize))
+}
+
+func xorBlocks(dst []byte, x []byte, y []byte) {
+	for i := 0; i < aesBlockSize; i++ {
+		dst[i] = x[i] ^ y[i]
+	}
+}
+
+// aesRound applies single AES encryption round (SubBytes, ShiftRows, MixColumns, AddRoundKey),
+// it is the "aesb_single_round" method in C++ implementation
+func aesRound(dst []byte, src []byte, key []byte) {
+	var out [4]uint32
+
+	for c := 0; c < 4; c++ {
+		out[c] = aesTables[0][src[4*c]] ^
+			aesTables[1][src[4*((c+1)%4)+1]] ^
+			aesTables[2][src[4*((c+2)%4)+2]] ^
+			aesTables[3][src[4*((c+3)%4)+3]] ^
+			binary.LittleEndian.Uint32(key[4*c:])
+	}
+
+	for c := 0; c < 4; c++ {
+		binary.LittleEndian.PutUint32(dst[4*c:], out[c])
+	}
+}
+
+// aesExpandKey expands 256 bits key into the round keys used by CryptoNight
+func aesExpandKey(key []byte) [aesRounds][aesBlockSize]byte {
+	var words [aesRounds * 4][4]byte
+	for i := 0; i < 8; i++ {
+		copy(words[i][:], key[4*i:4*i+4])
+	}
+
+	rcon := byte(1)
+	for i := 8; i < len(words); i++ {
+		t := words[i-1]
+
+		if i%8 == 0 {
+			t = [4]byte{aesSBox[t[1]] ^ rcon, aesSBox[t[2]], aesSBox[t[3]], aesSBox[t[0]]}
+			rcon = gfMul2(rcon)
+		} else if i%8 == 4 {
+			t = [4]byte{aesSBox[t[0]], aesSBox[t[1]], aesSBox[t[2]], aesSBox[t[3]]}
+		}
+
+		for j := 0; j < 4; j++ {
+			words[i][j] = words[i-8][j] ^ t[j]
+		}
+	}
+
+	var keys [aesRounds][aesBlockSize]byte
+	for i := range keys {
+		for j := 0; j < 4; j++ {
+			copy(keys[i][4*j:], words[4*i+j][:])
+		}
+	}
+
+	return keys
+}
+
+// gfMul2 multiplies by 2 in the AES Galois field
+func gfMul2(x byte) byte {
+	if x&0x80 != 0 {
+		return x<<1 ^ 0x1b
+	}
+
+	return x << 1
+}
+
+// gfMul multiplies two elements of the AES Galois field
+func gfMul(x byte, y byte) byte {
+	var p byte
+
+	for y != 0 {
+		if y&1 != 0 {
+			p ^= x
+		}
+
+		x = gfMul2(x)
+		y >>= 1
+	}
+
+	return p
+}
+
+// initAESTables builds the AES S-box and the combined SubBytes and MixColumns tables
+func initAESTables() {
+	for i := 0; i < 256; i++ {
+		// Multiplicative inverse is x^254, zero maps to zero
+		inv := byte(0)
+		if i != 0 {
+			inv = 1
+			for j := 0; j < 254; j++ {
+				inv = gfMul(inv, byte(i))
+			}
+		}
+
+		s := inv ^ bits.RotateLeft8(inv, 1) ^ bits.RotateLeft8(inv, 2) ^
+			bits.RotateLeft8(inv, 3) ^ bits.RotateLeft8(inv, 4) ^ 0x63
+
+		aesSBox[i] = s
+	}
+
+	for i := 0; i < 256; i++ {
+		s := aesSBox[i]
+		t := uint32(gfMul2(s)) | uint32(s)<<8 | uint32(s)<<16 | uint32(gfMul(s, 3))<<24
+
+		for j := 0; j < 4; j++ {
+			aesTables[j][i] = bits.RotateLeft32(t, 8*j)
+		}
+	}
+}
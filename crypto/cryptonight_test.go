@@ -0,0 +1,64 @@
+package crypto
+
+import (
+	"bufio"
+	"encoding/hex"
+	"github.com/stretchr/testify/assert"
+	"os"
+	"strings"
+	"testing"
+)
+
+// Known answers are taken from the "tests/hash/tests-slow.txt" of the C++ implementation
+func TestCryptoNight(t *testing.T) {
+	file, err := os.Open("./fixtures/cryptonight.txt")
+	check(err)
+	defer file.Close()
+
+	times := 0
+
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		cases := strings.Fields(scanner.Text())
+
+		expected, err := hex.DecodeString(cases[0])
+		check(err)
+
+		testBytes, err := hex.DecodeString(cases[1])
+		check(err)
+
+		hash := CryptoNight(testBytes)
+
+		assert.Equal(t, expected, hash[:])
+		times++
+	}
+
+	assert.Equal(t, 5, times)
+}
+
+func TestKeccak1600(t *testing.T) {
+	data := []byte("de omnibus dubitandum")
+	state := keccak1600(data)
+
+	assert.Equal(t, Keccak(data), state[:32])
+}
+
+func TestCryptoNightFinalHashes(t *testing.T) {
+	tests := []struct {
+		name     string
+		hash     func([]byte) Hash
+		expected string
+	}{
+		{"blake256", blake256, "716f6e863f744b9ac22c97ec7b76ea5f5908bc5b2f67c61510bfc4751384ea7a"},
+		{"groestl256", groestl256, "1a52d11d550039be16107f9c58db9ebcc417f16f736adb2502567119f0083467"},
+		{"jh256", jh256, "46e64619c18bb0a92a5e87185a47eef83ca747b8fcc8e1412921357e326df434"},
+		{"skein512256", skein512256, "39ccc4554a8b31853b9de7a1fe638a24cce6b35a55f2431009e18780335d2621"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			hash := test.hash([]byte{})
+			assert.Equal(t, test.expected, hash.String())
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package crypto
+
+import "encoding/binary"
+
+// Grøstl-256 used as one of the CryptoNight final hashes.
+// State is 8x8 bytes matrix, byte i of the block is placed to the row i%8 and column i/8.
+
+const groestlBlockSize = 64
+
+var groestlShiftP = [8]int{0, 1, 2, 3, 4, 5, 6, 7}
+var groestlShiftQ = [8]int{1, 3, 5, 7, 0, 2, 4, 6}
+var groestlMix = [8]byte{2, 2, 3, 4, 5, 3, 5, 7}
+
+func groestl256(data []byte) Hash {
+	var h [groestlBlockSize]byte
+	h[groestlBlockSize-2] = 0x01 // output size is 256 bits
+
+	// Padding: bit 1, zeros and blocks count as 64 bits big endian integer
+	padded := append([]byte{}, data...)
+	padded = append(padded, 0x80)
+	for len(padded)%groestlBlockSize != groestlBlockSize-8 {
+		padded = append(padded, 0)
+	}
+
+	var blocks [8]byte
+	binary.BigEndian.PutUint64(blocks[:], uint64(len(padded)+8)/groestlBlockSize)
+	padded = append(padded, blocks[:]...)
+
+	for offset := 0; offset < len(padded); offset += groestlBlockSize {
+		var p, q [groestlBlockSize]byte
+
+		for i := range p {
+			p[i] = h[i] ^ padded[offset+i]
+			q[i] = padded[offset+i]
+		}
+
+		groestlPermutation(&p, false)
+		groestlPermutation(&q, true)
+
+		for i := range h {
+			h[i] ^= p[i] ^ q[i]
+		}
+	}
+
+	p := h
+	groestlPermutation(&p, false)
+
+	var hash Hash
+	for i := range hash {
+		hash[i] = p[groestlBlockSize-32+i] ^ h[groestlBlockSize-32+i]
+	}
+
+	return hash
+}
+
+// groestlPermutation is the P512 permutation or the Q512 permutation when q is true
+func groestlPermutation(state *[groestlBlockSize]byte, q bool) {
+	shift := &groestlShiftP
+	if q {
+		shift = &groestlShiftQ
+	}
+
+	for round := 0; round < 10; round++ {
+		// AddRoundConstant
+		for col := 0; col < 8; col++ {
+			constant := byte(col<<4) ^ byte(round)
+
+			if q {
+				for row := 0; row < 8; row++ {
+					state[col*8+row] ^= 0xff
+				}
+				state[col*8+7] ^= constant
+			} else {
+				state[col*8] ^= constant
+			}
+		}
+
+		// SubBytes
+		for i := range state {
+			state[i] = aesSBox[state[i]]
+		}
+
+		// ShiftBytes
+		var shifted [groestlBlockSize]byte
+		for row := 0; row < 8; row++ {
+			for col := 0; col < 8; col++ {
+				shifted[col*8+row] = state[((col+shift[row])%8)*8+row]
+			}
+		}
+
+		// MixBytes
+		for col := 0; col < 8; col++ {
+			for row := 0; row < 8; row++ {
+				var v byte
+				for k := 0; k < 8; k++ {
+					v ^= gfMul(groestlMix[(k-row+8)%8], shifted[col*8+k])
+				}
+				state[col*8+row] = v
+			}
+		}
+	}
+}
@@ -119,6 +119,13 @@ func (hl HashList) MerkleRootHash() *Hash {
 }
 
 func (hl HashList) TreeHashFromBranch(leaf Hash) Hash {
+	return hl.TreeHashFromBranchPath(leaf, nil)
+}
+
+// TreeHashFromBranchPath calculates merkle root from the leaf and the branch,
+// bits of the path define if the leaf is on the left or on the right side on each level.
+// it is the "Crypto::tree_hash_from_branch" method in C++ implementation
+func (hl HashList) TreeHashFromBranchPath(leaf Hash, path *Hash) Hash {
 	depth := len(hl)
 
 	if depth == 0 {
@@ -133,17 +140,13 @@ func (hl HashList) TreeHashFromBranch(leaf Hash) Hash {
 	for depth > 0 {
 		depth--
 
-		// TODO: WTF?
-		//if (path && (((const char *) path)[depth >> 3] & (1 << (depth & 7))) != 0) {
-		//	leaf_path = buffer[1];
-		//	branch_path = buffer[0];
-		//} else {
-		//	leaf_path = buffer[0];
-		//	branch_path = buffer[1];
-		//}
-
-		leafPath = &buf[0]
-		branchPath = &buf[1]
+		if path != nil && (path[depth>>3]&(1<<(depth&7))) != 0 {
+			leafPath = &buf[1]
+			branchPath = &buf[0]
+		} else {
+			leafPath = &buf[0]
+			branchPath = &buf[1]
+		}
 
 		if fromLeaf {
 			copy(leafPath[:], leaf[:])
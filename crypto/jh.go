@@ -0,0 +1,183 @@
+package crypto
+
+import "encoding/binary"
+
+// JH-256 used as one of the CryptoNight final hashes.
+// It is the straightforward port of the JH reference implementation working with 4-bit elements.
+
+const jhBlockSize = 64
+
+var jhSBoxes = [2][16]byte{
+	{9, 0, 4, 11, 13, 12, 3, 15, 1, 10, 2, 6, 7, 5, 8, 14},
+	{3, 12, 6, 13, 5, 7, 1, 9, 15, 2, 0, 4, 11, 10, 14, 8},
+}
+
+var jhRoundConstantZero = [64]byte{
+	0x6, 0xa, 0x0, 0x9, 0xe, 0x6, 0x6, 0x7, 0xf, 0x3, 0xb, 0xc, 0xc, 0x9, 0x0, 0x8,
+	0xb, 0x2, 0xf, 0xb, 0x1, 0x3, 0x6, 0x6, 0xe, 0xa, 0x9, 0x5, 0x7, 0xd, 0x3, 0xe,
+	0x3, 0xa, 0xd, 0xe, 0xc, 0x1, 0x7, 0x5, 0x1, 0x2, 0x7, 0x7, 0x5, 0x0, 0x9, 0x9,
+	0xd, 0xa, 0x2, 0xf, 0x5, 0x9, 0x0, 0xb, 0x0, 0x6, 0x6, 0x7, 0x3, 0x2, 0x2, 0xa,
+}
+
+type jhState struct {
+	h             [128]byte
+	a             [256]byte
+	roundConstant [64]byte
+}
+
+func jh256(data []byte) Hash {
+	var s jhState
+	s.h[0] = 0x01 // output size is 256 bits
+	s.f8(make([]byte, jhBlockSize))
+
+	bitsLen := uint64(len(data)) * 8
+
+	// Padding: bit 1, at least 383 zero bits up to the block end and message length as 128 bits big endian integer
+	padded := append([]byte{}, data...)
+	padded = append(padded, 0x80)
+	for len(padded)%jhBlockSize != 0 {
+		padded = append(padded, 0)
+	}
+
+	if len(data)%jhBlockSize != 0 {
+		padded = append(padded, make([]byte, jhBlockSize)...)
+	}
+
+	binary.BigEndian.PutUint64(padded[len(padded)-8:], bitsLen)
+
+	for offset := 0; offset < len(padded); offset += jhBlockSize {
+		s.f8(padded[offset : offset+jhBlockSize])
+	}
+
+	var hash Hash
+	copy(hash[:], s.h[96:])
+
+	return hash
+}
+
+// f8 is the compression function
+func (s *jhState) f8(block []byte) {
+	for i := 0; i < jhBlockSize; i++ {
+		s.h[i] ^= block[i]
+	}
+
+	s.e8()
+
+	for i := 0; i < jhBlockSize; i++ {
+		s.h[i+64] ^= block[i]
+	}
+}
+
+// e8 is the bijective function
+func (s *jhState) e8() {
+	s.roundConstant = jhRoundConstantZero
+
+	s.initialGroup()
+
+	for i := 0; i < 42; i++ {
+		s.r8()
+		s.updateRoundConstant()
+	}
+
+	s.finalDegroup()
+}
+
+func jhLinear(a *byte, b *byte) {
+	*b ^= ((*a << 1) ^ (*a >> 3) ^ ((*a >> 2) & 2)) & 0xf
+	*a ^= ((*b << 1) ^ (*b >> 3) ^ ((*b >> 2) & 2)) & 0xf
+}
+
+func (s *jhState) r8() {
+	var tem [256]byte
+
+	// S-box layer, each bit of the round constant selects the S-box
+	for i := 0; i < 256; i++ {
+		bit := (s.roundConstant[i>>2] >> (3 - (i & 3))) & 1
+		tem[i] = jhSBoxes[bit][s.a[i]]
+	}
+
+	// MDS layer
+	for i := 0; i < 256; i += 2 {
+		jhLinear(&tem[i], &tem[i+1])
+	}
+
+	// Initial swap
+	for i := 0; i < 256; i += 4 {
+		tem[i+2], tem[i+3] = tem[i+3], tem[i+2]
+	}
+
+	// Permutation
+	for i := 0; i < 128; i++ {
+		s.a[i] = tem[i<<1]
+		s.a[i+128] = tem[(i<<1)+1]
+	}
+
+	// Final swap
+	for i := 128; i < 256; i += 2 {
+		s.a[i], s.a[i+1] = s.a[i+1], s.a[i]
+	}
+}
+
+func (s *jhState) updateRoundConstant() {
+	var tem [64]byte
+
+	for i := 0; i < 64; i++ {
+		tem[i] = jhSBoxes[0][s.roundConstant[i]]
+	}
+
+	for i := 0; i < 64; i += 2 {
+		jhLinear(&tem[i], &tem[i+1])
+	}
+
+	for i := 0; i < 64; i += 4 {
+		tem[i+2], tem[i+3] = tem[i+3], tem[i+2]
+	}
+
+	for i := 0; i < 32; i++ {
+		s.roundConstant[i] = tem[i<<1]
+		s.roundConstant[i+32] = tem[(i<<1)+1]
+	}
+
+	for i := 32; i < 64; i += 2 {
+		s.roundConstant[i], s.roundConstant[i+1] = s.roundConstant[i+1], s.roundConstant[i]
+	}
+}
+
+// initialGroup groups the bits of h into 4-bit elements of a
+func (s *jhState) initialGroup() {
+	var tem [256]byte
+
+	for i := 0; i < 256; i++ {
+		shift := uint(7 - (i & 7))
+		t0 := (s.h[i>>3] >> shift) & 1
+		t1 := (s.h[(i+256)>>3] >> shift) & 1
+		t2 := (s.h[(i+512)>>3] >> shift) & 1
+		t3 := (s.h[(i+768)>>3] >> shift) & 1
+		tem[i] = t0<<3 | t1<<2 | t2<<1 | t3
+	}
+
+	for i := 0; i < 128; i++ {
+		s.a[i<<1] = tem[i]
+		s.a[(i<<1)+1] = tem[i+128]
+	}
+}
+
+// finalDegroup decomposes 4-bit elements of a back into h
+func (s *jhState) finalDegroup() {
+	var tem [256]byte
+
+	for i := 0; i < 128; i++ {
+		tem[i] = s.a[i<<1]
+		tem[i+128] = s.a[(i<<1)+1]
+	}
+
+	s.h = [128]byte{}
+
+	for i := 0; i < 256; i++ {
+		shift := uint(7 - (i & 7))
+		s.h[i>>3] |= ((tem[i] >> 3) & 1) << shift
+		s.h[(i+256)>>3] |= ((tem[i] >> 2) & 1) << shift
+		s.h[(i+512)>>3] |= ((tem[i] >> 1) & 1) << shift
+		s.h[(i+768)>>3] |= (tem[i] & 1) << shift
+	}
+}
@@ -0,0 +1,115 @@
+package crypto
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// keccakStateSize is the size of the full keccak-f[1600] state in bytes
+const keccakStateSize = 200
+
+// keccakRate of the keccak sponge used by CryptoNote, it is the "HASH_DATA_AREA" in C++ implementation
+const keccakRate = 136
+
+var keccakRoundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808a, 0x8000000080008000,
+	0x000000000000808b, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008a, 0x0000000000000088, 0x0000000080008009, 0x000000008000000a,
+	0x000000008000808b, 0x800000000000008b, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800a, 0x800000008000000a,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+var keccakRotations = [24]int{
+	1, 3, 6, 10, 15, 21, 28, 36, 45, 55, 2, 14,
+	27, 41, 56, 8, 25, 43, 62, 18, 39, 61, 20, 44,
+}
+
+var keccakPiLanes = [24]int{
+	10, 7, 11, 17, 18, 3, 5, 16, 8, 21, 24, 4,
+	15, 23, 19, 13, 12, 2, 20, 14, 22, 9, 6, 1,
+}
+
+// keccakF1600 is the keccak permutation, it is the "keccakf" method in C++ implementation
+func keccakF1600(st *[25]uint64) {
+	var bc [5]uint64
+
+	for round := 0; round < 24; round++ {
+		// Theta
+		for i := 0; i < 5; i++ {
+			bc[i] = st[i] ^ st[i+5] ^ st[i+10] ^ st[i+15] ^ st[i+20]
+		}
+
+		for i := 0; i < 5; i++ {
+			t := bc[(i+4)%5] ^ bits.RotateLeft64(bc[(i+1)%5], 1)
+			for j := 0; j < 25; j += 5 {
+				st[j+i] ^= t
+			}
+		}
+
+		// Rho Pi
+		t := st[1]
+		for i := 0; i < 24; i++ {
+			j := keccakPiLanes[i]
+			bc[0] = st[j]
+			st[j] = bits.RotateLeft64(t, keccakRotations[i])
+			t = bc[0]
+		}
+
+		// Chi
+		for j := 0; j < 25; j += 5 {
+			for i := 0; i < 5; i++ {
+				bc[i] = st[j+i]
+			}
+
+			for i := 0; i < 5; i++ {
+				st[j+i] ^= (^bc[(i+1)%5]) & bc[(i+2)%5]
+			}
+		}
+
+		// Iota
+		st[0] ^= keccakRoundConstants[round]
+	}
+}
+
+// keccak1600 returns the whole keccak state after absorbing the data,
+// it is the "keccak1600" method in C++ implementation
+func keccak1600(data []byte) [keccakStateSize]byte {
+	var st [25]uint64
+
+	for ; len(data) >= keccakRate; data = data[keccakRate:] {
+		keccakAbsorb(&st, data[:keccakRate])
+		keccakF1600(&st)
+	}
+
+	var last [keccakRate]byte
+	copy(last[:], data)
+	last[len(data)] = 1
+	last[keccakRate-1] |= 0x80
+
+	keccakAbsorb(&st, last[:])
+	keccakF1600(&st)
+
+	var state [keccakStateSize]byte
+	keccakStateToBytes(&st, state[:])
+
+	return state
+}
+
+func keccakAbsorb(st *[25]uint64, block []byte) {
+	for i := 0; i < len(block)/8; i++ {
+		st[i] ^= binary.LittleEndian.Uint64(block[i*8:])
+	}
+}
+
+func keccakStateFromBytes(st *[25]uint64, b []byte) {
+	for i := range st {
+		st[i] = binary.LittleEndian.Uint64(b[i*8:])
+	}
+}
+
+func keccakStateToBytes(st *[25]uint64, b []byte) {
+	for i := range st {
+		binary.LittleEndian.PutUint64(b[i*8:], st[i])
+	}
+}
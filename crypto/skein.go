@@ -0,0 +1,145 @@
+package crypto
+
+import (
+	"encoding/binary"
+	"math/bits"
+)
+
+// Skein-512-256 used as one of the CryptoNight final hashes
+
+const skeinBlockSize = 64
+
+// UBI block types
+const (
+	skeinTypeConfig  = 4
+	skeinTypeMessage = 48
+	skeinTypeOutput  = 63
+)
+
+const skeinKeyScheduleParity = 0x1bd11bdaa9fc1a22
+
+var skeinRotations = [8][4]int{
+	{46, 36, 19, 37},
+	{33, 27, 14, 42},
+	{17, 49, 36, 39},
+	{44, 9, 54, 56},
+	{39, 30, 34, 24},
+	{13, 50, 10, 17},
+	{25, 29, 39, 43},
+	{8, 35, 56, 22},
+}
+
+var skeinPermutation = [8]int{2, 1, 4, 7, 6, 5, 0, 3}
+
+func skein512256(data []byte) Hash {
+	var h [8]uint64
+
+	// Configuration block: schema "SHA3", version 1 and output size in bits
+	config := make([]byte, 32)
+	copy(config, "SHA3")
+	binary.LittleEndian.PutUint16(config[4:], 1)
+	binary.LittleEndian.PutUint64(config[8:], 256)
+	skeinUBI(&h, config, skeinTypeConfig)
+
+	skeinUBI(&h, data, skeinTypeMessage)
+
+	skeinUBI(&h, make([]byte, 8), skeinTypeOutput)
+
+	var hash Hash
+	for i := 0; i < 4; i++ {
+		binary.LittleEndian.PutUint64(hash[i*8:], h[i])
+	}
+
+	return hash
+}
+
+// skeinUBI processes the message with the unique block iteration chaining mode
+func skeinUBI(h *[8]uint64, message []byte, blockType uint64) {
+	position := uint64(0)
+	first := true
+
+	for {
+		var block [skeinBlockSize]byte
+		n := copy(block[:], message)
+		message = message[n:]
+		position += uint64(n)
+
+		final := len(message) == 0
+
+		tweak1 := blockType << 56
+		if first {
+			tweak1 |= 1 << 62
+		}
+		if final {
+			tweak1 |= 1 << 63
+		}
+
+		var m [8]uint64
+		for i := range m {
+			m[i] = binary.LittleEndian.Uint64(block[i*8:])
+		}
+
+		out := threefish512(h, &[2]uint64{position, tweak1}, &m)
+		for i := range h {
+			h[i] = out[i] ^ m[i]
+		}
+
+		first = false
+
+		if final {
+			return
+		}
+	}
+}
+
+// threefish512 encrypts the block with the key and the tweak
+func threefish512(key *[8]uint64, tweak *[2]uint64, block *[8]uint64) [8]uint64 {
+	var k [9]uint64
+	copy(k[:8], key[:])
+	k[8] = skeinKeyScheduleParity
+	for i := 0; i < 8; i++ {
+		k[8] ^= key[i]
+	}
+
+	t := [3]uint64{tweak[0], tweak[1], tweak[0] ^ tweak[1]}
+
+	subKey := func(s int) [8]uint64 {
+		var sk [8]uint64
+		for i := 0; i < 8; i++ {
+			sk[i] = k[(s+i)%9]
+		}
+		sk[5] += t[s%3]
+		sk[6] += t[(s+1)%3]
+		sk[7] += uint64(s)
+
+		return sk
+	}
+
+	v := *block
+
+	for round := 0; round < 72; round++ {
+		if round%4 == 0 {
+			sk := subKey(round / 4)
+			for i := range v {
+				v[i] += sk[i]
+			}
+		}
+
+		var f [8]uint64
+		for j := 0; j < 4; j++ {
+			f[2*j] = v[2*j] + v[2*j+1]
+			f[2*j+1] = bits.RotateLeft64(v[2*j+1], skeinRotations[round%8][j]) ^ f[2*j]
+		}
+
+		for i := range v {
+			v[i] = f[skeinPermutation[i]]
+		}
+	}
+
+	sk := subKey(72 / 4)
+	for i := range v {
+		v[i] += sk[i]
+	}
+
+	return v
+}
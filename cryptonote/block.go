@@ -104,6 +104,20 @@ func (b *Block) Hash() *crypto.Hash {
 	return b.hash
 }
 
+// auxHeaderHash is the hash of the block hashing bytes, merge mined parent block includes it in the merkle tree.
+// it is the "get_aux_block_header_hash" method in C++ implementation
+func (b *Block) auxHeaderHash() crypto.Hash {
+	hashingBytes := b.HashingBytes()
+
+	var h bytes.Buffer
+	buf := make([]byte, binary.MaxVarintLen64)
+	written := binary.PutUvarint(buf, uint64(len(hashingBytes)))
+	h.Write(buf[:written])
+	h.Write(hashingBytes)
+
+	return crypto.HashFromBytes(h.Bytes())
+}
+
 func (b *Block) Index() uint32 {
 	if len(b.BaseTransaction.Inputs) == 1 {
 		i := b.BaseTransaction.Inputs[0]
@@ -139,6 +153,10 @@ func (b *Block) Deserialize(r *bytes.Reader) error {
 		}
 
 		b.Parent = parentBlock
+
+		// Merge mined blocks keep the timestamp and the nonce in the parent block
+		b.Timestamp = parentBlock.Timestamp
+		b.Nonce = parentBlock.Nonce
 	}
 
 	if err := b.BaseTransaction.Deserialize(r); err != nil {
@@ -240,7 +258,7 @@ func (h *BlockHeader) deserialize(reader *bytes.Reader) error {
 		if err := prev.Read(reader); err != nil {
 			return err
 		}
-	case config.BlockMajorVersion1, config.BlockMajorVersion4:
+	case config.BlockMajorVersion1, config.BlockMajorVersion4, config.BlockMajorVersion5:
 		ts, err = binary.ReadUvarint(reader)
 		if err != nil {
 			return err
@@ -285,7 +303,7 @@ func (h *BlockHeader) serialize() []byte {
 	switch h.MajorVersion {
 	case config.BlockMajorVersion2, config.BlockMajorVersion3:
 		serialized.Write(h.PreviousBlockHash[:])
-	case config.BlockMajorVersion1, config.BlockMajorVersion4:
+	case config.BlockMajorVersion1, config.BlockMajorVersion4, config.BlockMajorVersion5:
 		written = binary.PutUvarint(buf, h.Timestamp)
 		serialized.Write(buf[:written])
 		serialized.Write(h.PreviousBlockHash[:])
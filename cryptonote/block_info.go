@@ -1,6 +1,10 @@
 package cryptonote
 
-import "github.com/r3volut1oner/go-karbo/crypto"
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/r3volut1oner/go-karbo/crypto"
+)
 
 // BlockInfo represents additional information about block that is not part of block itself.
 //
@@ -35,3 +39,28 @@ func NewBlockInfo(i uint32, totalGeneratedCoins uint64) *blockInfo {
 		TotalGeneratedCoins: totalGeneratedCoins,
 	}
 }
+
+// genesisBlockInfo builds the info of the first block in the chain
+func genesisBlockInfo(genesisBlock *Block) *blockInfo {
+	return &blockInfo{
+		Index:                0,
+		Hash:                 *genesisBlock.Hash(),
+		CumulativeDifficulty: 1,
+		TotalGeneratedCoins:  genesisBlock.BaseTransaction.Outputs[0].Amount,
+		Timestamp:            genesisBlock.Timestamp,
+		Size:                 genesisBlock.BaseTransaction.Size(),
+	}
+}
+
+// serialize block info into the bytes, used by persistent storages
+func (info *blockInfo) serialize() []byte {
+	var serialized bytes.Buffer
+
+	_ = binary.Write(&serialized, binary.LittleEndian, info)
+
+	return serialized.Bytes()
+}
+
+func (info *blockInfo) deserialize(r *bytes.Reader) error {
+	return binary.Read(r, binary.LittleEndian, info)
+}
@@ -21,7 +21,29 @@ type ParentBlock struct {
 	BlockchainBranch      []crypto.Hash
 }
 
+// serialize returns parent block bytes, merkle root of the parent block transactions is included for hashing
 func (pb *ParentBlock) serialize(hashing bool) []byte {
+	var serialized bytes.Buffer
+
+	serialized.Write(pb.serializeHeader(hashing))
+
+	for _, tb := range pb.BaseTransactionBranch {
+		serialized.Write(tb[:])
+	}
+
+	serialized.Write(pb.BaseTransaction.serialize())
+
+	for _, h := range pb.BlockchainBranch {
+		serialized.Write(h[:])
+	}
+
+	return serialized.Bytes()
+}
+
+// serializeHeader returns parent block header with the transactions count.
+// With hashing enabled it is the parent block hashing blob used for the proof of work,
+// the "get_parent_block_hashing_blob" method in C++ implementation.
+func (pb *ParentBlock) serializeHeader(hashing bool) []byte {
 	buf := make([]byte, binary.MaxVarintLen64)
 
 	var serialized bytes.Buffer
@@ -49,16 +71,6 @@ func (pb *ParentBlock) serialize(hashing bool) []byte {
 	written = binary.PutUvarint(buf, uint64(pb.TransactionsCount))
 	serialized.Write(buf[:written])
 
-	for _, tb := range pb.BaseTransactionBranch {
-		serialized.Write(tb[:])
-	}
-
-	serialized.Write(pb.BaseTransaction.serialize())
-
-	for _, h := range pb.BlockchainBranch {
-		serialized.Write(h[:])
-	}
-
 	return serialized.Bytes()
 }
 
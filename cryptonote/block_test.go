@@ -3,6 +3,8 @@ package cryptonote
 import (
 	"bytes"
 	"github.com/r3volut1oner/go-karbo/config"
+	"github.com/r3volut1oner/go-karbo/crypto"
+	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
 	"testing"
@@ -169,3 +171,42 @@ func TestBlock_Deserialize(t *testing.T) {
 	assert.Len(t, block2.BaseTransaction.Outputs, 7)
 	assert.Equal(t, []byte{0x1, 0x6f, 0x7f, 0x61, 0xe2, 0x4e, 0xfe, 0x12, 0x41, 0xc2, 0x55, 0xc8, 0x8, 0xc0, 0x95, 0xbb, 0x3a, 0x80, 0xd5, 0x93, 0x28, 0x1, 0x3d, 0xb0, 0x93, 0x55, 0x91, 0xaf, 0xf5, 0x5d, 0xf4, 0x55, 0xf1}, block2.BaseTransaction.Extra)
 }
+
+func TestBlock_SerializeVersion5(t *testing.T) {
+	block := testLoadBlock(t, "./fixtures/block2.dat")
+	block.MajorVersion = config.BlockMajorVersion5
+	block.Signature = &crypto.Signature{C: crypto.EllipticCurveScalar{1}, R: crypto.EllipticCurveScalar{2}}
+
+	var decoded Block
+	assert.Nil(t, decoded.Deserialize(bytes.NewReader(block.Serialize())))
+	assert.Equal(t, block.Timestamp, decoded.Timestamp)
+	assert.Equal(t, block.Nonce, decoded.Nonce)
+	assert.Equal(t, block.PreviousBlockHash, decoded.PreviousBlockHash)
+	assert.Equal(t, block.Signature, decoded.Signature)
+	assert.Equal(t, block.Serialize(), decoded.Serialize())
+
+	// Nonce is hashed, so it changes the proof of work
+	hashingBytes := block.HashingBytes()
+	block.Nonce++
+	assert.NotEqual(t, hashingBytes, block.HashingBytes())
+}
+
+func TestBlock_MainNetCheckpoints(t *testing.T) {
+	checkpoints := config.NewCheckpoints(logrus.New())
+	assert.Nil(t, checkpoints.AddCheckpoints(config.MainNet().Checkpoints()))
+
+	for _, name := range []string{"block1.dat", "block_60001.dat", "block_105385.dat", "block_200054.dat"} {
+		payload, err := ioutil.ReadFile("./fixtures/" + name)
+		assert.Nil(t, err)
+
+		var block Block
+		assert.Nil(t, block.Deserialize(bytes.NewReader(payload)))
+
+		index := block.Index()
+		assert.True(t, checkpoints.IsInCheckpointZone(index), name)
+		assert.Nil(t, checkpoints.CheckBlock(index, block.Hash()), name)
+		if index > 1 {
+			assert.Nil(t, checkpoints.CheckBlock(index-1, &block.PreviousBlockHash), name)
+		}
+	}
+}
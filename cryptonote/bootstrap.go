@@ -0,0 +1,440 @@
+package cryptonote
+
+// Bootstrap files keep the main chain blocks in the format of the blocks.dat and blockindexes.dat files
+// of the C++ implementation, so the blockchain can be moved between the nodes without the network.
+//
+// blockindexes.dat starts with the count of the blocks (uint64) followed by the size of every block entry (uint32).
+// blocks.dat has the block entries one after another, entry is the block followed by its height, size,
+// cumulative difficulty, generated coins and the transactions (coinbase transaction is the first one)
+// with the global indexes of their outputs. Numbers are little endian in the indexes file and varints in the entries.
+// src/CryptoNoteCore/Blockchain.h BlockEntry, src/CryptoNoteCore/SwappedVector.h
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	BootstrapBlocksFile  = "blocks.dat"
+	BootstrapIndexesFile = "blockindexes.dat"
+
+	// bootstrapHeaderSize is the size of the blocks count in the beginning of the indexes file
+	bootstrapHeaderSize = 8
+)
+
+var (
+	ErrBootstrapCorrupted     = errors.New("bootstrap files are corrupted")
+	ErrBootstrapChainMismatch = errors.New("bootstrap blocks don't match the blockchain")
+)
+
+// Bootstrap is the pair of the bootstrap files opened for reading or for appending the blocks
+type Bootstrap struct {
+	blocks  *os.File
+	indexes *os.File
+
+	// offsets are the positions of the entries in the blocks file, the last offset is the end of the last entry
+	offsets []int64
+}
+
+// OpenBootstrap opens existing bootstrap files in the directory for reading.
+func OpenBootstrap(dir string) (*Bootstrap, error) {
+	return openBootstrap(dir, os.O_RDONLY)
+}
+
+// CreateBootstrap opens bootstrap files in the directory for appending the blocks, files are created if missing.
+// Partially written entry left by the interrupted export is dropped, so the export can be continued.
+func CreateBootstrap(dir string) (*Bootstrap, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+
+	b, err := openBootstrap(dir, os.O_RDWR|os.O_CREATE)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := b.truncate(); err != nil {
+		b.Close()
+		return nil, err
+	}
+
+	return b, nil
+}
+
+func openBootstrap(dir string, flag int) (*Bootstrap, error) {
+	blocks, err := os.OpenFile(filepath.Join(dir, BootstrapBlocksFile), flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	indexes, err := os.OpenFile(filepath.Join(dir, BootstrapIndexesFile), flag, 0644)
+	if err != nil {
+		blocks.Close()
+		return nil, err
+	}
+
+	b := &Bootstrap{blocks: blocks, indexes: indexes, offsets: []int64{0}}
+	if err := b.load(); err != nil {
+		b.Close()
+		return nil, err
+	}
+
+	return b, nil
+}
+
+// load reads the entries sizes, entries not completely saved to the files are skipped
+func (b *Bootstrap) load() error {
+	indexesInfo, err := b.indexes.Stat()
+	if err != nil {
+		return err
+	}
+
+	if indexesInfo.Size() < bootstrapHeaderSize {
+		return nil
+	}
+
+	blocksInfo, err := b.blocks.Stat()
+	if err != nil {
+		return err
+	}
+
+	var header [bootstrapHeaderSize]byte
+	if _, err := b.indexes.ReadAt(header[:], 0); err != nil {
+		return err
+	}
+
+	count := binary.LittleEndian.Uint64(header[:])
+	if saved := uint64(indexesInfo.Size()-bootstrapHeaderSize) / 4; saved < count {
+		count = saved
+	}
+
+	sizes := make([]byte, count*4)
+	if _, err := b.indexes.ReadAt(sizes, bootstrapHeaderSize); err != nil {
+		return err
+	}
+
+	for i := uint64(0); i < count; i++ {
+		offset := b.offsets[i] + int64(binary.LittleEndian.Uint32(sizes[i*4:]))
+		if offset > blocksInfo.Size() {
+			break
+		}
+
+		b.offsets = append(b.offsets, offset)
+	}
+
+	return nil
+}
+
+// truncate removes the data after the last loaded entry from the files
+func (b *Bootstrap) truncate() error {
+	if err := b.blocks.Truncate(b.end()); err != nil {
+		return err
+	}
+
+	if err := b.indexes.Truncate(bootstrapHeaderSize + int64(b.Count())*4); err != nil {
+		return err
+	}
+
+	return b.writeCount()
+}
+
+// Count returns the number of the blocks in the bootstrap
+func (b *Bootstrap) Count() uint32 {
+	return uint32(len(b.offsets) - 1)
+}
+
+// ReadBlock returns block at the index with its raw transactions, coinbase transaction is not included.
+func (b *Bootstrap) ReadBlock(index uint32) (*Block, [][]byte, error) {
+	if index >= b.Count() {
+		return nil, nil, ErrStorageBlockNotFound
+	}
+
+	payload := make([]byte, b.offsets[index+1]-b.offsets[index])
+	if _, err := b.blocks.ReadAt(payload, b.offsets[index]); err != nil {
+		return nil, nil, err
+	}
+
+	block, transactions, err := deserializeBootstrapEntry(bytes.NewReader(payload))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if block.Index() != index {
+		return nil, nil, ErrBootstrapCorrupted
+	}
+
+	rawTransactions := make([][]byte, len(transactions))
+	for i := range transactions {
+		rawTransactions[i] = transactions[i].Serialize()
+	}
+
+	return block, rawTransactions, nil
+}
+
+// Close closes the bootstrap files
+func (b *Bootstrap) Close() error {
+	err := b.blocks.Close()
+	if indexesErr := b.indexes.Close(); err == nil {
+		err = indexesErr
+	}
+
+	return err
+}
+
+// append writes the entry after the last one, the count is updated the last, so the interrupted write is dropped on open
+func (b *Bootstrap) append(entry []byte) error {
+	if _, err := b.blocks.WriteAt(entry, b.end()); err != nil {
+		return err
+	}
+
+	var size [4]byte
+	binary.LittleEndian.PutUint32(size[:], uint32(len(entry)))
+	if _, err := b.indexes.WriteAt(size[:], bootstrapHeaderSize+int64(b.Count())*4); err != nil {
+		return err
+	}
+
+	b.offsets = append(b.offsets, b.end()+int64(len(entry)))
+
+	return b.writeCount()
+}
+
+func (b *Bootstrap) writeCount() error {
+	var header [bootstrapHeaderSize]byte
+	binary.LittleEndian.PutUint64(header[:], uint64(b.Count()))
+
+	_, err := b.indexes.WriteAt(header[:], 0)
+	return err
+}
+
+func (b *Bootstrap) end() int64 {
+	return b.offsets[len(b.offsets)-1]
+}
+
+// ExportBlocks appends the main chain blocks up to the index to the bootstrap.
+// Export continues after the blocks already saved in the bootstrap, they must be the blocks of the main chain.
+// Progress is called with the index of every exported block.
+//
+// This function is safe for concurrent access.
+func (bc *BlockChain) ExportBlocks(ctx context.Context, b *Bootstrap, to uint32, progress func(index uint32)) error {
+	from := b.Count()
+	if err := bc.checkBootstrap(b, from); err != nil {
+		return err
+	}
+
+	counter := bootstrapOutputsCounter{keys: map[uint64]uint32{}, multisignatures: map[uint64]uint32{}}
+
+	// Global indexes of the outputs are counted from the genesis block, even if the blocks are already exported
+	for index := uint32(0); index <= to; index++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		block, transactions, info, err := bc.bootstrapBlock(index)
+		if err != nil {
+			return err
+		}
+
+		globalIndexes := counter.add(transactions)
+		if index < from {
+			continue
+		}
+
+		if err := b.append(serializeBootstrapEntry(block, info, transactions, globalIndexes)); err != nil {
+			return err
+		}
+
+		progress(index)
+	}
+
+	return nil
+}
+
+// ImportBlocks adds the bootstrap blocks missing in the blockchain with AddBlock.
+// Blocks already in the blockchain must be the same as the bootstrap ones, so the import can be continued.
+// Progress is called with the index of every imported block.
+//
+// This function is safe for concurrent access.
+func (bc *BlockChain) ImportBlocks(ctx context.Context, b *Bootstrap, progress func(index uint32)) error {
+	height := bc.Height()
+	if err := bc.checkBootstrap(b, height); err != nil {
+		return err
+	}
+
+	for index := height; index < b.Count(); index++ {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+
+		block, rawTransactions, err := b.ReadBlock(index)
+		if err != nil {
+			return err
+		}
+
+		if err := bc.AddBlock(block, rawTransactions); err != nil {
+			return fmt.Errorf("failed to add block %d: %w", index, err)
+		}
+
+		if *bc.TopBlock().Hash() != *block.Hash() {
+			return ErrBootstrapChainMismatch
+		}
+
+		progress(index)
+	}
+
+	return nil
+}
+
+// checkBootstrap verifies that the last block both in the blockchain and the bootstrap, before the height, is the same
+func (bc *BlockChain) checkBootstrap(b *Bootstrap, height uint32) error {
+	if b.Count() < height {
+		height = b.Count()
+	}
+
+	if height == 0 {
+		return nil
+	}
+
+	block, _, err := b.ReadBlock(height - 1)
+	if err != nil {
+		return err
+	}
+
+	mainBlock := bc.BlockByIndex(height - 1)
+	if mainBlock == nil || *mainBlock.Hash() != *block.Hash() {
+		return ErrBootstrapChainMismatch
+	}
+
+	return nil
+}
+
+// bootstrapBlock returns the main chain block at the index with all its transactions, coinbase transaction is the first.
+func (bc *BlockChain) bootstrapBlock(index uint32) (*Block, []Transaction, *blockInfo, error) {
+	bc.RLock()
+	defer bc.RUnlock()
+
+	block, err := bc.storage.GetBlockByHeight(index)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	transactions, err := bc.storage.GetBlockTransactions(index)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	info := bc.storage.getBlockInfoAtIndex(index)
+	if info == nil {
+		return nil, nil, nil, ErrStorageBlockNotFound
+	}
+
+	return block, append([]Transaction{block.BaseTransaction}, transactions...), info, nil
+}
+
+// bootstrapOutputsCounter assigns the global indexes to the outputs, key and multisignature outputs are counted separately
+type bootstrapOutputsCounter struct {
+	keys            map[uint64]uint32
+	multisignatures map[uint64]uint32
+}
+
+// add returns the global indexes of the transactions outputs
+func (c *bootstrapOutputsCounter) add(transactions []Transaction) [][]uint32 {
+	globalIndexes := make([][]uint32, len(transactions))
+
+	for i := range transactions {
+		globalIndexes[i] = make([]uint32, len(transactions[i].Outputs))
+
+		for j, output := range transactions[i].Outputs {
+			counts := c.keys
+			if _, ok := output.Target.(OutputMultisignature); ok {
+				counts = c.multisignatures
+			}
+
+			globalIndexes[i][j] = counts[output.Amount]
+			counts[output.Amount]++
+		}
+	}
+
+	return globalIndexes
+}
+
+func serializeBootstrapEntry(block *Block, info *blockInfo, transactions []Transaction, globalIndexes [][]uint32) []byte {
+	var serialized bytes.Buffer
+
+	buf := make([]byte, binary.MaxVarintLen64)
+	writeUvarint := func(v uint64) {
+		written := binary.PutUvarint(buf, v)
+		serialized.Write(buf[:written])
+	}
+
+	serialized.Write(block.Serialize())
+	writeUvarint(uint64(info.Index))
+	writeUvarint(info.Size)
+	writeUvarint(info.CumulativeDifficulty)
+	writeUvarint(info.TotalGeneratedCoins)
+
+	writeUvarint(uint64(len(transactions)))
+	for i := range transactions {
+		serialized.Write(transactions[i].Serialize())
+
+		writeUvarint(uint64(len(globalIndexes[i])))
+		for _, globalIndex := range globalIndexes[i] {
+			writeUvarint(uint64(globalIndex))
+		}
+	}
+
+	return serialized.Bytes()
+}
+
+// deserializeBootstrapEntry returns the block with its transactions, coinbase transaction is not included
+func deserializeBootstrapEntry(r *bytes.Reader) (*Block, []Transaction, error) {
+	block := &Block{}
+	if err := block.Deserialize(r); err != nil {
+		return nil, nil, err
+	}
+
+	// Height, size, cumulative difficulty and generated coins are calculated again when block is added
+	for i := 0; i < 4; i++ {
+		if _, err := binary.ReadUvarint(r); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Every transaction takes at least one byte
+	if count == 0 || count > uint64(r.Len()) {
+		return nil, nil, ErrBootstrapCorrupted
+	}
+
+	transactions := make([]Transaction, count)
+	for i := range transactions {
+		if err := transactions[i].Deserialize(r); err != nil {
+			return nil, nil, err
+		}
+
+		indexesCount, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		for j := uint64(0); j < indexesCount; j++ {
+			if _, err := binary.ReadUvarint(r); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if r.Len() != 0 || *transactions[0].Hash() != *block.BaseTransaction.Hash() {
+		return nil, nil, ErrBootstrapCorrupted
+	}
+
+	return block, transactions[1:], nil
+}
@@ -0,0 +1,159 @@
+package cryptonote
+
+import (
+	"context"
+	"encoding/binary"
+	"github.com/r3volut1oner/go-karbo/crypto"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestBootstrap_ExportImport(t *testing.T) {
+	bc, _ := testBlockChain(t)
+	ctx := context.Background()
+
+	prev := testMineChain(t, bc, bc.TopBlock(), 1, 1)[0]
+	transaction := testTransaction(t, bc, prev.BaseTransaction.Outputs[0].Amount, testKeyImage(t))
+	block := testMineBlockWithTransactions(t, bc, prev, 1, []Transaction{transaction}, bc.Network.MinimalFeeValidator(2))
+	assert.Nil(t, bc.AddBlock(block, [][]byte{transaction.Serialize()}))
+	testMineChain(t, bc, block, 1, 1)
+
+	// Export is continued after the interruption
+	dir := tempStorageDir(t)
+	var exported []uint32
+	progress := func(index uint32) {
+		exported = append(exported, index)
+	}
+
+	b, err := CreateBootstrap(dir)
+	assert.Nil(t, err)
+	assert.Nil(t, bc.ExportBlocks(ctx, b, 1, progress))
+	assert.Equal(t, uint32(2), b.Count())
+	assert.Nil(t, b.Close())
+
+	b, err = CreateBootstrap(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), b.Count())
+	assert.Nil(t, bc.ExportBlocks(ctx, b, 3, progress))
+	assert.Nil(t, b.Close())
+	assert.Equal(t, []uint32{0, 1, 2, 3}, exported)
+
+	fullDir := tempStorageDir(t)
+	full, err := CreateBootstrap(fullDir)
+	assert.Nil(t, err)
+	assert.Nil(t, bc.ExportBlocks(ctx, full, 3, func(uint32) {}))
+	assert.Nil(t, full.Close())
+
+	for _, name := range []string{BootstrapBlocksFile, BootstrapIndexesFile} {
+		assert.Equal(t, testReadFile(t, filepath.Join(fullDir, name)), testReadFile(t, filepath.Join(dir, name)))
+	}
+
+	indexes := testReadFile(t, filepath.Join(dir, BootstrapIndexesFile))
+	assert.Len(t, indexes, 8+4*4)
+	assert.Equal(t, uint64(4), binary.LittleEndian.Uint64(indexes))
+
+	b, err = OpenBootstrap(dir)
+	assert.Nil(t, err)
+	defer b.Close()
+
+	saved, rawTransactions, err := b.ReadBlock(2)
+	assert.Nil(t, err)
+	assert.Equal(t, block.Hash(), saved.Hash())
+	assert.Equal(t, [][]byte{transaction.Serialize()}, rawTransactions)
+
+	// Import adds only the missing blocks
+	imported, _ := testBlockChain(t)
+	assert.Nil(t, imported.AddBlock(prev, nil))
+
+	var added []uint32
+	assert.Nil(t, imported.ImportBlocks(ctx, b, func(index uint32) {
+		added = append(added, index)
+	}))
+	assert.Equal(t, []uint32{2, 3}, added)
+	assert.Equal(t, bc.TopBlock().Hash(), imported.TopBlock().Hash())
+
+	_, _, err = imported.GetTransaction(transaction.Hash())
+	assert.Nil(t, err)
+
+	added = nil
+	assert.Nil(t, imported.ImportBlocks(ctx, b, func(index uint32) {
+		added = append(added, index)
+	}))
+	assert.Empty(t, added)
+
+	// Blockchain with the other blocks can't be continued
+	other, _ := testBlockChain(t)
+	testMineChain(t, other, other.TopBlock(), 1, 2)
+	assert.Equal(t, ErrBootstrapChainMismatch, other.ImportBlocks(ctx, b, func(uint32) {}))
+
+	cancelled, cancel := context.WithCancel(ctx)
+	cancel()
+	fresh, _ := testBlockChain(t)
+	assert.Equal(t, context.Canceled, fresh.ImportBlocks(cancelled, b, func(uint32) {}))
+}
+
+func TestBootstrap_InterruptedWrite(t *testing.T) {
+	bc, _ := testBlockChain(t)
+	testMineChain(t, bc, bc.TopBlock(), 2, 1)
+
+	dir := tempStorageDir(t)
+	b, err := CreateBootstrap(dir)
+	assert.Nil(t, err)
+	assert.Nil(t, bc.ExportBlocks(context.Background(), b, 2, func(uint32) {}))
+	assert.Nil(t, b.Close())
+
+	blocks := testReadFile(t, filepath.Join(dir, BootstrapBlocksFile))
+	indexes := testReadFile(t, filepath.Join(dir, BootstrapIndexesFile))
+
+	// Entry and its size are written, but the count is not updated
+	testAppendFile(t, filepath.Join(dir, BootstrapBlocksFile), []byte{1, 2, 3})
+	testAppendFile(t, filepath.Join(dir, BootstrapIndexesFile), []byte{3, 0, 0, 0})
+
+	b, err = OpenBootstrap(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(3), b.Count())
+	assert.Nil(t, b.Close())
+
+	b, err = CreateBootstrap(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(3), b.Count())
+	assert.Nil(t, b.Close())
+
+	assert.Equal(t, blocks, testReadFile(t, filepath.Join(dir, BootstrapBlocksFile)))
+	assert.Equal(t, indexes, testReadFile(t, filepath.Join(dir, BootstrapIndexesFile)))
+}
+
+func TestBootstrap_OutputsCounter(t *testing.T) {
+	counter := bootstrapOutputsCounter{keys: map[uint64]uint32{}, multisignatures: map[uint64]uint32{}}
+	transaction := Transaction{
+		TransactionPrefix: TransactionPrefix{
+			Outputs: []TransactionOutput{
+				{Amount: 10, Target: OutputKey{}},
+				{Amount: 10, Target: OutputMultisignature{Keys: []crypto.PublicKey{{1}}, RequiredSignaturesCount: 1}},
+				{Amount: 20, Target: OutputKey{}},
+				{Amount: 10, Target: OutputKey{}},
+			},
+		},
+	}
+
+	assert.Equal(t, [][]uint32{{0, 0, 0, 1}}, counter.add([]Transaction{transaction}))
+	assert.Equal(t, [][]uint32{{2, 1, 1, 3}}, counter.add([]Transaction{transaction}))
+}
+
+func testReadFile(t *testing.T, path string) []byte {
+	payload, err := ioutil.ReadFile(path)
+	assert.Nil(t, err)
+
+	return payload
+}
+
+func testAppendFile(t *testing.T, path string, payload []byte) {
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+	assert.Nil(t, err)
+	_, err = f.Write(payload)
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+}
@@ -29,14 +29,18 @@ type BlockChain struct {
 	// bestTip the higher block in the blockchain
 	bestTip *Block
 
-	// tips higher blockchain tips
+	// tips of the alternative chains
 	tips []*Block
 
-	blocksIndex map[crypto.Hash]*Block
+	// blocksIndex keeps blocks of the alternative chains
+	blocksIndex map[crypto.Hash]*alternativeBlock
 
 	// genesisBlock network genesis block.
 	genesisBlock *Block
 
+	// pool is the memory pool notified about pushed and popped blocks, can be nil
+	pool *TxPool
+
 	sync.RWMutex
 }
 
@@ -48,7 +52,8 @@ func NewBlockChain(network *config.Network, storage Storage, logger *log.Logger)
 		logger:      logger,
 		storage:     storage,
 
-		tips: []*Block{},
+		tips:        []*Block{},
+		blocksIndex: map[crypto.Hash]*alternativeBlock{},
 	}
 
 	return bc
@@ -79,10 +84,20 @@ func (bc *BlockChain) Init() error {
 	return nil
 }
 
-// Height returns current blockchain height
+// Height returns current blockchain height, it is the number of blocks in the main chain.
+//
+// This function is safe for concurrent access.
 func (bc *BlockChain) Height() uint32 {
-	// TODO: Implement
-	return 0
+	bc.RLock()
+	defer bc.RUnlock()
+
+	topIndex, err := bc.storage.TopIndex()
+	if err != nil {
+		bc.logger.Errorf("failed to get top index from storage: %s", err)
+		return 0
+	}
+
+	return topIndex + 1
 }
 
 // TopBlock returns current best block
@@ -93,9 +108,119 @@ func (bc *BlockChain) TopBlock() *Block {
 	return topBlock
 }
 
+// BlockByIndex returns block of the main chain at the index.
+// Returns nil if block not found.
+//
+// This function is safe for concurrent access.
+func (bc *BlockChain) BlockByIndex(index uint32) *Block {
+	bc.RLock()
+	defer bc.RUnlock()
+
+	block, err := bc.storage.GetBlockByHeight(index)
+	if err != nil {
+		return nil
+	}
+
+	return block
+}
+
+// BlockByHash returns block of the main chain represented by provided hash.
+// Returns nil if block not found.
+//
+// This function is safe for concurrent access.
+func (bc *BlockChain) BlockByHash(hash *crypto.Hash) *Block {
+	bc.RLock()
+	block := bc.storage.GetBlock(hash)
+	bc.RUnlock()
+	return block
+}
+
+// BlockIndexByHash returns index of the main chain block represented by provided hash.
+// Returns ErrStorageBlockNotFound if block not found.
+//
+// This function is safe for concurrent access.
+func (bc *BlockChain) BlockIndexByHash(hash *crypto.Hash) (uint32, error) {
+	bc.RLock()
+	defer bc.RUnlock()
+
+	return bc.storage.GetBlockIndexByHash(hash)
+}
+
+// BlockWithTransactions returns block of the main chain represented by provided hash with its transactions.
+// Returns ErrStorageBlockNotFound if block not found.
+//
+// This function is safe for concurrent access.
+func (bc *BlockChain) BlockWithTransactions(hash *crypto.Hash) (*Block, []Transaction, error) {
+	bc.RLock()
+	defer bc.RUnlock()
+
+	index, err := bc.storage.GetBlockIndexByHash(hash)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	block, err := bc.storage.GetBlockByHeight(index)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	transactions, err := bc.storage.GetBlockTransactions(index)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return block, transactions, nil
+}
+
+// GetTransaction returns the main chain transaction with the block containing it.
+// Returns ErrStorageTransactionNotFound if transaction not found.
+//
+// This function is safe for concurrent access.
+func (bc *BlockChain) GetTransaction(hash *crypto.Hash) (*Transaction, *Block, error) {
+	bc.RLock()
+	defer bc.RUnlock()
+
+	return bc.storage.GetTransaction(hash)
+}
+
+// BlockInfo returns info of the main chain block at the index.
+// Returns nil if block not found.
+//
+// This function is safe for concurrent access.
+func (bc *BlockChain) BlockInfo(index uint32) *blockInfo {
+	bc.RLock()
+	info := bc.storage.getBlockInfoAtIndex(index)
+	bc.RUnlock()
+	return info
+}
+
+// DifficultyForNextBlock returns difficulty of the block that extends the main chain.
+//
+// This function is safe for concurrent access.
+func (bc *BlockChain) DifficultyForNextBlock() (uint64, error) {
+	bc.RLock()
+	defer bc.RUnlock()
+
+	return bc.difficultyForNextBlock(bc.bestTip)
+}
+
+// AlternativeBlocksCount returns number of blocks in the alternative chains.
+//
+// This function is safe for concurrent access.
+func (bc *BlockChain) AlternativeBlocksCount() int {
+	bc.RLock()
+	count := len(bc.blocksIndex)
+	bc.RUnlock()
+	return count
+}
+
 // AddBlock used for adding new blocks to the blockchain.
 //
-// It returns nil if block added successfully and ErrAddBlock* in case of error
+// Block that is not extending the best chain is saved in the alternative chain,
+// blockchain is reorganized when the alternative chain becomes heavier than the main one.
+//
+// It returns nil if block added successfully and ErrAddBlock* in case of error.
+// Category of the error is checked with errors.Is and ErrCategory* errors.
 func (bc *BlockChain) AddBlock(block *Block, rawTransactions [][]byte) error {
 	bc.Lock()
 	defer bc.Unlock()
@@ -114,7 +239,7 @@ func (bc *BlockChain) AddBlock(block *Block, rawTransactions [][]byte) error {
 		return err
 	}
 
-	prevBlock := bc.storage.GetBlock(&block.PreviousBlockHash)
+	prevBlock := bc.getBlock(&block.PreviousBlockHash)
 	if prevBlock == nil {
 		err := ErrAddBlockRejectedAsOrphaned
 		logger.Error(err)
@@ -122,7 +247,7 @@ func (bc *BlockChain) AddBlock(block *Block, rawTransactions [][]byte) error {
 	}
 
 	if blockIndex != prevBlock.Index()+1 {
-		logger.WithFields(log.Fields{
+		logger := logger.WithFields(log.Fields{
 			"prev_block_index": prevBlock.Index(),
 		})
 
@@ -131,111 +256,60 @@ func (bc *BlockChain) AddBlock(block *Block, rawTransactions [][]byte) error {
 		return err
 	}
 
-	coinbaseTransactionSize := block.BaseTransaction.Size()
-	if coinbaseTransactionSize > bc.Network.MaxTxSize {
-		err := ErrAddBlockTransactionCoinbaseMaxSize
-		logger.Error(err)
-		return err
-	}
-
-	transactions, transactionsSize, err := bc.deserializeTransactions(logger, rawTransactions)
-	if err != nil {
-		return err
-	}
-
-	if len(block.TransactionsHashes) != len(transactions) {
-		err := ErrAddBlockTransactionCountNotMatch
-		logger.Error(err)
-		return err
-	}
-
-	blockSize := coinbaseTransactionSize + transactionsSize
-	if blockSize > bc.Network.MaxBlockSize(uint64(blockIndex)) {
-		err := ErrBlockValidationCumulativeSizeTooBig
-		logger.Error(err)
-		return err
-	}
+	// Are we going to add the block to the best blockchain
+	addOnTop := *bc.bestTip.Hash() == block.PreviousBlockHash
 
-	minerReward, err := bc.validateBlock(logger, block, prevBlock)
-	if err != nil {
-		return err
+	if !addOnTop {
+		return bc.addAlternativeBlock(logger, block, prevBlock, rawTransactions)
 	}
 
-	if block.MajorVersion >= config.BlockMajorVersion5 {
-		sigHash := crypto.HashFromBytes(block.HashingBytes())
-		outputKey := block.BaseTransaction.Outputs[0].Target.(OutputKey)
-		if !block.Signature.Check(&sigHash, &outputKey.PublicKey) {
-			err := ErrBlockValidationBlockSignatureMismatch
-			logger.Error(err)
-			return err
-		}
-	}
+	return bc.addMainChainBlock(logger, block, prevBlock, rawTransactions)
+}
 
-	currentDifficulty, err := bc.difficultyForNextBlock(prevBlock)
+// addMainChainBlock validates the block with all its transactions and pushes it on top of the main chain.
+//
+// This function is NOT safe for concurrent access
+func (bc *BlockChain) addMainChainBlock(logger *log.Entry, block *Block, prevBlock *Block, rawTransactions [][]byte) error {
+	blockIndex := block.Index()
 
+	candidate, err := bc.validateBlockCandidate(logger, block, prevBlock, rawTransactions)
 	if err != nil {
-		err := ErrAddBlockFailedGetDifficulty
-		logger.Error(err)
 		return err
 	}
 
-	if currentDifficulty == 0 {
-		err := ErrBlockValidationDifficultyOverhead
-		logger.Error(err)
-		return err
-	}
-
-	// Are we going to add the block to the best blockchain
-	addOnTop := bc.bestTip.Index() == prevBlock.Index()
-
 	transactionsValidator := NewBlockTransactionsValidator(bc, blockIndex, logger.Logger)
 
-	txAddedHashes := map[crypto.Hash]bool{}
-	for i, transaction := range transactions {
-		// check if tx hashes in txs blob and header match
+	for i, transaction := range candidate.transactions {
 		txHash := transaction.Hash()
 
 		logger := logger.WithFields(log.Fields{
 			"transaction_index": i,
 			"transaction_hash":  txHash.String(),
-			"block_hash":        block.TransactionsHashes[i],
 		})
 
-		if *txHash != block.TransactionsHashes[i] {
-			err := ErrBlockValidationTransactionInconsistency
-			logger.Error(err)
-			return err
-		}
-
-		if addOnTop && bc.hasTransaction(txHash) {
+		if bc.hasTransaction(txHash) {
 			err := ErrBlockValidationDuplicateTransaction
 			logger.Error(err)
 			return err
 		}
 
-		// check that there's no duplicate transaction in the block
-		if _, ok := txAddedHashes[*txHash]; ok {
-			err := ErrBlockValidationDuplicateTransaction
-			logger.Error(err)
-			return err
-		}
-
-		txAddedHashes[*txHash] = true
-
 		if err := transactionsValidator.validate(&transaction); err != nil {
-			// TODO: Remove transaction from memory pool
+			if bc.pool != nil {
+				bc.pool.removeTransaction(txHash)
+			}
+
 			return err
 		}
 	}
 
 	prevBlockInfo := bc.storage.getBlockInfoAtIndex(prevBlock.Index())
-	lastBlockSizes := bc.lastBLockSizes(bc.Network.RewardBlockWindow(), prevBlock.Index())
+	lastBlockSizes := bc.lastBlocksSizes(bc.Network.RewardBlockWindow(), prevBlock)
 	blockSizeMedian := utils.MedianSlice(lastBlockSizes)
 
 	expectedReward, emissionChange, err := bc.Network.GetBlockReward(
 		block.MajorVersion,
 		blockSizeMedian,
-		blockSize,
+		candidate.size,
 		prevBlockInfo.TotalGeneratedCoins,
 		transactionsValidator.cumulativeFee,
 	)
@@ -246,10 +320,10 @@ func (bc *BlockChain) AddBlock(block *Block, rawTransactions [][]byte) error {
 		return err
 	}
 
-	if expectedReward != minerReward {
+	if expectedReward != candidate.minerReward {
 		logger := logger.WithFields(log.Fields{
 			"block_expected_reward": expectedReward,
-			"block_miner_reward":    minerReward,
+			"block_miner_reward":    candidate.minerReward,
 		})
 
 		err := ErrBlockValidationBlockRewardMismatch
@@ -257,32 +331,18 @@ func (bc *BlockChain) AddBlock(block *Block, rawTransactions [][]byte) error {
 		return err
 	}
 
-	if bc.Checkpoints.IsInCheckpointZone(blockIndex) {
-		if err := bc.Checkpoints.CheckBlock(blockIndex, block.Hash()); err != nil {
-			err := ErrBlockValidationCheckpointBlockHashMismatch
-			logger.Error(err)
-			return err
-		}
-	} else {
-		if err := bc.checkProofOfWork(block, currentDifficulty); err != nil {
-			err := ErrBlockValidationProofOfWorkTooWeak
-			logger.Error(err)
-			return err
-		}
-	}
-
 	info := blockInfo{
 		Index:                      blockIndex,
 		Hash:                       *block.Hash(),
-		CumulativeDifficulty:       prevBlockInfo.CumulativeDifficulty + currentDifficulty,
+		CumulativeDifficulty:       prevBlockInfo.CumulativeDifficulty + candidate.difficulty,
 		TotalGeneratedTransactions: prevBlockInfo.TotalGeneratedTransactions + uint64(len(block.TransactionsHashes)),
 		TotalGeneratedCoins:        prevBlockInfo.TotalGeneratedCoins + emissionChange,
 		Timestamp:                  block.Timestamp,
-		Size:                       blockSize,
+		Size:                       candidate.size,
 	}
 
 	transactionsDetails := TransactionsDetails{
-		transactions: transactions,
+		transactions: candidate.transactions,
 	}
 
 	for keyImage, _ := range transactionsValidator.spentKeyImages {
@@ -297,30 +357,158 @@ func (bc *BlockChain) AddBlock(block *Block, rawTransactions [][]byte) error {
 		)
 	}
 
-	// TODO: Add saving of the multisignature details
-
 	if err := bc.storage.PushBlock(block, &info, transactionsDetails); err != nil {
 		return err
 	}
 
 	bc.bestTip = block
 
+	if bc.pool != nil {
+		bc.pool.onBlockPushed(candidate.transactions)
+	}
+
 	return nil
 }
 
+// blockCandidate keeps results of the block validation that are not depending on the chain state.
+type blockCandidate struct {
+	// transactions of the block deserialized from the raw transactions
+	transactions []Transaction
+
+	// size of the block including coinbase transaction
+	size uint64
+
+	// minerReward is the sum of the coinbase outputs
+	minerReward uint64
+
+	// difficulty of the block
+	difficulty uint64
+}
+
+// validateBlockCandidate validates block against the branch of the chain it belongs to.
+// Transactions inputs are not validated here as they are depending on the main chain state.
+//
+// This function is NOT safe for concurrent access
+func (bc *BlockChain) validateBlockCandidate(logger *log.Entry, block *Block, prevBlock *Block, rawTransactions [][]byte) (*blockCandidate, error) {
+	blockIndex := block.Index()
+
+	coinbaseTransactionSize := block.BaseTransaction.Size()
+	if coinbaseTransactionSize > bc.Network.MaxTxSize {
+		err := ErrAddBlockTransactionCoinbaseMaxSize
+		logger.Error(err)
+		return nil, err
+	}
+
+	transactions, transactionsSize, err := bc.deserializeTransactions(logger, rawTransactions)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(block.TransactionsHashes) != len(transactions) {
+		err := ErrAddBlockTransactionCountNotMatch
+		logger.Error(err)
+		return nil, err
+	}
+
+	blockSize := coinbaseTransactionSize + transactionsSize
+	if blockSize > bc.Network.MaxBlockSize(uint64(blockIndex)) {
+		err := ErrBlockValidationCumulativeSizeTooBig
+		logger.Error(err)
+		return nil, err
+	}
+
+	minerReward, err := bc.validateBlock(logger, block, prevBlock)
+	if err != nil {
+		return nil, err
+	}
+
+	if block.MajorVersion >= config.BlockMajorVersion5 {
+		sigHash := crypto.HashFromBytes(block.HashingBytes())
+		outputKey := block.BaseTransaction.Outputs[0].Target.(OutputKey)
+		if !block.Signature.Check(&sigHash, &outputKey.PublicKey) {
+			err := ErrBlockValidationBlockSignatureMismatch
+			logger.Error(err)
+			return nil, err
+		}
+	}
+
+	currentDifficulty, err := bc.difficultyForNextBlock(prevBlock)
+
+	if err != nil {
+		err := ErrAddBlockFailedGetDifficulty
+		logger.Error(err)
+		return nil, err
+	}
+
+	if currentDifficulty == 0 {
+		err := ErrBlockValidationDifficultyOverhead
+		logger.Error(err)
+		return nil, err
+	}
+
+	txAddedHashes := map[crypto.Hash]bool{}
+	for i, transaction := range transactions {
+		// check if tx hashes in txs blob and header match
+		txHash := transaction.Hash()
+
+		logger := logger.WithFields(log.Fields{
+			"transaction_index": i,
+			"transaction_hash":  txHash.String(),
+			"block_hash":        block.TransactionsHashes[i],
+		})
+
+		if *txHash != block.TransactionsHashes[i] {
+			err := ErrBlockValidationTransactionInconsistency
+			logger.Error(err)
+			return nil, err
+		}
+
+		// check that there's no duplicate transaction in the block
+		if _, ok := txAddedHashes[*txHash]; ok {
+			err := ErrBlockValidationDuplicateTransaction
+			logger.Error(err)
+			return nil, err
+		}
+
+		txAddedHashes[*txHash] = true
+	}
+
+	if bc.Checkpoints.IsInCheckpointZone(blockIndex) {
+		if err := bc.Checkpoints.CheckBlock(blockIndex, block.Hash()); err != nil {
+			err := ErrBlockValidationCheckpointBlockHashMismatch
+			logger.Error(err)
+			return nil, err
+		}
+	} else {
+		if err := bc.checkProofOfWork(block, currentDifficulty); err != nil {
+			logger.Error(err)
+			return nil, err
+		}
+	}
+
+	return &blockCandidate{
+		transactions: transactions,
+		size:         blockSize,
+		minerReward:  minerReward,
+		difficulty:   currentDifficulty,
+	}, nil
+}
+
 // BuildSparseChain
 // IDs pow(2,n) offset, like 2, 4, 8, 16, 32, 64 and so on, and the last one is always genesis block
+//
+// This function is safe for concurrent access.
 func (bc *BlockChain) BuildSparseChain() ([]crypto.Hash, error) {
-	var list []crypto.Hash
+	bc.RLock()
+	defer bc.RUnlock()
 
-	topBlock := bc.TopBlock()
-	topHash := topBlock.Hash()
+	var list []crypto.Hash
 
-	list = append(list, *topHash)
+	topIndex := bc.bestTip.Index()
+	list = append(list, *bc.bestTip.Hash())
 
-	height := bc.Height()
-	for i := uint32(1); i < height; i *= 2 {
-		hash, err := bc.storage.HashAtIndex(height - i)
+	for i := uint32(1); i <= topIndex; i *= 2 {
+		hash, err := bc.storage.HashAtIndex(topIndex - i)
 		if err != nil {
 			return nil, err
 		}
@@ -340,6 +528,50 @@ func (bc *BlockChain) BuildSparseChain() ([]crypto.Hash, error) {
 	return list, nil
 }
 
+// FindBlockchainSupplement finds first block of the remote sparse chain that belongs to our main chain and
+// returns hashes of the main chain blocks starting from it, at most maxCount hashes returned.
+// Remote sparse chain is ordered from the top block to the genesis block.
+// Returns index of the first returned block and current blockchain height.
+//
+// This function is safe for concurrent access.
+func (bc *BlockChain) FindBlockchainSupplement(remoteHashes []crypto.Hash, maxCount uint32) (uint32, uint32, []crypto.Hash, error) {
+	bc.RLock()
+	defer bc.RUnlock()
+
+	topIndex := bc.bestTip.Index()
+
+	startIndex, found := uint32(0), false
+	for i := range remoteHashes {
+		index, err := bc.storage.GetBlockIndexByHash(&remoteHashes[i])
+		if err == ErrStorageBlockNotFound {
+			continue
+		}
+
+		if err != nil {
+			return 0, 0, nil, err
+		}
+
+		startIndex, found = index, true
+		break
+	}
+
+	if !found {
+		return 0, 0, nil, ErrNoCommonBlock
+	}
+
+	var hashes []crypto.Hash
+	for index := startIndex; index <= topIndex && uint32(len(hashes)) < maxCount; index++ {
+		hash, err := bc.storage.HashAtIndex(index)
+		if err != nil {
+			return 0, 0, nil, err
+		}
+
+		hashes = append(hashes, *hash)
+	}
+
+	return startIndex, topIndex + 1, hashes, nil
+}
+
 // HaveBlock returns whether the chain have block represented by provided hash.
 //
 // This function is safe for concurrent access.
@@ -380,7 +612,7 @@ func (bc *BlockChain) validateBlock(blogger *log.Entry, block *Block, prevBlock
 	}
 
 	timestampCheckWindow := bc.Network.BlockTimestampCheckWindow(block.MajorVersion)
-	lastTimestamps := bc.lastBlocksTimestamps(timestampCheckWindow, block, true)
+	lastTimestamps := bc.lastBlocksTimestamps(timestampCheckWindow, prevBlock, true)
 	if uint32(len(lastTimestamps)) >= timestampCheckWindow {
 		if block.Timestamp < utils.MedianSlice(lastTimestamps) {
 			err := ErrBlockValidationTimestampTooFarInPast
@@ -498,8 +730,7 @@ func (bc *BlockChain) validateBlock(blogger *log.Entry, block *Block, prevBlock
 	return minerReward, nil
 }
 
-// TODO: Properly implement this method
-// haveBlock return whether the block hash contains in the blockchain
+// haveBlock return whether the block hash contains in the main or in the alternative chains
 //
 // This function is NOT safe for concurrent access
 func (bc *BlockChain) haveBlock(h *crypto.Hash) bool {
@@ -507,8 +738,20 @@ func (bc *BlockChain) haveBlock(h *crypto.Hash) bool {
 		return true
 	}
 
-	// TODO: Implement search for a block in side chains
-	return false
+	_, ok := bc.blocksIndex[*h]
+	return ok
+}
+
+// getBlock returns block from the main or from the alternative chains.
+// Returns nil if block not found.
+//
+// This function is NOT safe for concurrent access
+func (bc *BlockChain) getBlock(h *crypto.Hash) *Block {
+	if alternative, ok := bc.blocksIndex[*h]; ok {
+		return alternative.block
+	}
+
+	return bc.storage.GetBlock(h)
 }
 
 // GenesisBlock returns first basic block of the blockchain
@@ -566,44 +809,78 @@ func (bc *BlockChain) deserializeTransactions(blogger *log.Entry, rt [][]byte) (
 	return transactions, transactionsSize, nil
 }
 
-// lastBlocksTimestamps fetches the timestamps of the
-func (bc *BlockChain) lastBlocksTimestamps(count uint32, b *Block, addGenesisBlock bool) []uint64 {
-	timestamps := []uint64{}
-	tempBlock := b
+// lastBlocksInfos returns infos of the last count blocks of the chain that ends with provided block.
+// The chain is walked through the alternative blocks first and then continues in the main chain.
+// Infos are ordered from the oldest to the newest block.
+//
+// This function is NOT safe for concurrent access
+func (bc *BlockChain) lastBlocksInfos(count uint32, b *Block, addGenesisBlock bool) []*blockInfo {
+	var infos []*blockInfo
+
+	hash := b.Hash()
+	index := b.Index()
 
 	for count > 0 {
-		if tempBlock.Index() == 0 && !addGenesisBlock {
+		alternative, ok := bc.blocksIndex[*hash]
+		if !ok {
 			break
 		}
 
-		timestamps = append(timestamps, tempBlock.Timestamp)
+		infos = append(infos, alternative.info)
+		hash = &alternative.block.PreviousBlockHash
+		index--
+		count--
+	}
 
-		if tempBlock.Index() == 0 {
+	for count > 0 {
+		if index == 0 && !addGenesisBlock {
 			break
 		}
 
-		tempBlock = bc.storage.GetBlock(&tempBlock.PreviousBlockHash)
+		info := bc.storage.getBlockInfoAtIndex(index)
+		if info == nil {
+			break
+		}
+
+		infos = append(infos, info)
+
+		if index == 0 {
+			break
+		}
+
+		index--
 		count--
 	}
 
-	return utils.SliceReverse(timestamps)
+	for i, j := 0, len(infos)-1; i < j; i, j = i+1, j-1 {
+		infos[i], infos[j] = infos[j], infos[i]
+	}
+
+	return infos
 }
 
-func (bc *BlockChain) lastBLockSizes(count uint32, index uint32) []uint64 {
-	sizes := []uint64{}
+// lastBlocksTimestamps fetches the timestamps of the last count blocks ending with provided block
+func (bc *BlockChain) lastBlocksTimestamps(count uint32, b *Block, addGenesisBlock bool) []uint64 {
+	infos := bc.lastBlocksInfos(count, b, addGenesisBlock)
 
-	tempInfo := bc.storage.getBlockInfoAtIndex(index)
-	for i := uint32(1); i <= count; i++ {
-		if tempInfo == nil {
-			break
-		}
+	timestamps := make([]uint64, len(infos))
+	for i, info := range infos {
+		timestamps[i] = info.Timestamp
+	}
 
-		sizes = append(sizes, tempInfo.Size)
-		tempInfo = bc.storage.getBlockInfoAtIndex(index - i)
-		count--
+	return timestamps
+}
+
+// lastBlocksSizes fetches the sizes of the last count blocks ending with provided block
+func (bc *BlockChain) lastBlocksSizes(count uint32, b *Block) []uint64 {
+	infos := bc.lastBlocksInfos(count, b, true)
+
+	sizes := make([]uint64, len(infos))
+	for i, info := range infos {
+		sizes[i] = info.Size
 	}
 
-	return utils.SliceReverse(sizes)
+	return sizes
 }
 
 // IsTransactionSpendTimeUnlocked check
@@ -623,20 +900,55 @@ func (bc *BlockChain) DecomposeAmount(amount uint64, dustThreshold uint64) []uin
 	return append(chunks, dusts...)
 }
 
-// checkProofOfWork verify block proof of work
-// TODO: Implement
+// checkProofOfWork verify block proof of work.
+// Blocks of the 2nd and 3rd versions are merge mined, so the block must be included in the parent block merkle tree.
 func (bc *BlockChain) checkProofOfWork(block *Block, difficulty uint64) error {
+	switch block.MajorVersion {
+	case config.BlockMajorVersion1, config.BlockMajorVersion4:
+		if !checkHash(crypto.CryptoNight(block.HashingBytes()), difficulty) {
+			return ErrBlockValidationProofOfWorkTooWeak
+		}
+	case config.BlockMajorVersion2, config.BlockMajorVersion3:
+		if !checkHash(crypto.CryptoNight(block.Parent.serializeHeader(true)), difficulty) {
+			return ErrBlockValidationProofOfWorkTooWeak
+		}
+
+		extra, err := block.Parent.BaseTransaction.ParseExtra()
+		if err != nil || extra.MiningTag == nil {
+			return ErrBlockValidationMergeMiningTagNotFound
+		}
+
+		genesisBlock, err := bc.GenesisBlock()
+		if err != nil {
+			return err
+		}
+
+		if len(block.Parent.BlockchainBranch) > 8*len(genesisBlock.Hash()) {
+			return ErrBlockValidationMergeMiningBranchTooLong
+		}
+
+		branch := crypto.HashList(block.Parent.BlockchainBranch)
+		auxBlocksMerkleRoot := branch.TreeHashFromBranchPath(block.auxHeaderHash(), genesisBlock.Hash())
+
+		if auxBlocksMerkleRoot != extra.MiningTag.MerkleRoot {
+			return ErrBlockValidationMergeMiningAuxBlockNotFound
+		}
+	default:
+		return ErrBlockValidationProofOfWorkNotSupported
+	}
+
 	return nil
 }
 
-// IsSpent
-// TODO: Implement
+// IsSpent checks if key image was spent in the main chain at the block index not higher than provided height.
 func (bc *BlockChain) IsSpent(image crypto.KeyImage, height uint32) bool {
-	return false
+	spentIndex, ok := bc.storage.getKeyImageSpentIndex(image)
+
+	return ok && spentIndex <= height
 }
 
-// ExtractKeyOutputKeys
-// TODO: Implement
+// ExtractKeyOutputKeys returns public keys of the key outputs with the amount and the global indexes.
+// Outputs must exist at the height and must be unlocked.
 func (bc *BlockChain) ExtractKeyOutputKeys(amount uint64, height uint32, globalIndexes []uint32) ([]crypto.PublicKey, error) {
 	if len(globalIndexes) == 0 {
 		return nil, utils.AssertionError("globalIndexes must be not empty")
@@ -650,23 +962,48 @@ func (bc *BlockChain) ExtractKeyOutputKeys(amount uint64, height uint32, globalI
 		return nil, utils.AssertionError("globalIndexes must be unique")
 	}
 
-	return nil, nil
+	publicKeys := make([]crypto.PublicKey, len(globalIndexes))
+	for i, globalIndex := range globalIndexes {
+		output := bc.storage.getKeyOutput(amount, globalIndex)
+		if output == nil || output.BlockIndex > height {
+			return nil, ErrExtractOutputKeyInvalidGlobalIndex
+		}
+
+		if !bc.IsTransactionSpendTimeUnlocked(output.UnlockTime, height) {
+			return nil, ErrExtractOutputKeyLocked
+		}
+
+		publicKeys[i] = output.PublicKey
+	}
+
+	return publicKeys, nil
 }
 
 // hasTransaction check if transaction is stored in blockchain already
-// TODO: Implement
 func (bc *BlockChain) hasTransaction(txHash *crypto.Hash) bool {
-	return false
+	return bc.storage.HaveTransaction(txHash)
 }
 
-// IsMultiSignatureOutputExists check if multisig output exists
-// TODO: Implement
+// IsMultiSignatureOutputExists checks if multisignature output with the amount and the global index exists
+// in the main chain at the block index not higher than provided height. Returns the output with its unlock time.
 func (bc *BlockChain) IsMultiSignatureOutputExists(amount uint64, globalIndex uint32, blockHeight uint32) (*OutputMultisignature, uint64, bool) {
-	return nil, 0, false
+	output := bc.storage.getMultisignatureOutput(amount, globalIndex)
+	if output == nil || output.BlockIndex > blockHeight {
+		return nil, 0, false
+	}
+
+	target := &OutputMultisignature{
+		Keys:                    output.Keys,
+		RequiredSignaturesCount: output.RequiredSignaturesCount,
+	}
+
+	return target, output.UnlockTime, true
 }
 
-// IsMultiSignatureSpent check
-// TODO: Implement
+// IsMultiSignatureSpent checks if multisignature output was spent in the main chain
+// at the block index not higher than provided height.
 func (bc *BlockChain) IsMultiSignatureSpent(amount uint64, globalIndex uint32, blockHeight uint32) bool {
-	return false
+	spentIndex, ok := bc.storage.getMultisignatureSpentIndex(amount, globalIndex)
+
+	return ok && spentIndex <= blockHeight
 }
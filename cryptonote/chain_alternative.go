@@ -0,0 +1,256 @@
+package cryptonote
+
+import (
+	"github.com/r3volut1oner/go-karbo/crypto"
+	log "github.com/sirupsen/logrus"
+)
+
+// alternativeBlock is the block that is not part of the main chain.
+// Raw transactions are kept, so the block can be validated again when chain is switched.
+type alternativeBlock struct {
+	block *Block
+
+	// info of the block in the alternative chain,
+	// only cumulative difficulty is used for choosing the best chain.
+	info *blockInfo
+
+	rawTransactions [][]byte
+}
+
+// addAlternativeBlock saves block into the alternative chain.
+// Blockchain is switched to the alternative chain if it has more cumulative difficulty than the main one.
+//
+// This function is NOT safe for concurrent access
+func (bc *BlockChain) addAlternativeBlock(logger *log.Entry, block *Block, prevBlock *Block, rawTransactions [][]byte) error {
+	if err := bc.Checkpoints.AlternativeBlockAllowed(bc.bestTip.Index()+1, block.Index()); err != nil {
+		return wrapCategoryError(ErrCategoryValidationFailed, err)
+	}
+
+	candidate, err := bc.validateBlockCandidate(logger, block, prevBlock, rawTransactions)
+	if err != nil {
+		return err
+	}
+
+	prevBlockInfo := bc.getBlockInfo(prevBlock)
+
+	// Generated totals are calculated when the block is pushed to the main chain
+	alternative := &alternativeBlock{
+		block: block,
+		info: &blockInfo{
+			Index:                      block.Index(),
+			Hash:                       *block.Hash(),
+			CumulativeDifficulty:       prevBlockInfo.CumulativeDifficulty + candidate.difficulty,
+			TotalGeneratedTransactions: prevBlockInfo.TotalGeneratedTransactions + uint64(len(block.TransactionsHashes)),
+			TotalGeneratedCoins:        prevBlockInfo.TotalGeneratedCoins,
+			Timestamp:                  block.Timestamp,
+			Size:                       candidate.size,
+		},
+		rawTransactions: rawTransactions,
+	}
+
+	bc.blocksIndex[*block.Hash()] = alternative
+	bc.updateTips()
+
+	mainChainInfo := bc.storage.getBlockInfoAtIndex(bc.bestTip.Index())
+	if alternative.info.CumulativeDifficulty <= mainChainInfo.CumulativeDifficulty {
+		logger.WithFields(log.Fields{
+			"alternative_cumulative_difficulty": alternative.info.CumulativeDifficulty,
+			"main_cumulative_difficulty":        mainChainInfo.CumulativeDifficulty,
+		}).Info("block added to alternative chain")
+
+		return nil
+	}
+
+	return bc.switchToAlternativeChain(logger, alternative)
+}
+
+// switchToAlternativeChain makes chain that ends with provided block the main chain.
+// Main chain blocks above the split point are moved to the alternative chains.
+// In case any of the alternative blocks fails validation the main chain is restored,
+// the invalid block and all its descendants are removed.
+//
+// This function is NOT safe for concurrent access
+func (bc *BlockChain) switchToAlternativeChain(logger *log.Entry, tip *alternativeBlock) error {
+	// Branch is ordered from the oldest to the newest block
+	var branch []*alternativeBlock
+	for alternative := tip; alternative != nil; alternative = bc.blocksIndex[alternative.block.PreviousBlockHash] {
+		branch = append([]*alternativeBlock{alternative}, branch...)
+	}
+
+	splitIndex := branch[0].block.Index() - 1
+
+	logger = logger.WithFields(log.Fields{
+		"split_index":       splitIndex,
+		"main_chain_top":    bc.bestTip.Index(),
+		"alternative_chain": tip.block.Index(),
+	})
+
+	logger.Info("switching to alternative chain")
+
+	// Popped blocks are ordered from the newest to the oldest
+	var popped []*alternativeBlock
+	for bc.bestTip.Index() > splitIndex {
+		alternative, err := bc.popBlock()
+		if err != nil {
+			logger.Error(err)
+			return err
+		}
+
+		popped = append(popped, alternative)
+	}
+
+	for i, alternative := range branch {
+		delete(bc.blocksIndex, *alternative.block.Hash())
+
+		err := bc.addMainChainBlock(logger, alternative.block, bc.bestTip, alternative.rawTransactions)
+		if err == nil {
+			continue
+		}
+
+		logger.WithField("failed_block_index", alternative.block.Index()).Error("failed to switch to alternative chain")
+
+		bc.removeAlternativeDescendants(alternative.block.Hash())
+
+		for j := i - 1; j >= 0; j-- {
+			if _, err := bc.popBlock(); err != nil {
+				logger.Error(err)
+				return err
+			}
+		}
+
+		for j := len(popped) - 1; j >= 0; j-- {
+			delete(bc.blocksIndex, *popped[j].block.Hash())
+
+			if err := bc.addMainChainBlock(logger, popped[j].block, bc.bestTip, popped[j].rawTransactions); err != nil {
+				logger.Error(err)
+				return err
+			}
+		}
+
+		bc.updateTips()
+
+		return err
+	}
+
+	bc.updateTips()
+
+	logger.Info("switched to alternative chain")
+
+	return nil
+}
+
+// popBlock removes top block of the main chain and moves it to the alternative chains.
+//
+// This function is NOT safe for concurrent access
+func (bc *BlockChain) popBlock() (*alternativeBlock, error) {
+	info := bc.storage.getBlockInfoAtIndex(bc.bestTip.Index())
+
+	block, transactions, err := bc.storage.PopBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	rawTransactions := make([][]byte, len(transactions))
+	for i := range transactions {
+		rawTransactions[i] = transactions[i].Serialize()
+	}
+
+	alternative := &alternativeBlock{
+		block:           block,
+		info:            info,
+		rawTransactions: rawTransactions,
+	}
+
+	bc.blocksIndex[*block.Hash()] = alternative
+
+	topBlock, err := bc.storage.TopBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	bc.bestTip = topBlock
+
+	if bc.pool != nil {
+		bc.pool.onBlockPopped(transactions)
+	}
+
+	return alternative, nil
+}
+
+// Rollback removes the main chain blocks above the index.
+// Removed blocks are not kept as the alternative ones, so they can be received and validated again.
+// Transactions of the removed blocks are returned to the pool.
+//
+// This function is safe for concurrent access.
+func (bc *BlockChain) Rollback(index uint32) error {
+	bc.Lock()
+	defer bc.Unlock()
+
+	if index > bc.bestTip.Index() {
+		return ErrRollbackAboveTop
+	}
+
+	defer bc.updateTips()
+
+	for bc.bestTip.Index() > index {
+		block, transactions, err := bc.storage.PopBlock()
+		if err != nil {
+			return err
+		}
+
+		bc.removeAlternativeDescendants(block.Hash())
+
+		topBlock, err := bc.storage.TopBlock()
+		if err != nil {
+			return err
+		}
+
+		bc.bestTip = topBlock
+
+		if bc.pool != nil {
+			bc.pool.onBlockPopped(transactions)
+		}
+	}
+
+	return nil
+}
+
+// removeAlternativeDescendants removes all the alternative blocks that are built on top of the provided block.
+//
+// This function is NOT safe for concurrent access
+func (bc *BlockChain) removeAlternativeDescendants(hash *crypto.Hash) {
+	for childHash, alternative := range bc.blocksIndex {
+		if alternative.block.PreviousBlockHash == *hash {
+			delete(bc.blocksIndex, childHash)
+			bc.removeAlternativeDescendants(&childHash)
+		}
+	}
+}
+
+// updateTips collects the alternative blocks that have no children.
+//
+// This function is NOT safe for concurrent access
+func (bc *BlockChain) updateTips() {
+	parents := map[crypto.Hash]bool{}
+	for _, alternative := range bc.blocksIndex {
+		parents[alternative.block.PreviousBlockHash] = true
+	}
+
+	bc.tips = []*Block{}
+	for hash, alternative := range bc.blocksIndex {
+		if !parents[hash] {
+			bc.tips = append(bc.tips, alternative.block)
+		}
+	}
+}
+
+// getBlockInfo returns info of the block from the main or from the alternative chains.
+//
+// This function is NOT safe for concurrent access
+func (bc *BlockChain) getBlockInfo(b *Block) *blockInfo {
+	if alternative, ok := bc.blocksIndex[*b.Hash()]; ok {
+		return alternative.info
+	}
+
+	return bc.storage.getBlockInfoAtIndex(b.Index())
+}
@@ -1,10 +1,13 @@
 package cryptonote
 
 import (
+	"errors"
+	"fmt"
 	"github.com/r3volut1oner/go-karbo/config"
 	"github.com/r3volut1oner/go-karbo/crypto"
 	"github.com/sirupsen/logrus"
 	"github.com/stretchr/testify/assert"
+	"math"
 	"testing"
 )
 
@@ -40,3 +43,510 @@ func TestBlockChain_GenesisBlock(t *testing.T) {
 	assert.Equal(t, &genesisBlockHash, hash)
 	assert.Equal(t, block.PreviousBlockHash, crypto.Hash{})
 }
+
+func TestBlockChain_AddBlock(t *testing.T) {
+	bc, _ := testBlockChain(t)
+
+	block := testMineBlock(t, bc, bc.TopBlock(), 1)
+	assert.Nil(t, bc.AddBlock(block, nil))
+	assert.Equal(t, block.Hash(), bc.TopBlock().Hash())
+
+	assert.Equal(t, ErrAddBlockAlreadyExists, bc.AddBlock(block, nil))
+
+	orphan := testMineBlock(t, bc, block, 1)
+	orphan.PreviousBlockHash = crypto.Hash{1}
+	assert.Equal(t, ErrAddBlockRejectedAsOrphaned, bc.AddBlock(orphan, nil))
+}
+
+func TestBlockChain_AddBlockErrorCategory(t *testing.T) {
+	bc, _ := testBlockChain(t)
+
+	block := testMineBlock(t, bc, bc.TopBlock(), 1)
+	assert.Nil(t, bc.AddBlock(block, nil))
+	assert.True(t, errors.Is(bc.AddBlock(block, nil), ErrCategoryAlreadyExists))
+
+	orphan := testMineBlock(t, bc, block, 1)
+	orphan.PreviousBlockHash = crypto.Hash{1}
+	assert.True(t, errors.Is(bc.AddBlock(orphan, nil), ErrCategoryRejected))
+
+	next := testMineBlock(t, bc, block, 1)
+	err := bc.AddBlock(next, [][]byte{{1}})
+	assert.True(t, errors.Is(err, ErrCategoryDeserialization))
+	assert.False(t, errors.Is(err, ErrCategoryValidationFailed))
+
+	// Category is kept in the wrapped errors
+	wrapped := fmt.Errorf("block %s: %w", next.Hash(), ErrBlockValidationWrongVersion)
+	assert.True(t, errors.Is(wrapped, ErrCategoryValidationFailed))
+	assert.True(t, errors.Is(wrapped, ErrBlockValidationWrongVersion))
+	assert.True(t, errors.Is(ErrAddBlockFailedGetDifficulty, ErrCategoryValidationFailed))
+	assert.False(t, errors.Is(ErrAddBlockUnexpectedError, ErrCategoryValidationFailed))
+
+	// Alternative block before the checkpoint is the validation error
+	main := testMineChain(t, bc, block, 3, 1)
+	assert.Nil(t, bc.Checkpoints.AddCheckpoint(main[0].Index(), *main[0].Hash()))
+	err = bc.AddBlock(testMineBlock(t, bc, block, 2), nil)
+	assert.True(t, errors.Is(err, ErrCategoryValidationFailed))
+	assert.True(t, errors.Is(err, config.ErrCheckpointsAltBeforeCheckpoint))
+}
+
+func TestBlockChain_AddAlternativeBlock(t *testing.T) {
+	bc, _ := testBlockChain(t)
+	genesis := bc.TopBlock()
+
+	main := testMineChain(t, bc, genesis, 3, 1)
+	assert.Equal(t, main[2].Hash(), bc.TopBlock().Hash())
+
+	// Alternative chain with the same length must not change the main chain
+	alternative := testMineChain(t, bc, genesis, 3, 2)
+	assert.Equal(t, main[2].Hash(), bc.TopBlock().Hash())
+	assert.True(t, bc.HaveBlock(alternative[2].Hash()))
+	assert.Len(t, bc.tips, 1)
+	assert.Equal(t, alternative[2].Hash(), bc.tips[0].Hash())
+}
+
+func TestBlockChain_SwitchToAlternativeChain(t *testing.T) {
+	bc, s := testBlockChain(t)
+	genesis := bc.TopBlock()
+
+	main := testMineChain(t, bc, genesis, 3, 1)
+	alternative := testMineChain(t, bc, main[0], 3, 2)
+
+	assert.Equal(t, alternative[2].Hash(), bc.TopBlock().Hash())
+
+	topIndex, err := s.TopIndex()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(4), topIndex)
+
+	hash, err := s.HashAtIndex(2)
+	assert.Nil(t, err)
+	assert.Equal(t, alternative[0].Hash(), hash)
+
+	// Old main chain blocks are kept as alternative ones
+	assert.True(t, bc.HaveBlock(main[1].Hash()))
+	assert.True(t, bc.HaveBlock(main[2].Hash()))
+	assert.Len(t, bc.tips, 1)
+	assert.Equal(t, main[2].Hash(), bc.tips[0].Hash())
+
+	// Totals are recalculated for the new main chain
+	info := s.getBlockInfoAtIndex(4)
+	assert.Equal(t, *alternative[2].Hash(), info.Hash)
+	assert.Equal(t, testGeneratedCoins(alternative[2]), info.TotalGeneratedCoins)
+}
+
+func TestBlockChain_SwitchToInvalidAlternativeChain(t *testing.T) {
+	bc, s := testBlockChain(t)
+	genesis := bc.TopBlock()
+
+	main := testMineChain(t, bc, genesis, 2, 1)
+
+	alternative := testMineChain(t, bc, genesis, 2, 2)
+	assert.Equal(t, main[1].Hash(), bc.TopBlock().Hash())
+
+	// Block with wrong reward is valid for the alternative chain, but fails on switching
+	invalid := testMineBlock(t, bc, alternative[1], 2)
+	invalid.BaseTransaction.Outputs[0].Amount--
+	invalid.BaseTransaction.hash = nil
+	invalid.hash = nil
+	invalid.hashTransactions = nil
+
+	assert.Equal(t, ErrBlockValidationBlockRewardMismatch, bc.AddBlock(invalid, nil))
+
+	assert.Equal(t, main[1].Hash(), bc.TopBlock().Hash())
+	hash, err := s.HashAtIndex(1)
+	assert.Nil(t, err)
+	assert.Equal(t, main[0].Hash(), hash)
+
+	assert.False(t, bc.HaveBlock(invalid.Hash()))
+	assert.True(t, bc.HaveBlock(alternative[1].Hash()))
+	assert.Len(t, bc.tips, 1)
+	assert.Equal(t, alternative[1].Hash(), bc.tips[0].Hash())
+}
+
+func TestBlockChain_Rollback(t *testing.T) {
+	bc, s := testBlockChain(t)
+	pool := testTxPool(bc)
+	genesis := bc.TopBlock()
+
+	main := testMineChain(t, bc, genesis, 1, 1)
+	transaction := testTransaction(t, bc, main[0].BaseTransaction.Outputs[0].Amount, testKeyImage(t))
+	block := testMineBlockWithTransactions(t, bc, main[0], 1, []Transaction{transaction}, bc.Network.MinimalFeeValidator(2))
+	assert.Nil(t, bc.AddBlock(block, [][]byte{transaction.Serialize()}))
+	main = append(main, block)
+
+	alternative := testMineChain(t, bc, main[0], 1, 2)
+
+	assert.Equal(t, ErrRollbackAboveTop, bc.Rollback(3))
+	assert.Nil(t, bc.Rollback(0))
+
+	assert.Equal(t, genesis.Hash(), bc.TopBlock().Hash())
+	topIndex, err := s.TopIndex()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(0), topIndex)
+
+	// Removed blocks and the alternative blocks on top of them are dropped
+	for _, b := range append(main, alternative...) {
+		assert.False(t, bc.HaveBlock(b.Hash()))
+	}
+	assert.Empty(t, bc.tips)
+
+	// Transactions of the removed blocks are returned to the pool
+	assert.NotNil(t, pool.GetTransaction(transaction.Hash()))
+
+	// Removed blocks can be added again
+	assert.Nil(t, bc.AddBlock(main[0], nil))
+	assert.Equal(t, main[0].Hash(), bc.TopBlock().Hash())
+}
+
+func TestBlockChain_IsSpent(t *testing.T) {
+	bc, s := testBlockChain(t)
+	image := crypto.KeyImage{1}
+
+	assert.False(t, bc.IsSpent(image, 10))
+
+	block := testMineBlock(t, bc, bc.TopBlock(), 1)
+	details := TransactionsDetails{spentKeyImages: []crypto.KeyImage{image}}
+	assert.Nil(t, s.PushBlock(block, testBlockInfo(s, block), details))
+
+	assert.False(t, bc.IsSpent(image, 0))
+	assert.True(t, bc.IsSpent(image, 1))
+	assert.True(t, bc.IsSpent(image, 10))
+
+	_, _, err := s.PopBlock()
+	assert.Nil(t, err)
+	assert.False(t, bc.IsSpent(image, 10))
+}
+
+func TestBlockChain_ExtractKeyOutputKeys(t *testing.T) {
+	bc, _ := testBlockChain(t)
+
+	block := testMineChain(t, bc, bc.TopBlock(), 1, 1)[0]
+	output := block.BaseTransaction.Outputs[0]
+	publicKey := output.Target.(OutputKey).PublicKey
+
+	keys, err := bc.ExtractKeyOutputKeys(output.Amount, 20, []uint32{0})
+	assert.Nil(t, err)
+	assert.Equal(t, []crypto.PublicKey{publicKey}, keys)
+
+	_, err = bc.ExtractKeyOutputKeys(output.Amount, 1, []uint32{0})
+	assert.Equal(t, ErrExtractOutputKeyLocked, err)
+
+	_, err = bc.ExtractKeyOutputKeys(output.Amount, 0, []uint32{0})
+	assert.Equal(t, ErrExtractOutputKeyInvalidGlobalIndex, err)
+
+	_, err = bc.ExtractKeyOutputKeys(output.Amount, 20, []uint32{0, 1})
+	assert.Equal(t, ErrExtractOutputKeyInvalidGlobalIndex, err)
+}
+
+func TestBlockChain_MultisignatureInput(t *testing.T) {
+	bc, s := testBlockChain(t)
+
+	var secretKeys []crypto.SecretKey
+	var publicKeys []crypto.PublicKey
+	for i := 0; i < 3; i++ {
+		secretKey, err := crypto.GenerateKey()
+		assert.Nil(t, err)
+		publicKey, err := crypto.PublicFromSecret(&secretKey)
+		assert.Nil(t, err)
+
+		secretKeys = append(secretKeys, secretKey)
+		publicKeys = append(publicKeys, *publicKey)
+	}
+
+	output := OutputMultisignature{Keys: publicKeys, RequiredSignaturesCount: 2}
+	block := testMineBlock(t, bc, bc.TopBlock(), 1)
+	details := TransactionsDetails{
+		transactions: []Transaction{{
+			TransactionPrefix: TransactionPrefix{
+				Version: config.TransactionVersion1,
+				Outputs: []TransactionOutput{{Amount: 10, Target: output}},
+				Extra:   []byte{},
+			},
+		}},
+		spentMultisignatureGlobalIndexes: []MultisigAmountGlobalOutputIndexPair{{Amount: 10, GlobalOutputIndex: 1}},
+	}
+	assert.Nil(t, s.PushBlock(block, testBlockInfo(s, block), details))
+
+	saved, unlockTime, exists := bc.IsMultiSignatureOutputExists(10, 0, 1)
+	assert.True(t, exists)
+	assert.Equal(t, &output, saved)
+	assert.Equal(t, uint64(0), unlockTime)
+
+	_, _, exists = bc.IsMultiSignatureOutputExists(10, 0, 0)
+	assert.False(t, exists)
+	_, _, exists = bc.IsMultiSignatureOutputExists(10, 1, 1)
+	assert.False(t, exists)
+
+	assert.False(t, bc.IsMultiSignatureSpent(10, 1, 0))
+	assert.True(t, bc.IsMultiSignatureSpent(10, 1, 1))
+	assert.False(t, bc.IsMultiSignatureSpent(10, 0, 1))
+
+	spend := func(keys ...int) *Transaction {
+		transaction := &Transaction{
+			TransactionPrefix: TransactionPrefix{
+				Version: config.TransactionVersion1,
+				Inputs:  []TransactionInput{InputMultiSignature{Amount: 10, SignatureCount: 2, OutputIndex: 0}},
+				Outputs: []TransactionOutput{{Amount: 10, Target: OutputKey{PublicKey: publicKeys[0]}}},
+				Extra:   []byte{},
+			},
+		}
+
+		var sigs []crypto.Signature
+		for _, key := range keys {
+			sig, err := transaction.TransactionPrefix.Hash().Sign(&secretKeys[key])
+			assert.Nil(t, err)
+			sigs = append(sigs, *sig)
+		}
+		transaction.TransactionSignatures = TransactionSignatures{sigs}
+
+		return transaction
+	}
+
+	// Signatures are checked only after the checkpoints zone
+	validator := NewBlockTransactionsValidator(bc, 1001, bc.logger)
+
+	assert.Nil(t, validator.validateTransactionInputExpensive(spend(0, 2)))
+	assert.Nil(t, validator.validateTransactionInputExpensive(spend(1, 2)))
+
+	// Signatures must follow the order of the keys
+	assert.Equal(t, ErrTransactionInputInvalidSignatures, validator.validateTransactionInputExpensive(spend(2, 0)))
+	assert.Equal(t, ErrTransactionInputInvalidSignatures, validator.validateTransactionInputExpensive(spend(1, 1)))
+	assert.Equal(t, ErrTransactionInputInvalidSignaturesCount, validator.validateTransactionInputExpensive(spend(0)))
+
+	missing := spend(0, 1)
+	missing.Inputs[0] = InputMultiSignature{Amount: 20, SignatureCount: 2, OutputIndex: 0}
+	assert.Equal(t, ErrTransactionInputInvalidGlobalIndex, validator.validateTransactionInputExpensive(missing))
+}
+
+func TestBlockChain_Height(t *testing.T) {
+	bc, _ := testBlockChain(t)
+	assert.Equal(t, uint32(1), bc.Height())
+
+	blocks := testMineChain(t, bc, bc.TopBlock(), 3, 1)
+	assert.Equal(t, uint32(4), bc.Height())
+
+	for i, block := range blocks {
+		index := uint32(i + 1)
+
+		assert.Equal(t, block.Hash(), bc.BlockByIndex(index).Hash())
+		assert.Equal(t, block.Hash(), bc.BlockByHash(block.Hash()).Hash())
+
+		blockIndex, err := bc.BlockIndexByHash(block.Hash())
+		assert.Nil(t, err)
+		assert.Equal(t, index, blockIndex)
+	}
+
+	assert.Nil(t, bc.BlockByIndex(4))
+
+	_, err := bc.BlockIndexByHash(&crypto.Hash{})
+	assert.Equal(t, ErrStorageBlockNotFound, err)
+}
+
+func TestBlockChain_BlockWithTransactions(t *testing.T) {
+	bc, _ := testBlockChain(t)
+
+	prev := testMineChain(t, bc, bc.TopBlock(), 1, 1)[0]
+	transaction := testTransaction(t, bc, prev.BaseTransaction.Outputs[0].Amount, testKeyImage(t))
+
+	block := testMineBlockWithTransactions(t, bc, prev, 1, []Transaction{transaction}, bc.Network.MinimalFeeValidator(2))
+	assert.Nil(t, bc.AddBlock(block, [][]byte{transaction.Serialize()}))
+
+	saved, transactions, err := bc.BlockWithTransactions(block.Hash())
+	assert.Nil(t, err)
+	assert.Equal(t, block.Hash(), saved.Hash())
+	assert.Len(t, transactions, 1)
+	assert.Equal(t, transaction.Hash(), transactions[0].Hash())
+
+	_, _, err = bc.BlockWithTransactions(&crypto.Hash{})
+	assert.Equal(t, ErrStorageBlockNotFound, err)
+}
+
+func TestBlockChain_GetTransaction(t *testing.T) {
+	bc, _ := testBlockChain(t)
+
+	prev := testMineChain(t, bc, bc.TopBlock(), 1, 1)[0]
+	transaction := testTransaction(t, bc, prev.BaseTransaction.Outputs[0].Amount, testKeyImage(t))
+	fee := bc.Network.MinimalFeeValidator(2)
+
+	block := testMineBlockWithTransactions(t, bc, prev, 1, []Transaction{transaction}, fee)
+	assert.Nil(t, bc.AddBlock(block, [][]byte{transaction.Serialize()}))
+
+	saved, savedBlock, err := bc.GetTransaction(transaction.Hash())
+	assert.Nil(t, err)
+	assert.Equal(t, transaction.Hash(), saved.Hash())
+	assert.Equal(t, block.Hash(), savedBlock.Hash())
+
+	saved, savedBlock, err = bc.GetTransaction(prev.BaseTransaction.Hash())
+	assert.Nil(t, err)
+	assert.Equal(t, prev.BaseTransaction.Hash(), saved.Hash())
+	assert.Equal(t, prev.Hash(), savedBlock.Hash())
+
+	_, _, err = bc.GetTransaction(&crypto.Hash{1})
+	assert.Equal(t, ErrStorageTransactionNotFound, err)
+
+	// Transaction already in the main chain can't be added again
+	duplicate := testMineBlockWithTransactions(t, bc, block, 1, []Transaction{transaction}, fee)
+	assert.Equal(t, ErrBlockValidationDuplicateTransaction, bc.AddBlock(duplicate, [][]byte{transaction.Serialize()}))
+}
+
+func TestBlockChain_BuildSparseChain(t *testing.T) {
+	bc, _ := testBlockChain(t)
+	genesisHash := *bc.TopBlock().Hash()
+
+	list, err := bc.BuildSparseChain()
+	assert.Nil(t, err)
+	assert.Equal(t, []crypto.Hash{genesisHash}, list)
+
+	blocks := testMineChain(t, bc, bc.TopBlock(), 5, 1)
+
+	list, err = bc.BuildSparseChain()
+	assert.Nil(t, err)
+	assert.Equal(t, []crypto.Hash{
+		*blocks[4].Hash(),
+		*blocks[3].Hash(),
+		*blocks[2].Hash(),
+		*blocks[0].Hash(),
+		genesisHash,
+	}, list)
+}
+
+func TestBlockChain_FindBlockchainSupplement(t *testing.T) {
+	bc, _ := testBlockChain(t)
+	genesisHash := *bc.TopBlock().Hash()
+	blocks := testMineChain(t, bc, bc.TopBlock(), 5, 1)
+
+	// Remote chain has unknown top block, common block is at index 2
+	remote := []crypto.Hash{{1}, *blocks[1].Hash(), genesisHash}
+
+	start, total, hashes, err := bc.FindBlockchainSupplement(remote, 10)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), start)
+	assert.Equal(t, uint32(6), total)
+	assert.Equal(t, []crypto.Hash{*blocks[1].Hash(), *blocks[2].Hash(), *blocks[3].Hash(), *blocks[4].Hash()}, hashes)
+
+	start, _, hashes, err = bc.FindBlockchainSupplement(remote, 2)
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(2), start)
+	assert.Equal(t, []crypto.Hash{*blocks[1].Hash(), *blocks[2].Hash()}, hashes)
+
+	_, _, _, err = bc.FindBlockchainSupplement([]crypto.Hash{{1}}, 10)
+	assert.Equal(t, ErrNoCommonBlock, err)
+}
+
+// testBlockChain returns initialized mainnet blockchain with memory storage.
+// Checkpoint far in the future is added, so proof of work is not checked for the test blocks.
+func testBlockChain(t *testing.T) (*BlockChain, Storage) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	s := NewMemoryStorage()
+	bc := NewBlockChain(config.MainNet(), s, logger)
+	assert.Nil(t, bc.Checkpoints.AddCheckpoint(1000, crypto.Hash{}))
+	assert.Nil(t, bc.Init())
+
+	return bc, s
+}
+
+// testMineChain adds count blocks on top of the prev block, nonce makes the blocks of different chains unique
+func testMineChain(t *testing.T, bc *BlockChain, prev *Block, count int, nonce uint32) []*Block {
+	var blocks []*Block
+
+	for i := 0; i < count; i++ {
+		block := testMineBlock(t, bc, prev, nonce)
+		assert.Nil(t, bc.AddBlock(block, nil))
+
+		blocks = append(blocks, block)
+		prev = block
+	}
+
+	return blocks
+}
+
+// testGeneratedCoinsIndex keeps coins generated by the chain ending with the block
+var testGeneratedCoinsIndex = map[crypto.Hash]uint64{}
+
+func testGeneratedCoins(block *Block) uint64 {
+	if block.Index() == 0 {
+		return block.BaseTransaction.Outputs[0].Amount
+	}
+
+	return testGeneratedCoinsIndex[*block.Hash()]
+}
+
+// testMineBlock creates valid version 1 block without transactions on top of the prev block
+func testMineBlock(t *testing.T, bc *BlockChain, prev *Block, nonce uint32) *Block {
+	return testMineBlockWithTransactions(t, bc, prev, nonce, nil, 0)
+}
+
+// testMineBlockWithTransactions creates valid version 1 block with the transactions paying the fee
+func testMineBlockWithTransactions(t *testing.T, bc *BlockChain, prev *Block, nonce uint32, transactions []Transaction, fee uint64) *Block {
+	secretKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	publicKey, err := crypto.PublicFromSecret(&secretKey)
+	assert.Nil(t, err)
+
+	index := prev.Index() + 1
+	alreadyGenerated := testGeneratedCoins(prev)
+
+	reward, emission, err := bc.Network.GetBlockReward(config.BlockMajorVersion1, 0, 0, alreadyGenerated, fee)
+	assert.Nil(t, err)
+
+	block := &Block{
+		BlockHeader: BlockHeader{
+			MajorVersion:      config.BlockMajorVersion1,
+			MinorVersion:      config.BlockMinorVersion0,
+			Timestamp:         prev.Timestamp + uint64(config.DifficultyTarget),
+			PreviousBlockHash: *prev.Hash(),
+			Nonce:             nonce,
+		},
+		BaseTransaction: Transaction{
+			TransactionPrefix: TransactionPrefix{
+				Version:      1,
+				UnlockHeight: uint64(index + bc.Network.MinedMoneyUnlockWindow()),
+				Inputs:       []TransactionInput{InputCoinbase{BlockIndex: index}},
+				Outputs: []TransactionOutput{
+					{Amount: reward, Target: OutputKey{PublicKey: *publicKey}},
+				},
+				Extra: []byte{},
+			},
+		},
+	}
+
+	for i := range transactions {
+		block.TransactionsHashes = append(block.TransactionsHashes, *transactions[i].Hash())
+	}
+
+	testGeneratedCoinsIndex[*block.Hash()] = alreadyGenerated + emission
+
+	return block
+}
+
+func TestBlockChain_CheckProofOfWork(t *testing.T) {
+	bc := NewBlockChain(config.MainNet(), NewMemoryStorage(), logrus.New())
+	assert.Nil(t, bc.Init())
+
+	// Mainnet blocks are added without checkpoints, so proof of work is checked at the real difficulty
+	for _, name := range []string{"block1.dat", "block2.dat", "block3.dat"} {
+		assert.Nil(t, bc.AddBlock(testLoadBlock(t, "./fixtures/"+name), nil), name)
+	}
+
+	block := bc.TopBlock()
+	info := bc.BlockInfo(block.Index())
+	difficulty := info.CumulativeDifficulty - bc.BlockInfo(block.Index()-1).CumulativeDifficulty
+	assert.Equal(t, uint64(240), difficulty)
+	assert.Nil(t, bc.checkProofOfWork(block, difficulty))
+	assert.Equal(t, ErrBlockValidationProofOfWorkTooWeak, bc.checkProofOfWork(block, math.MaxUint64))
+
+	block.MajorVersion = config.BlockMajorVersion5
+	assert.Equal(t, ErrBlockValidationProofOfWorkNotSupported, bc.checkProofOfWork(block, 1))
+}
+
+func TestBlockChain_CheckProofOfWorkMergeMining(t *testing.T) {
+	bc := NewBlockChain(config.MainNet(), nil, logrus.New())
+
+	block := testLoadBlock(t, "./fixtures/block_200054.dat")
+	assert.Nil(t, bc.checkProofOfWork(block, 100000000))
+	assert.Equal(t, ErrBlockValidationProofOfWorkTooWeak, bc.checkProofOfWork(block, math.MaxUint64))
+
+	block.Parent.BlockchainBranch[0] = crypto.Hash{}
+	assert.Equal(t, ErrBlockValidationMergeMiningAuxBlockNotFound, bc.checkProofOfWork(block, 1))
+}
@@ -1,43 +1,60 @@
 package cryptonote
 
 import (
-	"errors"
-	"fmt"
+	"encoding/binary"
+	"github.com/r3volut1oner/go-karbo/crypto"
 	"github.com/r3volut1oner/go-karbo/utils"
 )
 
 // difficultyForNextBlock calculates difficulty for the next block.
+// Block may belong to the main or to the alternative chain.
 func (bc *BlockChain) difficultyForNextBlock(b *Block) (uint64, error) {
-	if b.Index() > bc.bestTip.Index() {
-		return 0, errors.New(fmt.Sprintf("unknown block hashIndex %d, top hashIndex is %d", b.Index(), bc.bestTip.Index()))
-	}
-
 	nextBlockMajorVersion := bc.Network.GetBlockMajorVersion(b.Index())
 	difficultyBlocksCount := bc.Network.DifficultyBlocksCountByBlockVersion(nextBlockMajorVersion)
 
 	timestamps := bc.lastBlocksTimestamps(difficultyBlocksCount, b, false)
-	cumulativeDifficulties := bc.lastBlocksCumulativeDifficulties(difficultyBlocksCount, b.Index(), false)
+	cumulativeDifficulties := bc.lastBlocksCumulativeDifficulties(difficultyBlocksCount, b, false)
 
 	return bc.Network.NextDifficulty(b.Index(), nextBlockMajorVersion, timestamps, cumulativeDifficulties)
 }
 
-func (bc *BlockChain) lastBlocksCumulativeDifficulties(count uint32, index uint32, addGenesisBlock bool) []uint64 {
-	difficulties := []uint64{}
+// lastBlocksCumulativeDifficulties fetches the cumulative difficulties of the last count blocks ending with provided block
+func (bc *BlockChain) lastBlocksCumulativeDifficulties(count uint32, b *Block, addGenesisBlock bool) []uint64 {
+	infos := bc.lastBlocksInfos(count, b, addGenesisBlock)
 
-	tempInfo := bc.storage.getBlockInfoAtIndex(index)
-	for i := uint32(1); i <= count; i++ {
-		if tempInfo == nil {
-			break
-		}
+	difficulties := make([]uint64, len(infos))
+	for i, info := range infos {
+		difficulties[i] = info.CumulativeDifficulty
+	}
+
+	return difficulties
+}
+
+// checkHash checks that hash multiplied by difficulty fits in 256 bits.
+// Hash is treated as 256 bits little endian number.
+// it is the "check_hash" method in C++ implementation
+func checkHash(hash crypto.Hash, difficulty uint64) bool {
+	var words [4]uint64
+	for i := range words {
+		words[i] = binary.LittleEndian.Uint64(hash[i*8:])
+	}
+
+	// First check the highest word, this will most likely fail for a random hash
+	if _, high := utils.Mul128(words[3], difficulty); high != 0 {
+		return false
+	}
+
+	carry := uint64(0)
+	for _, word := range words {
+		low, high := utils.Mul128(word, difficulty)
 
-		if !addGenesisBlock && tempInfo.Index == 0 {
-			break
+		low += carry
+		if low < carry {
+			high++
 		}
 
-		difficulties = append(difficulties, tempInfo.CumulativeDifficulty)
-		tempInfo = bc.storage.getBlockInfoAtIndex(index - i)
-		count--
+		carry = high
 	}
 
-	return utils.SliceReverse(difficulties)
+	return carry == 0
 }
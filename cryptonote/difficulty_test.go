@@ -0,0 +1,30 @@
+package cryptonote
+
+import (
+	"github.com/r3volut1oner/go-karbo/crypto"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestCheckHash(t *testing.T) {
+	assert.True(t, checkHash(crypto.Hash{}, math.MaxUint64))
+
+	hash := crypto.Hash{}
+	hash[31] = 0x80
+	assert.True(t, checkHash(hash, 1))
+	assert.False(t, checkHash(hash, 2))
+
+	// Overflow caused only by the carry from the lower words
+	hash = crypto.Hash{}
+	for i := 24; i < 32; i++ {
+		hash[i] = 0x55
+	}
+	assert.True(t, checkHash(hash, 3))
+	hash[23] = 0x60
+	assert.False(t, checkHash(hash, 3))
+
+	hash = crypto.Hash{}
+	hash[0] = 0xff
+	assert.True(t, checkHash(hash, math.MaxUint64))
+}
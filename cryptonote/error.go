@@ -2,86 +2,145 @@ package cryptonote
 
 import "errors"
 
+// Categories of the AddBlock errors, category of the error is checked with errors.Is.
+// Errors without category are the internal errors not caused by the block.
+// src/CryptoNoteCore/Core.cpp AddBlockErrorCondition
 var (
-	ErrAddBlockAlreadyExists              = errors.New("block already exists")
-	ErrAddBlockPrevBlockIndexMismatch     = errors.New("block hashIndex and prev block hashIndex don't match")
-	ErrAddBlockTransactionCountNotMatch   = errors.New("transaction sizes not match")
-	ErrAddBlockTransactionSizeMax         = errors.New("transaction size bigger than allowed")
-	ErrAddBlockTransactionCoinbaseMaxSize = errors.New("coinbase transaction size bigger than allowed")
-	ErrAddBlockTransactionDeserialization = errors.New("transaction deserialization failed")
-	ErrAddBlockRejectedAsOrphaned         = errors.New("rejected as orphaned")
+	ErrCategoryValidationFailed = errors.New("block validation failed")
+	ErrCategoryRejected         = errors.New("block rejected")
+	ErrCategoryAlreadyExists    = errors.New("block already exists")
+	ErrCategoryDeserialization  = errors.New("block deserialization failed")
+)
+
+// categoryError is the error belonging to the category
+type categoryError struct {
+	message  string
+	category error
+
+	// err is the wrapped error of the other package, nil if error is created by this package
+	err error
+}
+
+func newCategoryError(category error, message string) error {
+	return &categoryError{message: message, category: category}
+}
+
+// wrapCategoryError adds the category to the error, the wrapped error is still matched by errors.Is
+func wrapCategoryError(category error, err error) error {
+	return &categoryError{message: err.Error(), category: category, err: err}
+}
+
+func (e *categoryError) Error() string {
+	return e.message
+}
+
+// Is reports whether the error belongs to the category
+func (e *categoryError) Is(target error) bool {
+	return target == e.category
+}
+
+func (e *categoryError) Unwrap() error {
+	return e.err
+}
+
+var (
+	ErrAddBlockAlreadyExists              = newCategoryError(ErrCategoryAlreadyExists, "block already exists")
+	ErrAddBlockPrevBlockIndexMismatch     = newCategoryError(ErrCategoryValidationFailed, "block hashIndex and prev block hashIndex don't match")
+	ErrAddBlockTransactionCountNotMatch   = newCategoryError(ErrCategoryDeserialization, "transaction sizes not match")
+	ErrAddBlockTransactionSizeMax         = newCategoryError(ErrCategoryValidationFailed, "transaction size bigger than allowed")
+	ErrAddBlockTransactionCoinbaseMaxSize = newCategoryError(ErrCategoryValidationFailed, "coinbase transaction size bigger than allowed")
+	ErrAddBlockTransactionDeserialization = newCategoryError(ErrCategoryDeserialization, "transaction deserialization failed")
+	ErrAddBlockRejectedAsOrphaned         = newCategoryError(ErrCategoryRejected, "rejected as orphaned")
 
-	ErrAddBlockFailedGetDifficulty = errors.New("failed to get difficulty for next block")
+	ErrAddBlockFailedGetDifficulty = newCategoryError(ErrCategoryValidationFailed, "failed to get difficulty for next block")
 
 	// ErrAddBlockUnexpectedError any unpredictable error
 	ErrAddBlockUnexpectedError = errors.New("unexpected error")
 )
 
 var (
-	ErrBlockValidationCumulativeSizeTooBig        = errors.New("cumulative size too big")
-	ErrBlockValidationWrongVersion                = errors.New("wrong block version")
-	ErrBlockValidationParentBlockSizeTooBig       = errors.New("parent block size too big")
-	ErrBlockValidationParentBlockWrongVersion     = errors.New("parent block wrong version")
-	ErrBlockValidationTimestampTooFarInFuture     = errors.New("timestamp too far in future")
-	ErrBlockValidationTimestampTooFarInPast       = errors.New("timestamp too far in past")
-	ErrBlockValidationDifficultyOverhead          = errors.New("difficulty overhead")
-	ErrBlockValidationBlockRewardMismatch         = errors.New("block reward mismatch")
-	ErrBlockValidationBlockSignatureMismatch      = errors.New("block signature mismatch")
-	ErrBlockValidationCheckpointBlockHashMismatch = errors.New("checkout block hash mismatch")
-	ErrBlockValidationProofOfWorkTooWeak          = errors.New("proof of work too weak")
-	ErrBlockValidationTransactionAbsentInPool     = errors.New("transaction absent in pool")
-	ErrBlockValidationBaseTransactionExtraMMTag   = errors.New("base transaction extra MM tag")
-	ErrBlockValidationTransactionInconsistency    = errors.New("transaction inconsistency")
-	ErrBlockValidationDuplicateTransaction        = errors.New("duplicate transaction")
+	ErrBlockValidationCumulativeSizeTooBig        = newCategoryError(ErrCategoryValidationFailed, "cumulative size too big")
+	ErrBlockValidationWrongVersion                = newCategoryError(ErrCategoryValidationFailed, "wrong block version")
+	ErrBlockValidationParentBlockSizeTooBig       = newCategoryError(ErrCategoryValidationFailed, "parent block size too big")
+	ErrBlockValidationParentBlockWrongVersion     = newCategoryError(ErrCategoryValidationFailed, "parent block wrong version")
+	ErrBlockValidationTimestampTooFarInFuture     = newCategoryError(ErrCategoryValidationFailed, "timestamp too far in future")
+	ErrBlockValidationTimestampTooFarInPast       = newCategoryError(ErrCategoryValidationFailed, "timestamp too far in past")
+	ErrBlockValidationDifficultyOverhead          = newCategoryError(ErrCategoryValidationFailed, "difficulty overhead")
+	ErrBlockValidationBlockRewardMismatch         = newCategoryError(ErrCategoryValidationFailed, "block reward mismatch")
+	ErrBlockValidationBlockSignatureMismatch      = newCategoryError(ErrCategoryValidationFailed, "block signature mismatch")
+	ErrBlockValidationCheckpointBlockHashMismatch = newCategoryError(ErrCategoryValidationFailed, "checkout block hash mismatch")
+	ErrBlockValidationProofOfWorkTooWeak          = newCategoryError(ErrCategoryValidationFailed, "proof of work too weak")
+	ErrBlockValidationProofOfWorkNotSupported     = newCategoryError(ErrCategoryValidationFailed, "proof of work not supported for the block version")
+	ErrBlockValidationMergeMiningTagNotFound      = newCategoryError(ErrCategoryValidationFailed, "merge mining tag wasn't found in extra of the parent block miner transaction")
+	ErrBlockValidationMergeMiningBranchTooLong    = newCategoryError(ErrCategoryValidationFailed, "blockchain branch of the parent block too long")
+	ErrBlockValidationMergeMiningAuxBlockNotFound = newCategoryError(ErrCategoryValidationFailed, "aux block hash wasn't found in merkle tree")
+	ErrBlockValidationTransactionAbsentInPool     = newCategoryError(ErrCategoryValidationFailed, "transaction absent in pool")
+	ErrBlockValidationBaseTransactionExtraMMTag   = newCategoryError(ErrCategoryValidationFailed, "base transaction extra MM tag")
+	ErrBlockValidationTransactionInconsistency    = newCategoryError(ErrCategoryValidationFailed, "transaction inconsistency")
+	ErrBlockValidationDuplicateTransaction        = newCategoryError(ErrCategoryValidationFailed, "duplicate transaction")
 )
 
 var (
-	ErrTransactionEmptyInputs                          = errors.New("transaction has no inputs")
-	ErrTransactionInputUnknownType                     = errors.New("transaction has input with unknown type")
-	ErrTransactionInputEmptyOutputUsage                = errors.New("transaction's input uses empty output")
-	ErrTransactionInputInvalidDomainKeyImages          = errors.New("transaction uses key image not in the valid domain")
-	ErrTransactionInputIdenticalKeyImages              = errors.New("transaction has identical key images")
-	ErrTransactionInputIdenticalOutputIndexes          = errors.New("transaction has identical output indexes")
-	ErrTransactionInputKeyImageAlreadySpent            = errors.New("transaction uses spent key image")
-	ErrTransactionInputMultisignatureAlreadySpent      = errors.New("transaction uses spent multisignature")
-	ErrTransactionInputInvalidGlobalIndex              = errors.New("transaction has input with invalid global hashIndex")
-	ErrTransactionInputSpendLockedOut                  = errors.New("transaction uses locked input")
-	ErrTransactionInputInvalidSignatures               = errors.New("transaction has input with invalid signature")
-	ErrTransactionInputWrongSignaturesCount            = errors.New("transaction has input with wrong signatures count")
-	ErrTransactionInputsAmountOverflow                 = errors.New("transaction's inputs sum overflow")
-	ErrTransactionInputWrongCount                      = errors.New("wrong input count")
-	ErrTransactionInputUnexpectedType                  = errors.New("wrong input type")
-	ErrTransactionBaseInputWrongBlockIndex             = errors.New("base input has wrong block hashIndex")
-	ErrTransactionOutputZeroAmount                     = errors.New("transaction has zero output amount")
-	ErrTransactionOutputInvalidKey                     = errors.New("transaction has output with invalid key")
-	ErrTransactionOutputInvalidRequiredSignaturesCount = errors.New("transaction has output with invalid signatures count")
-	ErrTransactionOutputInvalidMultisignatureKey       = errors.New("transaction has output with invalid multisignature key")
-	ErrTransactionOutputUnknownType                    = errors.New("transaction has unknown output type")
-	ErrTransactionOutputsAmountOverflow                = errors.New("transaction has outputs amount overflow")
-	ErrTransactionWrongAmount                          = errors.New("transaction wrong amount")
-	ErrTransactionWrongUnlockTime                      = errors.New("transaction has wrong unlock time")
-	ErrTransactionInvalidMixin                         = errors.New("transaction has wrong mixin")
-	ErrTransactionExtraTooLarge                        = errors.New("transaction extra is too large")
-	ErrTransactionBaseInvalidSignaturesCount           = errors.New("coinbase transactions must not have input signatures")
-	ErrTransactionInputInvalidSignaturesCount          = errors.New("the number of input signatures is not correct")
-	ErrTransactionOutputInvalidDecomposedAmount        = errors.New("invalid decomposed output amount (unmixable output)")
-	ErrTransactionInvalidFee                           = errors.New("fee is too small and it's not a fusion transaction")
-	ErrTransactionSizeTooLarge                         = errors.New("transaction is too large (in bytes)")
-	ErrTransactionOutputsInvalidCount                  = errors.New("only 1 output in coinbase transaction allowed")
-	ErrTransactionBaseOutputWrongType                  = errors.New("coinbase transaction can have only output key output type")
+	ErrTransactionEmptyInputs                          = newCategoryError(ErrCategoryValidationFailed, "transaction has no inputs")
+	ErrTransactionInputUnknownType                     = newCategoryError(ErrCategoryValidationFailed, "transaction has input with unknown type")
+	ErrTransactionInputEmptyOutputUsage                = newCategoryError(ErrCategoryValidationFailed, "transaction's input uses empty output")
+	ErrTransactionInputInvalidDomainKeyImages          = newCategoryError(ErrCategoryValidationFailed, "transaction uses key image not in the valid domain")
+	ErrTransactionInputIdenticalKeyImages              = newCategoryError(ErrCategoryValidationFailed, "transaction has identical key images")
+	ErrTransactionInputIdenticalOutputIndexes          = newCategoryError(ErrCategoryValidationFailed, "transaction has identical output indexes")
+	ErrTransactionInputKeyImageAlreadySpent            = newCategoryError(ErrCategoryValidationFailed, "transaction uses spent key image")
+	ErrTransactionInputMultisignatureAlreadySpent      = newCategoryError(ErrCategoryValidationFailed, "transaction uses spent multisignature")
+	ErrTransactionInputInvalidGlobalIndex              = newCategoryError(ErrCategoryValidationFailed, "transaction has input with invalid global hashIndex")
+	ErrTransactionInputSpendLockedOut                  = newCategoryError(ErrCategoryValidationFailed, "transaction uses locked input")
+	ErrTransactionInputInvalidSignatures               = newCategoryError(ErrCategoryValidationFailed, "transaction has input with invalid signature")
+	ErrTransactionInputWrongSignaturesCount            = newCategoryError(ErrCategoryValidationFailed, "transaction has input with wrong signatures count")
+	ErrTransactionInputsAmountOverflow                 = newCategoryError(ErrCategoryValidationFailed, "transaction's inputs sum overflow")
+	ErrTransactionInputWrongCount                      = newCategoryError(ErrCategoryValidationFailed, "wrong input count")
+	ErrTransactionInputUnexpectedType                  = newCategoryError(ErrCategoryValidationFailed, "wrong input type")
+	ErrTransactionBaseInputWrongBlockIndex             = newCategoryError(ErrCategoryValidationFailed, "base input has wrong block hashIndex")
+	ErrTransactionOutputZeroAmount                     = newCategoryError(ErrCategoryValidationFailed, "transaction has zero output amount")
+	ErrTransactionOutputInvalidKey                     = newCategoryError(ErrCategoryValidationFailed, "transaction has output with invalid key")
+	ErrTransactionOutputInvalidRequiredSignaturesCount = newCategoryError(ErrCategoryValidationFailed, "transaction has output with invalid signatures count")
+	ErrTransactionOutputInvalidMultisignatureKey       = newCategoryError(ErrCategoryValidationFailed, "transaction has output with invalid multisignature key")
+	ErrTransactionOutputUnknownType                    = newCategoryError(ErrCategoryValidationFailed, "transaction has unknown output type")
+	ErrTransactionOutputsAmountOverflow                = newCategoryError(ErrCategoryValidationFailed, "transaction has outputs amount overflow")
+	ErrTransactionWrongAmount                          = newCategoryError(ErrCategoryValidationFailed, "transaction wrong amount")
+	ErrTransactionWrongUnlockTime                      = newCategoryError(ErrCategoryValidationFailed, "transaction has wrong unlock time")
+	ErrTransactionInvalidMixin                         = newCategoryError(ErrCategoryValidationFailed, "transaction has wrong mixin")
+	ErrTransactionExtraTooLarge                        = newCategoryError(ErrCategoryValidationFailed, "transaction extra is too large")
+	ErrTransactionBaseInvalidSignaturesCount           = newCategoryError(ErrCategoryValidationFailed, "coinbase transactions must not have input signatures")
+	ErrTransactionInputInvalidSignaturesCount          = newCategoryError(ErrCategoryValidationFailed, "the number of input signatures is not correct")
+	ErrTransactionOutputInvalidDecomposedAmount        = newCategoryError(ErrCategoryValidationFailed, "invalid decomposed output amount (unmixable output)")
+	ErrTransactionInvalidFee                           = newCategoryError(ErrCategoryValidationFailed, "fee is too small and it's not a fusion transaction")
+	ErrTransactionSizeTooLarge                         = newCategoryError(ErrCategoryValidationFailed, "transaction is too large (in bytes)")
+	ErrTransactionOutputsInvalidCount                  = newCategoryError(ErrCategoryValidationFailed, "only 1 output in coinbase transaction allowed")
+	ErrTransactionBaseOutputWrongType                  = newCategoryError(ErrCategoryValidationFailed, "coinbase transaction can have only output key output type")
 	ErrTransactionUnknownError                         = errors.New("unknown error")
-	ErrTransactionMultiSignaturesNotImplemented        = errors.New("multisignatures not implemented")
 )
 
 var (
-	ErrTransactionFusionMaxSize                    = errors.New("fusion transaction verification failed: size exceeded max allowed size")
-	ErrTransactionFusionInputCountsLessThanMinimum = errors.New("fusion transaction verification failed: inputs count is less than minimum")
-	ErrTransactionFusionRatioInvalid               = errors.New("fusion transaction verification failed: inputs to outputs count ratio is less than minimum")
-	ErrTransactionFusionAmountLessThenThreshold    = errors.New("fusion transaction verification failed: amount is less than dust threshold")
-	ErrTransactionFusionDecomposedNotMatch         = errors.New("fusion transaction verification failed: decomposed output amounts do not match expected")
+	ErrTransactionFusionMaxSize                    = newCategoryError(ErrCategoryValidationFailed, "fusion transaction verification failed: size exceeded max allowed size")
+	ErrTransactionFusionInputCountsLessThanMinimum = newCategoryError(ErrCategoryValidationFailed, "fusion transaction verification failed: inputs count is less than minimum")
+	ErrTransactionFusionRatioInvalid               = newCategoryError(ErrCategoryValidationFailed, "fusion transaction verification failed: inputs to outputs count ratio is less than minimum")
+	ErrTransactionFusionAmountLessThenThreshold    = newCategoryError(ErrCategoryValidationFailed, "fusion transaction verification failed: amount is less than dust threshold")
+	ErrTransactionFusionDecomposedNotMatch         = newCategoryError(ErrCategoryValidationFailed, "fusion transaction verification failed: decomposed output amounts do not match expected")
 )
 
 var (
 	ErrExtractOutputKeyInvalidGlobalIndex = errors.New("invalid global hashIndex")
 	ErrExtractOutputKeyLocked             = errors.New("output locked")
 )
+
+var (
+	ErrNoCommonBlock = errors.New("no common block found in the sparse chain")
+)
+
+var (
+	ErrRollbackAboveTop = errors.New("rollback index is above the top block")
+)
+
+var (
+	ErrTxPoolTransactionExists      = errors.New("transaction already exists in pool")
+	ErrTxPoolTransactionInChain     = errors.New("transaction already exists in blockchain")
+	ErrTxPoolKeyImageConflict       = errors.New("transaction key image already spent in pool")
+	ErrTxPoolMultisignatureConflict = errors.New("transaction multisignature output already spent in pool")
+)
@@ -9,6 +9,14 @@ var (
 	ErrStorageNetworkMismatch = errors.New("storage network mismatch")
 
 	ErrStorageBlockExists = errors.New("block exists in storage")
+
+	ErrStorageBlockNotFound = errors.New("block not found in storage")
+
+	ErrStorageBlockNotNext = errors.New("block is not next to the top block in storage")
+
+	ErrStoragePopGenesis = errors.New("genesis block can't be popped from storage")
+
+	ErrStorageTransactionNotFound = errors.New("transaction not found in storage")
 )
 
 // Storage used by blockchain for storing blocks information.
@@ -31,6 +39,10 @@ type Storage interface {
 	// PushBlock to the blockchain storage.
 	PushBlock(*Block, *blockInfo, TransactionsDetails) error
 
+	// PopBlock removes the top block from the storage.
+	// Returns removed block with its transactions.
+	PopBlock() (*Block, []Transaction, error)
+
 	// HaveBlock verifies that block is saved in DB
 	HaveBlock(*crypto.Hash) bool
 
@@ -43,17 +55,28 @@ type Storage interface {
 	//       with some different method there.
 	HashAtIndex(uint32) (*crypto.Hash, error)
 
+	// GetBlockIndexByHash returns index of the block represented by provided hash.
+	// Returns ErrStorageBlockNotFound if block not found.
+	GetBlockIndexByHash(*crypto.Hash) (uint32, error)
+
+	// GetBlockByHeight returns block at the index.
+	// Returns ErrStorageBlockNotFound if block not found.
+	GetBlockByHeight(uint32) (*Block, error)
+
+	// GetBlockTransactions returns transactions of the block at the index, coinbase transaction is not included.
+	// Returns ErrStorageBlockNotFound if block not found.
+	GetBlockTransactions(uint32) ([]Transaction, error)
+
+	// HaveTransaction verifies that transaction is saved in the main chain, coinbase transactions included.
+	HaveTransaction(*crypto.Hash) bool
+
+	// GetTransaction returns transaction represented by provided hash with the block containing it.
+	// Returns ErrStorageTransactionNotFound if transaction not found.
+	GetTransaction(*crypto.Hash) (*Transaction, *Block, error)
+
 	// Close database connection
 	Close() error
 
-	//// GetBlockIndexByHash returns height for specific block hash
-	//GetBlockIndexByHash(*crypto.Hash) (uint32, error)
-	//
-	//// GetBlockByHeight returns block by height
-	//GetBlockByHeight(uint32) (*Block, error)
-	//
-	//
-	//
 	//// IsEmpty checks if database is new and empty
 	//IsEmpty() (bool, error)
 	//
@@ -64,6 +87,22 @@ type Storage interface {
 
 	// getBlockInfoAtIndex return block info at specified index
 	getBlockInfoAtIndex(index uint32) *blockInfo
+
+	// getKeyImageSpentIndex returns index of the block where key image was spent.
+	// Returns false if key image is not spent in the main chain.
+	getKeyImageSpentIndex(image crypto.KeyImage) (uint32, bool)
+
+	// getKeyOutput returns key output by amount and its global index.
+	// Returns nil if output not exists.
+	getKeyOutput(amount uint64, globalIndex uint32) *keyOutput
+
+	// getMultisignatureOutput returns multisignature output by amount and its global index.
+	// Returns nil if output not exists.
+	getMultisignatureOutput(amount uint64, globalIndex uint32) *multisignatureOutput
+
+	// getMultisignatureSpentIndex returns index of the block where multisignature output was spent.
+	// Returns false if output is not spent in the main chain.
+	getMultisignatureSpentIndex(amount uint64, globalIndex uint32) (uint32, bool)
 }
 
 type MultisigAmountGlobalOutputIndexPair struct {
@@ -79,3 +118,92 @@ type TransactionsDetails struct {
 
 	spentMultisignatureGlobalIndexes []MultisigAmountGlobalOutputIndexPair
 }
+
+// transactionPosition is the place of the transaction in the main chain.
+// Coinbase transaction has index 0, block transactions follow it in the block order.
+type transactionPosition struct {
+	BlockIndex uint32
+	Index      uint32
+}
+
+// blockTransactionsHashes returns hashes of the coinbase transaction and then of the block transactions,
+// position in the slice is the transaction index in the block.
+func blockTransactionsHashes(block *Block, transactions []Transaction) []crypto.Hash {
+	hashes := []crypto.Hash{*block.BaseTransaction.Hash()}
+	for i := range transactions {
+		hashes = append(hashes, *transactions[i].Hash())
+	}
+
+	return hashes
+}
+
+// keyOutput is the key output saved in the global outputs index.
+// Global index is the position of the output between all the outputs with same amount.
+type keyOutput struct {
+	Amount     uint64
+	PublicKey  crypto.PublicKey
+	UnlockTime uint64
+	BlockIndex uint32
+}
+
+// blockKeyOutputs collects key outputs of the coinbase transaction and then of the block transactions in their order.
+// Outputs are assigned global indexes in the same order.
+func blockKeyOutputs(block *Block, transactions []Transaction) []keyOutput {
+	var outputs []keyOutput
+
+	collect := func(transaction *Transaction) {
+		for _, output := range transaction.Outputs {
+			if target, ok := output.Target.(OutputKey); ok {
+				outputs = append(outputs, keyOutput{
+					Amount:     output.Amount,
+					PublicKey:  target.PublicKey,
+					UnlockTime: transaction.UnlockHeight,
+					BlockIndex: block.Index(),
+				})
+			}
+		}
+	}
+
+	collect(&block.BaseTransaction)
+	for i := range transactions {
+		collect(&transactions[i])
+	}
+
+	return outputs
+}
+
+// multisignatureOutput is the multisignature output saved in the global outputs index.
+// Multisignature outputs have own global indexes, separate from the key outputs with same amount.
+type multisignatureOutput struct {
+	Amount                  uint64
+	Keys                    []crypto.PublicKey
+	RequiredSignaturesCount byte
+	UnlockTime              uint64
+	BlockIndex              uint32
+}
+
+// blockMultisignatureOutputs collects multisignature outputs in the same order as blockKeyOutputs.
+func blockMultisignatureOutputs(block *Block, transactions []Transaction) []multisignatureOutput {
+	var outputs []multisignatureOutput
+
+	collect := func(transaction *Transaction) {
+		for _, output := range transaction.Outputs {
+			if target, ok := output.Target.(OutputMultisignature); ok {
+				outputs = append(outputs, multisignatureOutput{
+					Amount:                  output.Amount,
+					Keys:                    target.Keys,
+					RequiredSignaturesCount: target.RequiredSignaturesCount,
+					UnlockTime:              transaction.UnlockHeight,
+					BlockIndex:              block.Index(),
+				})
+			}
+		}
+	}
+
+	collect(&block.BaseTransaction)
+	for i := range transactions {
+		collect(&transactions[i])
+	}
+
+	return outputs
+}
@@ -1,327 +1,907 @@
 package cryptonote
 
-//import (
-//	"bytes"
-//	"encoding/binary"
-//	"errors"
-//	"github.com/dgraph-io/badger/v3"
-//	"github.com/r3volut1oner/go-karbo/crypto"
-//)
-//
-//var (
-//	ErrBlockExists = errors.New("block already exists")
-//
-//	ErrBlockNotFound = errors.New("block not found")
-//)
-//
-//type badgerDB struct {
-//	badger *badger.DB
-//}
-//
-//type storeTxn struct {
-//	*badger.Txn
-//}
-//
-//func itob(i uint64) []byte {
-//	var buf [8]byte
-//	binary.LittleEndian.PutUint64(buf[:], i)
-//	return buf[:]
-//}
-//
-//func btoi(b []byte) uint64 {
-//	return binary.LittleEndian.Uint64(b)
-//}
-//
-//func keyBlockByHash(h *crypto.Hash) []byte {
-//	return []byte("block-" + h.String())
-//}
-//
-//func keyBlockHeightByHash(h *crypto.Hash) []byte {
-//	return []byte("block-height-" + h.String())
-//}
-//
-//func keyBlockHashByHeight(h uint32) []byte {
-//	return append([]byte("block-hashIndex-"), itob(uint64(h))...)
-//}
-//
-//func keyHeight() []byte {
-//	return []byte("block-height")
-//}
-//
-//func NewBadgerDB() (Storage, error) {
-//	db, err := badger.Open(
-//		badger.DefaultOptions("./.badger"),
-//	)
-//
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	return &badgerDB{
-//		badger: db,
-//	}, nil
-//}
-//
-//func (db *badgerDB) Init(genesisBlock *Block) error {
-//	genesisHash := genesisBlock.Hash()
-//
-//	height, err := db.GetBlockIndexByHash(genesisHash)
-//
-//	if err == ErrBlockNotFound {
-//		if err := db.PushBlock(genesisBlock); err != nil {
-//			return err
-//		}
-//	} else if err != nil {
-//		return err
-//	} else if height != 0 {
-//		return errors.New("genesis genesisBlock height is not match")
-//	}
-//
-//	return nil
-//}
-//
-//func (db *badgerDB) TopBlock() (*Block, error) {
-//	return nil, nil
-//}
-//
-//func (db *badgerDB) PushBlock(b *Block) error {
-//	hash := b.Hash()
-//	payload := b.Serialize()
-//
-//	return db.badger.Update(func(txn *badger.Txn) error {
-//		keyHeight := keyHeight()
-//		keyBlock := keyBlockByHash(hash)
-//		keyHashByHeight := keyBlockHeightByHash(hash)
-//
-//		if _, err := txn.Get(keyBlock); err == nil {
-//			return ErrBlockExists
-//		}
-//
-//		blockHeight := uint64(0)
-//		heightItem, err := txn.Get(keyHeight)
-//
-//		if err != nil && err != badger.ErrKeyNotFound {
-//			return err
-//		} else if err == nil {
-//			heightPayload, err := heightItem.ValueCopy(nil)
-//			if err != nil {
-//				return err
-//			}
-//
-//			blockHeight = btoi(heightPayload) + 1
-//		}
-//
-//		keyBlockHeight := keyBlockHashByHeight(uint32(blockHeight))
-//
-//		if err := txn.Set(keyBlock, payload); err != nil {
-//			return err
-//		}
-//
-//		if err := txn.Set(keyHeight, itob(blockHeight)); err != nil {
-//			return err
-//		}
-//
-//		if err := txn.Set(keyHashByHeight, itob(blockHeight)); err != nil {
-//			return err
-//		}
-//
-//		if err := txn.Set(keyBlockHeight, hash[:]); err != nil {
-//			return err
-//		}
-//
-//		return nil
-//	})
-//}
-//
-//func (db *badgerDB) HasBlock(hash *crypto.Hash) (bool, error) {
-//	hasBlock := false
-//
-//	err := db.badger.View(func(txn *badger.Txn) error {
-//		_, err := txn.Get(keyBlockByHash(hash))
-//
-//		if err != nil && err != badger.ErrKeyNotFound {
-//			return err
-//		}
-//
-//		hasBlock = err != badger.ErrKeyNotFound
-//
-//		return nil
-//	})
-//
-//	if err != nil {
-//		return false, err
-//	}
-//
-//	return hasBlock, nil
-//}
-//
-//func (db *badgerDB) HashAtIndex(height uint32) (*crypto.Hash, error) {
-//	var h *crypto.Hash
-//
-//	err := db.badger.View(func(txn *badger.Txn) error {
-//		stxn := &storeTxn{txn}
-//
-//		foundHash, err := stxn.getHashByKey(keyBlockHashByHeight(height))
-//		if err != nil {
-//			return err
-//		}
-//
-//		h = foundHash
-//
-//		return nil
-//	})
-//
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	return h, err
-//}
-//
-//func (db *badgerDB) GetBlockIndexByHash(h *crypto.Hash) (uint32, error) {
-//	var height uint32
-//
-//	err := db.badger.View(func(txn *badger.Txn) error {
-//		item, err := txn.Get(keyBlockHeightByHash(h))
-//
-//		if err == badger.ErrKeyNotFound {
-//			return ErrBlockNotFound
-//		} else if err != nil {
-//			return err
-//		}
-//
-//		hb, err := item.ValueCopy(nil)
-//		if err != nil {
-//			return err
-//		}
-//
-//		height = uint32(btoi(hb))
-//
-//		return nil
-//	})
-//
-//	if err != nil {
-//		return 0, err
-//	}
-//
-//	return height, nil
-//}
-//
-//func (db *badgerDB) GetBlockByHeight(height uint32) (*Block, error) {
-//	var b *Block
-//
-//	err := db.badger.View(func(txn *badger.Txn) error {
-//		stxn := &storeTxn{txn}
-//
-//		foundHash, err := stxn.getHashByKey(keyBlockHashByHeight(height))
-//		if err != nil {
-//			return err
-//		}
-//
-//		block, err := stxn.getBlockByKey(keyBlockByHash(foundHash))
-//		if err != nil {
-//			return err
-//		}
-//
-//		b = block
-//
-//		return nil
-//	})
-//
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	return b, nil
-//}
-//
-//func (db *badgerDB) IsEmpty() (bool, error) {
-//	height, err := db.TopIndex()
-//	if err != nil {
-//		return false, err
-//	}
-//
-//	return height == 0, nil
-//}
-//
-//func (db *badgerDB) Close() error {
-//	if err := db.badger.Close(); err != nil {
-//		return err
-//	}
-//
-//	return nil
-//}
-//
-//func (db *badgerDB) TopIndex() (uint32, error) {
-//	height := uint64(0)
-//
-//	err := db.badger.View(func(txn *badger.Txn) error {
-//		keyHeight := keyHeight()
-//
-//		heightItem, err := txn.Get(keyHeight)
-//		if err != nil {
-//			return err
-//		}
-//
-//		heightPayload, err := heightItem.ValueCopy(nil)
-//		if err != nil {
-//			return err
-//		}
-//
-//		height = btoi(heightPayload)
-//
-//		return nil
-//	})
-//
-//	if err != nil {
-//		return 0, err
-//	}
-//
-//	return uint32(height), nil
-//}
-//
-//func (txn *storeTxn) getHashByKey(b []byte) (*crypto.Hash, error) {
-//	var h crypto.Hash
-//
-//	item, err := txn.Get(b)
-//
-//	if err == badger.ErrKeyNotFound {
-//		return nil, ErrBlockNotFound
-//	} else if err != nil {
-//		return nil, err
-//	}
-//
-//	hb, err := item.ValueCopy(nil)
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	if err := h.Read(bytes.NewReader(hb)); err != nil {
-//		return nil, err
-//	}
-//
-//	return &h, nil
-//}
-//
-//func (txn *storeTxn) getBlockByKey(b []byte) (*Block, error) {
-//	var block Block
-//
-//	item, err := txn.Get(b)
-//
-//	if err == badger.ErrKeyNotFound {
-//		return nil, ErrBlockNotFound
-//	} else if err != nil {
-//		return nil, err
-//	}
-//
-//	bb, err := item.ValueCopy(nil)
-//	if err != nil {
-//		return nil, err
-//	}
-//
-//	if err := block.Deserialize(bytes.NewReader(bb)); err != nil {
-//		return nil, err
-//	}
-//
-//	return &block, nil
-//}
+// Badger storage keeps the blockchain on the disk using Badger key-value database.
+// All the data of one block is written in a single database transaction.
+
+import (
+	"bytes"
+	"encoding/binary"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/r3volut1oner/go-karbo/crypto"
+	"github.com/r3volut1oner/go-karbo/utils"
+	"io"
+)
+
+// Keys prefixes of the badger storage.
+// Indexes are encoded in big endian, so the keys are sorted by the block index.
+var (
+	badgerKeyTopIndex         = []byte("top-index")
+	badgerPrefixBlock         = []byte("block-")
+	badgerPrefixBlockIndex    = []byte("hash-")
+	badgerPrefixBlockInfo     = []byte("info-")
+	badgerPrefixTransactions  = []byte("transactions-")
+	badgerPrefixTransaction   = []byte("transaction-")
+	badgerPrefixKeyImages     = []byte("key-images-")
+	badgerPrefixKeyImage      = []byte("key-image-")
+	badgerPrefixMultisigSpent = []byte("multisig-spent-")
+	badgerPrefixKeyOutput     = []byte("key-output-")
+	badgerPrefixKeyOutputs    = []byte("key-outputs-count-")
+
+	badgerPrefixMultisigOutput      = []byte("multisig-output-")
+	badgerPrefixMultisigOutputs     = []byte("multisig-outputs-count-")
+	badgerPrefixMultisigOutputSpent = []byte("multisig-output-spent-")
+)
+
+type badgerStorage struct {
+	db *badger.DB
+}
+
+// NewBadgerStorage opens (or creates) badger storage in the provided directory.
+func NewBadgerStorage(dir string) (Storage, error) {
+	options := badger.DefaultOptions(dir).WithLoggingLevel(badger.WARNING)
+
+	db, err := badger.Open(options)
+	if err != nil {
+		return nil, err
+	}
+
+	return &badgerStorage{db}, nil
+}
+
+func (s *badgerStorage) Init(genesisBlock *Block) error {
+	genesisHash, err := s.HashAtIndex(0)
+	if err != nil {
+		return err
+	}
+
+	// New database, so we just save the genesis block
+	if genesisHash == nil {
+		return s.PushBlock(genesisBlock, genesisBlockInfo(genesisBlock), TransactionsDetails{})
+	}
+
+	if *genesisHash != *genesisBlock.Hash() {
+		return ErrStorageNetworkMismatch
+	}
+
+	return nil
+}
+
+func (s *badgerStorage) TopIndex() (uint32, error) {
+	var index uint32
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		i, err := badgerGetTopIndex(txn)
+		index = i
+		return err
+	})
+
+	return index, err
+}
+
+func (s *badgerStorage) TopBlock() (*Block, error) {
+	var block *Block
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		index, err := badgerGetTopIndex(txn)
+		if err != nil {
+			return err
+		}
+
+		block, err = badgerGetBlock(txn, index)
+		return err
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+func (s *badgerStorage) PushBlock(block *Block, info *blockInfo, details TransactionsDetails) error {
+	hash := block.Hash()
+	index := block.Index()
+
+	if index != info.Index {
+		return utils.AssertionError("block info index and block index must be same")
+	}
+
+	if *hash != info.Hash {
+		return utils.AssertionError("block info hash and block hash must be same")
+	}
+
+	return s.db.Update(func(txn *badger.Txn) error {
+		if _, err := txn.Get(badgerKeyBlockIndex(hash)); err == nil {
+			return ErrStorageBlockExists
+		} else if err != badger.ErrKeyNotFound {
+			return err
+		}
+
+		if _, err := txn.Get(badgerKeyBlock(index)); err == nil {
+			return ErrStorageBlockExists
+		} else if err != badger.ErrKeyNotFound {
+			return err
+		}
+
+		// Only genesis block can be pushed to the empty storage
+		top, err := badgerGetTopIndex(txn)
+		if err == ErrStorageBlockNotFound {
+			if index != 0 {
+				return ErrStorageBlockNotNext
+			}
+		} else if err != nil {
+			return err
+		} else if index != top+1 {
+			return ErrStorageBlockNotNext
+		}
+
+		entries := []*badger.Entry{
+			badger.NewEntry(badgerKeyBlock(index), block.Serialize()),
+			badger.NewEntry(badgerKeyBlockIndex(hash), badgerEncodeIndex(index)),
+			badger.NewEntry(badgerKeyBlockInfo(index), info.serialize()),
+			badger.NewEntry(badgerKeyTransactions(index), serializeTransactionsList(details.transactions)),
+			badger.NewEntry(badgerKeyKeyImages(index), serializeKeyImages(details.spentKeyImages)),
+			badger.NewEntry(badgerKeyMultisigSpent(index), serializeMultisigPairs(details.spentMultisignatureGlobalIndexes)),
+		}
+
+		for i, txHash := range blockTransactionsHashes(block, details.transactions) {
+			txHash := txHash
+			if _, err := txn.Get(badgerKeyTransaction(&txHash)); err == badger.ErrKeyNotFound {
+				position := transactionPosition{BlockIndex: index, Index: uint32(i)}
+				entries = append(entries, badger.NewEntry(badgerKeyTransaction(&txHash), serializeTransactionPosition(&position)))
+			} else if err != nil {
+				return err
+			}
+		}
+
+		for _, image := range details.spentKeyImages {
+			if _, err := txn.Get(badgerKeyKeyImage(image)); err == badger.ErrKeyNotFound {
+				entries = append(entries, badger.NewEntry(badgerKeyKeyImage(image), badgerEncodeIndex(index)))
+			} else if err != nil {
+				return err
+			}
+		}
+
+		counts := map[uint64]uint32{}
+		for _, output := range blockKeyOutputs(block, details.transactions) {
+			count, ok := counts[output.Amount]
+			if !ok {
+				var err error
+				if count, err = badgerGetKeyOutputsCount(txn, output.Amount); err != nil {
+					return err
+				}
+			}
+
+			entries = append(entries, badger.NewEntry(badgerKeyKeyOutput(output.Amount, count), serializeKeyOutput(&output)))
+			counts[output.Amount] = count + 1
+		}
+
+		for amount, count := range counts {
+			entries = append(entries, badger.NewEntry(badgerKeyKeyOutputsCount(amount), badgerEncodeIndex(count)))
+		}
+
+		for _, pair := range details.spentMultisignatureGlobalIndexes {
+			key := badgerKeyMultisigOutputSpent(pair.Amount, pair.GlobalOutputIndex)
+			if _, err := txn.Get(key); err == badger.ErrKeyNotFound {
+				entries = append(entries, badger.NewEntry(key, badgerEncodeIndex(index)))
+			} else if err != nil {
+				return err
+			}
+		}
+
+		multisigCounts := map[uint64]uint32{}
+		for _, output := range blockMultisignatureOutputs(block, details.transactions) {
+			count, ok := multisigCounts[output.Amount]
+			if !ok {
+				var err error
+				if count, err = badgerGetMultisigOutputsCount(txn, output.Amount); err != nil {
+					return err
+				}
+			}
+
+			entries = append(entries, badger.NewEntry(badgerKeyMultisigOutput(output.Amount, count), serializeMultisigOutput(&output)))
+			multisigCounts[output.Amount] = count + 1
+		}
+
+		for amount, count := range multisigCounts {
+			entries = append(entries, badger.NewEntry(badgerKeyMultisigOutputsCount(amount), badgerEncodeIndex(count)))
+		}
+
+		topIndex, err := badgerGetTopIndex(txn)
+		if err == ErrStorageBlockNotFound || (err == nil && index > topIndex) {
+			entries = append(entries, badger.NewEntry(badgerKeyTopIndex, badgerEncodeIndex(index)))
+		} else if err != nil {
+			return err
+		}
+
+		for _, entry := range entries {
+			if err := txn.SetEntry(entry); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (s *badgerStorage) PopBlock() (*Block, []Transaction, error) {
+	var block *Block
+	var transactions []Transaction
+
+	err := s.db.Update(func(txn *badger.Txn) error {
+		index, err := badgerGetTopIndex(txn)
+		if err != nil {
+			return err
+		}
+
+		if index == 0 {
+			return ErrStoragePopGenesis
+		}
+
+		block, err = badgerGetBlock(txn, index)
+		if err != nil {
+			return err
+		}
+
+		payload, err := badgerGetValue(txn, badgerKeyTransactions(index))
+		if err != nil {
+			return err
+		}
+
+		transactions, err = deserializeTransactionsList(bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+
+		payload, err = badgerGetValue(txn, badgerKeyKeyImages(index))
+		if err != nil {
+			return err
+		}
+
+		images, err := deserializeKeyImages(bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+
+		payload, err = badgerGetValue(txn, badgerKeyMultisigSpent(index))
+		if err != nil {
+			return err
+		}
+
+		pairs, err := deserializeMultisigPairs(bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+
+		keys := [][]byte{
+			badgerKeyBlock(index),
+			badgerKeyBlockIndex(block.Hash()),
+			badgerKeyBlockInfo(index),
+			badgerKeyTransactions(index),
+			badgerKeyKeyImages(index),
+			badgerKeyMultisigSpent(index),
+		}
+
+		for _, txHash := range blockTransactionsHashes(block, transactions) {
+			txHash := txHash
+			position, err := badgerGetTransactionPosition(txn, &txHash)
+			if err == nil && position.BlockIndex == index {
+				keys = append(keys, badgerKeyTransaction(&txHash))
+			} else if err != nil && err != ErrStorageTransactionNotFound {
+				return err
+			}
+		}
+
+		for _, image := range images {
+			spentIndex, err := badgerGetIndex(txn, badgerKeyKeyImage(image))
+			if err == nil && spentIndex == index {
+				keys = append(keys, badgerKeyKeyImage(image))
+			} else if err != nil && err != ErrStorageBlockNotFound {
+				return err
+			}
+		}
+
+		// Outputs of the top block are always the last ones for their amounts
+		counts := map[uint64]uint32{}
+		for _, output := range blockKeyOutputs(block, transactions) {
+			count, ok := counts[output.Amount]
+			if !ok {
+				if count, err = badgerGetKeyOutputsCount(txn, output.Amount); err != nil {
+					return err
+				}
+			}
+
+			keys = append(keys, badgerKeyKeyOutput(output.Amount, count-1))
+			counts[output.Amount] = count - 1
+		}
+
+		for amount, count := range counts {
+			if count == 0 {
+				keys = append(keys, badgerKeyKeyOutputsCount(amount))
+			} else if err := txn.Set(badgerKeyKeyOutputsCount(amount), badgerEncodeIndex(count)); err != nil {
+				return err
+			}
+		}
+
+		for _, pair := range pairs {
+			key := badgerKeyMultisigOutputSpent(pair.Amount, pair.GlobalOutputIndex)
+			spentIndex, err := badgerGetIndex(txn, key)
+			if err == nil && spentIndex == index {
+				keys = append(keys, key)
+			} else if err != nil && err != ErrStorageBlockNotFound {
+				return err
+			}
+		}
+
+		multisigCounts := map[uint64]uint32{}
+		for _, output := range blockMultisignatureOutputs(block, transactions) {
+			count, ok := multisigCounts[output.Amount]
+			if !ok {
+				if count, err = badgerGetMultisigOutputsCount(txn, output.Amount); err != nil {
+					return err
+				}
+			}
+
+			keys = append(keys, badgerKeyMultisigOutput(output.Amount, count-1))
+			multisigCounts[output.Amount] = count - 1
+		}
+
+		for amount, count := range multisigCounts {
+			if count == 0 {
+				keys = append(keys, badgerKeyMultisigOutputsCount(amount))
+			} else if err := txn.Set(badgerKeyMultisigOutputsCount(amount), badgerEncodeIndex(count)); err != nil {
+				return err
+			}
+		}
+
+		for _, key := range keys {
+			if err := txn.Delete(key); err != nil {
+				return err
+			}
+		}
+
+		return txn.Set(badgerKeyTopIndex, badgerEncodeIndex(index-1))
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return block, transactions, nil
+}
+
+func (s *badgerStorage) HaveBlock(hash *crypto.Hash) bool {
+	have := false
+
+	_ = s.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(badgerKeyBlockIndex(hash))
+		have = err == nil
+		return nil
+	})
+
+	return have
+}
+
+func (s *badgerStorage) GetBlock(hash *crypto.Hash) *Block {
+	var block *Block
+
+	_ = s.db.View(func(txn *badger.Txn) error {
+		index, err := badgerGetIndex(txn, badgerKeyBlockIndex(hash))
+		if err != nil {
+			return err
+		}
+
+		block, err = badgerGetBlock(txn, index)
+		return err
+	})
+
+	return block
+}
+
+func (s *badgerStorage) HashAtIndex(index uint32) (*crypto.Hash, error) {
+	var hash *crypto.Hash
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		block, err := badgerGetBlock(txn, index)
+		if err == ErrStorageBlockNotFound {
+			return nil
+		} else if err != nil {
+			return err
+		}
+
+		hash = block.Hash()
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	return hash, nil
+}
+
+func (s *badgerStorage) GetBlockIndexByHash(hash *crypto.Hash) (uint32, error) {
+	var index uint32
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		var err error
+		index, err = badgerGetIndex(txn, badgerKeyBlockIndex(hash))
+		return err
+	})
+
+	return index, err
+}
+
+func (s *badgerStorage) GetBlockByHeight(index uint32) (*Block, error) {
+	var block *Block
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		var err error
+		block, err = badgerGetBlock(txn, index)
+		return err
+	})
+
+	return block, err
+}
+
+func (s *badgerStorage) GetBlockTransactions(index uint32) ([]Transaction, error) {
+	var transactions []Transaction
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		payload, err := badgerGetValue(txn, badgerKeyTransactions(index))
+		if err != nil {
+			return err
+		}
+
+		transactions, err = deserializeTransactionsList(bytes.NewReader(payload))
+		return err
+	})
+
+	return transactions, err
+}
+
+func (s *badgerStorage) HaveTransaction(hash *crypto.Hash) bool {
+	have := false
+
+	_ = s.db.View(func(txn *badger.Txn) error {
+		_, err := txn.Get(badgerKeyTransaction(hash))
+		have = err == nil
+		return nil
+	})
+
+	return have
+}
+
+func (s *badgerStorage) GetTransaction(hash *crypto.Hash) (*Transaction, *Block, error) {
+	var transaction *Transaction
+	var block *Block
+
+	err := s.db.View(func(txn *badger.Txn) error {
+		position, err := badgerGetTransactionPosition(txn, hash)
+		if err != nil {
+			return err
+		}
+
+		block, err = badgerGetBlock(txn, position.BlockIndex)
+		if err != nil {
+			return err
+		}
+
+		if position.Index == 0 {
+			transaction = &block.BaseTransaction
+			return nil
+		}
+
+		payload, err := badgerGetValue(txn, badgerKeyTransactions(position.BlockIndex))
+		if err != nil {
+			return err
+		}
+
+		transactions, err := deserializeTransactionsList(bytes.NewReader(payload))
+		if err != nil {
+			return err
+		}
+
+		transaction = &transactions[position.Index-1]
+		return nil
+	})
+
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return transaction, block, nil
+}
+
+func (s *badgerStorage) Close() error {
+	return s.db.Close()
+}
+
+func (s *badgerStorage) getBlockInfoAtIndex(index uint32) *blockInfo {
+	var info *blockInfo
+
+	_ = s.db.View(func(txn *badger.Txn) error {
+		payload, err := badgerGetValue(txn, badgerKeyBlockInfo(index))
+		if err != nil {
+			return err
+		}
+
+		info = &blockInfo{}
+		if err := info.deserialize(bytes.NewReader(payload)); err != nil {
+			info = nil
+			return err
+		}
+
+		return nil
+	})
+
+	return info
+}
+
+func (s *badgerStorage) getKeyImageSpentIndex(image crypto.KeyImage) (uint32, bool) {
+	var index uint32
+	spent := false
+
+	_ = s.db.View(func(txn *badger.Txn) error {
+		i, err := badgerGetIndex(txn, badgerKeyKeyImage(image))
+		if err != nil {
+			return err
+		}
+
+		index = i
+		spent = true
+		return nil
+	})
+
+	return index, spent
+}
+
+func (s *badgerStorage) getKeyOutput(amount uint64, globalIndex uint32) *keyOutput {
+	var output *keyOutput
+
+	_ = s.db.View(func(txn *badger.Txn) error {
+		payload, err := badgerGetValue(txn, badgerKeyKeyOutput(amount, globalIndex))
+		if err != nil {
+			return err
+		}
+
+		output = &keyOutput{}
+		if err := binary.Read(bytes.NewReader(payload), binary.LittleEndian, output); err != nil {
+			output = nil
+			return err
+		}
+
+		return nil
+	})
+
+	return output
+}
+
+func (s *badgerStorage) getMultisignatureOutput(amount uint64, globalIndex uint32) *multisignatureOutput {
+	var output *multisignatureOutput
+
+	_ = s.db.View(func(txn *badger.Txn) error {
+		payload, err := badgerGetValue(txn, badgerKeyMultisigOutput(amount, globalIndex))
+		if err != nil {
+			return err
+		}
+
+		output, err = deserializeMultisigOutput(bytes.NewReader(payload))
+		return err
+	})
+
+	return output
+}
+
+func (s *badgerStorage) getMultisignatureSpentIndex(amount uint64, globalIndex uint32) (uint32, bool) {
+	var index uint32
+	spent := false
+
+	_ = s.db.View(func(txn *badger.Txn) error {
+		i, err := badgerGetIndex(txn, badgerKeyMultisigOutputSpent(amount, globalIndex))
+		if err != nil {
+			return err
+		}
+
+		index = i
+		spent = true
+		return nil
+	})
+
+	return index, spent
+}
+
+// badgerGetValue returns copy of the value saved by the key.
+// Returns ErrStorageBlockNotFound if the key not exists.
+func badgerGetValue(txn *badger.Txn, key []byte) ([]byte, error) {
+	item, err := txn.Get(key)
+	if err == badger.ErrKeyNotFound {
+		return nil, ErrStorageBlockNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	return item.ValueCopy(nil)
+}
+
+func badgerGetIndex(txn *badger.Txn, key []byte) (uint32, error) {
+	payload, err := badgerGetValue(txn, key)
+	if err != nil {
+		return 0, err
+	}
+
+	return binary.BigEndian.Uint32(payload), nil
+}
+
+func badgerGetTopIndex(txn *badger.Txn) (uint32, error) {
+	return badgerGetIndex(txn, badgerKeyTopIndex)
+}
+
+// badgerGetKeyOutputsCount returns count of the key outputs with the amount, it is the next global index for the amount
+func badgerGetKeyOutputsCount(txn *badger.Txn, amount uint64) (uint32, error) {
+	count, err := badgerGetIndex(txn, badgerKeyKeyOutputsCount(amount))
+	if err == ErrStorageBlockNotFound {
+		return 0, nil
+	}
+
+	return count, err
+}
+
+// badgerGetMultisigOutputsCount returns count of the multisignature outputs with the amount
+func badgerGetMultisigOutputsCount(txn *badger.Txn, amount uint64) (uint32, error) {
+	count, err := badgerGetIndex(txn, badgerKeyMultisigOutputsCount(amount))
+	if err == ErrStorageBlockNotFound {
+		return 0, nil
+	}
+
+	return count, err
+}
+
+// badgerGetTransactionPosition returns position of the transaction in the main chain.
+// Returns ErrStorageTransactionNotFound if the transaction is not indexed.
+func badgerGetTransactionPosition(txn *badger.Txn, hash *crypto.Hash) (*transactionPosition, error) {
+	payload, err := badgerGetValue(txn, badgerKeyTransaction(hash))
+	if err == ErrStorageBlockNotFound {
+		return nil, ErrStorageTransactionNotFound
+	} else if err != nil {
+		return nil, err
+	}
+
+	position := &transactionPosition{
+		BlockIndex: binary.BigEndian.Uint32(payload),
+		Index:      binary.BigEndian.Uint32(payload[4:]),
+	}
+
+	return position, nil
+}
+
+func badgerGetBlock(txn *badger.Txn, index uint32) (*Block, error) {
+	payload, err := badgerGetValue(txn, badgerKeyBlock(index))
+	if err != nil {
+		return nil, err
+	}
+
+	block := &Block{}
+	if err := block.Deserialize(bytes.NewReader(payload)); err != nil {
+		return nil, err
+	}
+
+	return block, nil
+}
+
+func badgerEncodeIndex(index uint32) []byte {
+	var buf [4]byte
+	binary.BigEndian.PutUint32(buf[:], index)
+	return buf[:]
+}
+
+func badgerKey(prefix []byte, suffix []byte) []byte {
+	key := make([]byte, 0, len(prefix)+len(suffix))
+	key = append(key, prefix...)
+	return append(key, suffix...)
+}
+
+func badgerKeyBlock(index uint32) []byte {
+	return badgerKey(badgerPrefixBlock, badgerEncodeIndex(index))
+}
+
+func badgerKeyBlockIndex(hash *crypto.Hash) []byte {
+	return badgerKey(badgerPrefixBlockIndex, hash[:])
+}
+
+func badgerKeyBlockInfo(index uint32) []byte {
+	return badgerKey(badgerPrefixBlockInfo, badgerEncodeIndex(index))
+}
+
+func badgerKeyTransactions(index uint32) []byte {
+	return badgerKey(badgerPrefixTransactions, badgerEncodeIndex(index))
+}
+
+func badgerKeyTransaction(hash *crypto.Hash) []byte {
+	return badgerKey(badgerPrefixTransaction, hash[:])
+}
+
+func badgerKeyKeyImages(index uint32) []byte {
+	return badgerKey(badgerPrefixKeyImages, badgerEncodeIndex(index))
+}
+
+func badgerKeyKeyImage(image crypto.KeyImage) []byte {
+	return badgerKey(badgerPrefixKeyImage, image[:])
+}
+
+func badgerKeyKeyOutput(amount uint64, globalIndex uint32) []byte {
+	return badgerKey(badgerPrefixKeyOutput, badgerEncodeGlobalIndex(amount, globalIndex))
+}
+
+func badgerKeyKeyOutputsCount(amount uint64) []byte {
+	return badgerKey(badgerPrefixKeyOutputs, badgerEncodeAmount(amount))
+}
+
+func badgerKeyMultisigOutput(amount uint64, globalIndex uint32) []byte {
+	return badgerKey(badgerPrefixMultisigOutput, badgerEncodeGlobalIndex(amount, globalIndex))
+}
+
+func badgerKeyMultisigOutputsCount(amount uint64) []byte {
+	return badgerKey(badgerPrefixMultisigOutputs, badgerEncodeAmount(amount))
+}
+
+func badgerKeyMultisigOutputSpent(amount uint64, globalIndex uint32) []byte {
+	return badgerKey(badgerPrefixMultisigOutputSpent, badgerEncodeGlobalIndex(amount, globalIndex))
+}
+
+func badgerEncodeAmount(amount uint64) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint64(buf[:], amount)
+	return buf[:]
+}
+
+func badgerEncodeGlobalIndex(amount uint64, globalIndex uint32) []byte {
+	var buf [12]byte
+	binary.BigEndian.PutUint64(buf[:], amount)
+	binary.BigEndian.PutUint32(buf[8:], globalIndex)
+	return buf[:]
+}
+
+func badgerKeyMultisigSpent(index uint32) []byte {
+	return badgerKey(badgerPrefixMultisigSpent, badgerEncodeIndex(index))
+}
+
+// serializeTransactionsList writes transactions count and then each transaction prefixed with its size
+func serializeTransactionsList(transactions []Transaction) []byte {
+	var serialized bytes.Buffer
+
+	buf := make([]byte, binary.MaxVarintLen64)
+	written := binary.PutUvarint(buf, uint64(len(transactions)))
+	serialized.Write(buf[:written])
+
+	for i := range transactions {
+		txBytes := transactions[i].Serialize()
+
+		written = binary.PutUvarint(buf, uint64(len(txBytes)))
+		serialized.Write(buf[:written])
+		serialized.Write(txBytes)
+	}
+
+	return serialized.Bytes()
+}
+
+func deserializeTransactionsList(r *bytes.Reader) ([]Transaction, error) {
+	count, err := binary.ReadUvarint(r)
+	if err != nil {
+		return nil, err
+	}
+
+	transactions := make([]Transaction, count)
+	for i := range transactions {
+		size, err := binary.ReadUvarint(r)
+		if err != nil {
+			return nil, err
+		}
+
+		txBytes := make([]byte, size)
+		if _, err := io.ReadFull(r, txBytes); err != nil {
+			return nil, err
+		}
+
+		if err := transactions[i].Deserialize(bytes.NewReader(txBytes)); err != nil {
+			return nil, err
+		}
+	}
+
+	return transactions, nil
+}
+
+func serializeTransactionPosition(position *transactionPosition) []byte {
+	var buf [8]byte
+	binary.BigEndian.PutUint32(buf[:], position.BlockIndex)
+	binary.BigEndian.PutUint32(buf[4:], position.Index)
+	return buf[:]
+}
+
+func serializeKeyImages(images []crypto.KeyImage) []byte {
+	var serialized bytes.Buffer
+
+	_ = binary.Write(&serialized, binary.LittleEndian, images)
+
+	return serialized.Bytes()
+}
+
+func deserializeKeyImages(r *bytes.Reader) ([]crypto.KeyImage, error) {
+	images := make([]crypto.KeyImage, r.Len()/len(crypto.KeyImage{}))
+
+	if err := binary.Read(r, binary.LittleEndian, images); err != nil {
+		return nil, err
+	}
+
+	return images, nil
+}
+
+func serializeKeyOutput(output *keyOutput) []byte {
+	var serialized bytes.Buffer
+
+	_ = binary.Write(&serialized, binary.LittleEndian, output)
+
+	return serialized.Bytes()
+}
+
+func serializeMultisigPairs(pairs []MultisigAmountGlobalOutputIndexPair) []byte {
+	var serialized bytes.Buffer
+
+	_ = binary.Write(&serialized, binary.LittleEndian, pairs)
+
+	return serialized.Bytes()
+}
+
+func deserializeMultisigPairs(r *bytes.Reader) ([]MultisigAmountGlobalOutputIndexPair, error) {
+	pairs := make([]MultisigAmountGlobalOutputIndexPair, r.Len()/binary.Size(MultisigAmountGlobalOutputIndexPair{}))
+
+	if err := binary.Read(r, binary.LittleEndian, pairs); err != nil {
+		return nil, err
+	}
+
+	return pairs, nil
+}
+
+// multisigOutputHeader is the fixed size part of the serialized multisignature output, the keys are written after it
+type multisigOutputHeader struct {
+	Amount                  uint64
+	UnlockTime              uint64
+	BlockIndex              uint32
+	RequiredSignaturesCount byte
+}
+
+func serializeMultisigOutput(output *multisignatureOutput) []byte {
+	var serialized bytes.Buffer
+
+	header := multisigOutputHeader{
+		Amount:                  output.Amount,
+		UnlockTime:              output.UnlockTime,
+		BlockIndex:              output.BlockIndex,
+		RequiredSignaturesCount: output.RequiredSignaturesCount,
+	}
+
+	_ = binary.Write(&serialized, binary.LittleEndian, header)
+	_ = binary.Write(&serialized, binary.LittleEndian, output.Keys)
+
+	return serialized.Bytes()
+}
+
+func deserializeMultisigOutput(r *bytes.Reader) (*multisignatureOutput, error) {
+	var header multisigOutputHeader
+	if err := binary.Read(r, binary.LittleEndian, &header); err != nil {
+		return nil, err
+	}
+
+	keys := make([]crypto.PublicKey, r.Len()/len(crypto.PublicKey{}))
+	if err := binary.Read(r, binary.LittleEndian, keys); err != nil {
+		return nil, err
+	}
+
+	return &multisignatureOutput{
+		Amount:                  header.Amount,
+		Keys:                    keys,
+		RequiredSignaturesCount: header.RequiredSignaturesCount,
+		UnlockTime:              header.UnlockTime,
+		BlockIndex:              header.BlockIndex,
+	}, nil
+}
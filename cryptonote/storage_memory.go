@@ -23,8 +23,23 @@ type memoryStorage struct {
 
 	spentKeysImagesIndex map[uint32]*[]crypto.KeyImage
 
+	// transactionsHashIndex keeps position of the transaction in the main chain
+	transactionsHashIndex map[crypto.Hash]transactionPosition
+
+	// keyImagesIndex keeps index of the block where key image was spent
+	keyImagesIndex map[crypto.KeyImage]uint32
+
 	spentMultisignatureGlobalIndexesIndex map[uint32]*[]MultisigAmountGlobalOutputIndexPair
 
+	// keyOutputsIndex keeps key outputs by amount, position in the slice is the global index of the output
+	keyOutputsIndex map[uint64][]keyOutput
+
+	// multisignatureOutputsIndex keeps multisignature outputs by amount, position in the slice is the global index
+	multisignatureOutputsIndex map[uint64][]multisignatureOutput
+
+	// multisignatureSpentIndex keeps index of the block where multisignature output was spent
+	multisignatureSpentIndex map[MultisigAmountGlobalOutputIndexPair]uint32
+
 	topBlock *Block
 
 	sync.RWMutex
@@ -34,28 +49,27 @@ func NewMemoryStorage() Storage {
 	return &memoryStorage{
 		blockIndex:                            map[uint32]*Block{},
 		blockInfosIndex:                       map[uint32]*blockInfo{},
+		blockInfosHashIndex:                   map[crypto.Hash]*blockInfo{},
 		transactionsIndex:                     map[uint32]*[]Transaction{},
 		spentKeysImagesIndex:                  map[uint32]*[]crypto.KeyImage{},
+		transactionsHashIndex:                 map[crypto.Hash]transactionPosition{},
+		keyImagesIndex:                        map[crypto.KeyImage]uint32{},
 		spentMultisignatureGlobalIndexesIndex: map[uint32]*[]MultisigAmountGlobalOutputIndexPair{},
+		keyOutputsIndex:                       map[uint64][]keyOutput{},
+		multisignatureOutputsIndex:            map[uint64][]multisignatureOutput{},
+		multisignatureSpentIndex:              map[MultisigAmountGlobalOutputIndexPair]uint32{},
 	}
 }
 
 func (s *memoryStorage) Init(genesisBlock *Block) error {
-	info := blockInfo{
-		Index:                0,
-		CumulativeDifficulty: 1,
-		Size:                 genesisBlock.BaseTransaction.Size(),
-		TotalGeneratedCoins:  genesisBlock.BaseTransaction.Outputs[0].Amount,
-	}
-
-	err := s.PushBlock(genesisBlock, &info, TransactionsDetails{})
+	err := s.PushBlock(genesisBlock, genesisBlockInfo(genesisBlock), TransactionsDetails{})
 	return err
 }
 
 func (s *memoryStorage) TopIndex() (uint32, error) {
 	s.RLock()
 	index := s.topBlock.Index()
-	s.Unlock()
+	s.RUnlock()
 	return index, nil
 }
 
@@ -93,6 +107,11 @@ func (s *memoryStorage) appendBlock(block *Block, info *blockInfo, details Trans
 		return ErrStorageBlockExists
 	}
 
+	// Only genesis block can be pushed to the empty storage
+	if (s.topBlock == nil && index != 0) || (s.topBlock != nil && index != s.topBlock.Index()+1) {
+		return ErrStorageBlockNotNext
+	}
+
 	s.blockIndex[index] = block
 	s.blockInfosIndex[index] = info
 	s.blockInfosHashIndex[*hash] = info
@@ -101,6 +120,32 @@ func (s *memoryStorage) appendBlock(block *Block, info *blockInfo, details Trans
 	s.spentKeysImagesIndex[index] = &details.spentKeyImages
 	s.spentMultisignatureGlobalIndexesIndex[index] = &details.spentMultisignatureGlobalIndexes
 
+	for i, txHash := range blockTransactionsHashes(block, details.transactions) {
+		if _, ok := s.transactionsHashIndex[txHash]; !ok {
+			s.transactionsHashIndex[txHash] = transactionPosition{BlockIndex: index, Index: uint32(i)}
+		}
+	}
+
+	for _, image := range details.spentKeyImages {
+		if _, ok := s.keyImagesIndex[image]; !ok {
+			s.keyImagesIndex[image] = index
+		}
+	}
+
+	for _, output := range blockKeyOutputs(block, details.transactions) {
+		s.keyOutputsIndex[output.Amount] = append(s.keyOutputsIndex[output.Amount], output)
+	}
+
+	for _, pair := range details.spentMultisignatureGlobalIndexes {
+		if _, ok := s.multisignatureSpentIndex[pair]; !ok {
+			s.multisignatureSpentIndex[pair] = index
+		}
+	}
+
+	for _, output := range blockMultisignatureOutputs(block, details.transactions) {
+		s.multisignatureOutputsIndex[output.Amount] = append(s.multisignatureOutputsIndex[output.Amount], output)
+	}
+
 	if s.topBlock == nil || index > s.topBlock.Index() {
 		s.topBlock = block
 	}
@@ -108,6 +153,68 @@ func (s *memoryStorage) appendBlock(block *Block, info *blockInfo, details Trans
 	return nil
 }
 
+func (s *memoryStorage) PopBlock() (*Block, []Transaction, error) {
+	s.Lock()
+	defer s.Unlock()
+
+	block := s.topBlock
+	index := block.Index()
+
+	if index == 0 {
+		return nil, nil, ErrStoragePopGenesis
+	}
+
+	transactions := *s.transactionsIndex[index]
+
+	for _, txHash := range blockTransactionsHashes(block, transactions) {
+		if position, ok := s.transactionsHashIndex[txHash]; ok && position.BlockIndex == index {
+			delete(s.transactionsHashIndex, txHash)
+		}
+	}
+
+	for _, image := range *s.spentKeysImagesIndex[index] {
+		if spentIndex, ok := s.keyImagesIndex[image]; ok && spentIndex == index {
+			delete(s.keyImagesIndex, image)
+		}
+	}
+
+	// Outputs of the top block are always the last ones for their amounts
+	for _, output := range blockKeyOutputs(block, transactions) {
+		outputs := s.keyOutputsIndex[output.Amount]
+		if len(outputs) == 1 {
+			delete(s.keyOutputsIndex, output.Amount)
+		} else {
+			s.keyOutputsIndex[output.Amount] = outputs[:len(outputs)-1]
+		}
+	}
+
+	for _, pair := range *s.spentMultisignatureGlobalIndexesIndex[index] {
+		if spentIndex, ok := s.multisignatureSpentIndex[pair]; ok && spentIndex == index {
+			delete(s.multisignatureSpentIndex, pair)
+		}
+	}
+
+	for _, output := range blockMultisignatureOutputs(block, transactions) {
+		outputs := s.multisignatureOutputsIndex[output.Amount]
+		if len(outputs) == 1 {
+			delete(s.multisignatureOutputsIndex, output.Amount)
+		} else {
+			s.multisignatureOutputsIndex[output.Amount] = outputs[:len(outputs)-1]
+		}
+	}
+
+	delete(s.blockIndex, index)
+	delete(s.blockInfosIndex, index)
+	delete(s.blockInfosHashIndex, *block.Hash())
+	delete(s.transactionsIndex, index)
+	delete(s.spentKeysImagesIndex, index)
+	delete(s.spentMultisignatureGlobalIndexesIndex, index)
+
+	s.topBlock = s.blockIndex[index-1]
+
+	return block, transactions, nil
+}
+
 func (s *memoryStorage) HaveBlock(hash *crypto.Hash) bool {
 	s.RLock()
 	have := s.haveBlock(hash)
@@ -146,6 +253,64 @@ func (s *memoryStorage) HashAtIndex(index uint32) (*crypto.Hash, error) {
 	return nil, nil
 }
 
+func (s *memoryStorage) GetBlockIndexByHash(hash *crypto.Hash) (uint32, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	if info, ok := s.blockInfosHashIndex[*hash]; ok {
+		return info.Index, nil
+	}
+
+	return 0, ErrStorageBlockNotFound
+}
+
+func (s *memoryStorage) GetBlockByHeight(index uint32) (*Block, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	if block, ok := s.blockIndex[index]; ok {
+		return block, nil
+	}
+
+	return nil, ErrStorageBlockNotFound
+}
+
+func (s *memoryStorage) GetBlockTransactions(index uint32) ([]Transaction, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	if transactions, ok := s.transactionsIndex[index]; ok {
+		return *transactions, nil
+	}
+
+	return nil, ErrStorageBlockNotFound
+}
+
+func (s *memoryStorage) HaveTransaction(hash *crypto.Hash) bool {
+	s.RLock()
+	_, ok := s.transactionsHashIndex[*hash]
+	s.RUnlock()
+	return ok
+}
+
+func (s *memoryStorage) GetTransaction(hash *crypto.Hash) (*Transaction, *Block, error) {
+	s.RLock()
+	defer s.RUnlock()
+
+	position, ok := s.transactionsHashIndex[*hash]
+	if !ok {
+		return nil, nil, ErrStorageTransactionNotFound
+	}
+
+	block := s.blockIndex[position.BlockIndex]
+	if position.Index == 0 {
+		return &block.BaseTransaction, block, nil
+	}
+
+	transaction := (*s.transactionsIndex[position.BlockIndex])[position.Index-1]
+	return &transaction, block, nil
+}
+
 func (s *memoryStorage) getBlockInfoAtIndex(index uint32) *blockInfo {
 	s.RLock()
 	info := s.blockInfosIndex[index]
@@ -153,6 +318,46 @@ func (s *memoryStorage) getBlockInfoAtIndex(index uint32) *blockInfo {
 	return info
 }
 
+func (s *memoryStorage) getKeyImageSpentIndex(image crypto.KeyImage) (uint32, bool) {
+	s.RLock()
+	index, ok := s.keyImagesIndex[image]
+	s.RUnlock()
+	return index, ok
+}
+
+func (s *memoryStorage) getKeyOutput(amount uint64, globalIndex uint32) *keyOutput {
+	s.RLock()
+	defer s.RUnlock()
+
+	outputs := s.keyOutputsIndex[amount]
+	if int(globalIndex) >= len(outputs) {
+		return nil
+	}
+
+	output := outputs[globalIndex]
+	return &output
+}
+
+func (s *memoryStorage) getMultisignatureOutput(amount uint64, globalIndex uint32) *multisignatureOutput {
+	s.RLock()
+	defer s.RUnlock()
+
+	outputs := s.multisignatureOutputsIndex[amount]
+	if int(globalIndex) >= len(outputs) {
+		return nil
+	}
+
+	output := outputs[globalIndex]
+	return &output
+}
+
+func (s *memoryStorage) getMultisignatureSpentIndex(amount uint64, globalIndex uint32) (uint32, bool) {
+	s.RLock()
+	index, ok := s.multisignatureSpentIndex[MultisigAmountGlobalOutputIndexPair{amount, globalIndex}]
+	s.RUnlock()
+	return index, ok
+}
+
 func (s *memoryStorage) Close() error {
 	return nil
 }
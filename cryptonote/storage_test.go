@@ -0,0 +1,608 @@
+package cryptonote
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/dgraph-io/badger/v3"
+	"github.com/r3volut1oner/go-karbo/config"
+	"github.com/r3volut1oner/go-karbo/crypto"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// storageFactories contains all the storage implementations, every storage must pass same test suite.
+var storageFactories = map[string]func(t *testing.T) Storage{
+	"memory": func(t *testing.T) Storage {
+		return NewMemoryStorage()
+	},
+	"badger": func(t *testing.T) Storage {
+		return newTestBadgerStorage(t, tempStorageDir(t))
+	},
+}
+
+// runStorageTest runs the test against each of the storages.
+// Storages are passed initialized with mainnet genesis block.
+func runStorageTest(t *testing.T, test func(t *testing.T, s Storage)) {
+	for name, factory := range storageFactories {
+		t.Run(name, func(t *testing.T) {
+			s := factory(t)
+			defer s.Close()
+
+			assert.Nil(t, s.Init(testGenesisBlock(config.MainNet())))
+
+			test(t, s)
+		})
+	}
+}
+
+func TestStorage_Init(t *testing.T) {
+	runStorageTest(t, func(t *testing.T, s Storage) {
+		genesisBlock := testGenesisBlock(config.MainNet())
+
+		topIndex, err := s.TopIndex()
+		assert.Nil(t, err)
+		assert.Equal(t, uint32(0), topIndex)
+
+		topBlock, err := s.TopBlock()
+		assert.Nil(t, err)
+		assert.Equal(t, genesisBlock.Hash(), topBlock.Hash())
+
+		hash, err := s.HashAtIndex(0)
+		assert.Nil(t, err)
+		assert.Equal(t, genesisBlock.Hash(), hash)
+
+		info := s.getBlockInfoAtIndex(0)
+		assert.Equal(t, genesisBlockInfo(genesisBlock), info)
+	})
+}
+
+func TestStorage_PushBlock(t *testing.T) {
+	runStorageTest(t, func(t *testing.T, s Storage) {
+		block := testLoadBlock(t, "./fixtures/block1.dat")
+		info := testBlockInfo(s, block)
+
+		assert.False(t, s.HaveBlock(block.Hash()))
+		assert.Nil(t, s.GetBlock(block.Hash()))
+
+		assert.Nil(t, s.PushBlock(block, info, TransactionsDetails{}))
+
+		assert.True(t, s.HaveBlock(block.Hash()))
+		assert.Equal(t, block.Hash(), s.GetBlock(block.Hash()).Hash())
+		assert.Equal(t, info, s.getBlockInfoAtIndex(1))
+
+		topIndex, err := s.TopIndex()
+		assert.Nil(t, err)
+		assert.Equal(t, uint32(1), topIndex)
+
+		topBlock, err := s.TopBlock()
+		assert.Nil(t, err)
+		assert.Equal(t, block.Hash(), topBlock.Hash())
+
+		hash, err := s.HashAtIndex(1)
+		assert.Nil(t, err)
+		assert.Equal(t, block.Hash(), hash)
+
+		assert.Equal(t, ErrStorageBlockExists, s.PushBlock(block, info, TransactionsDetails{}))
+	})
+}
+
+func TestStorage_GetBlockByIndexAndHash(t *testing.T) {
+	runStorageTest(t, func(t *testing.T, s Storage) {
+		block := testLoadBlock(t, "./fixtures/block1.dat")
+
+		_, err := s.GetBlockIndexByHash(block.Hash())
+		assert.Equal(t, ErrStorageBlockNotFound, err)
+
+		_, err = s.GetBlockByHeight(1)
+		assert.Equal(t, ErrStorageBlockNotFound, err)
+
+		assert.Nil(t, s.PushBlock(block, testBlockInfo(s, block), TransactionsDetails{}))
+
+		index, err := s.GetBlockIndexByHash(block.Hash())
+		assert.Nil(t, err)
+		assert.Equal(t, uint32(1), index)
+
+		saved, err := s.GetBlockByHeight(1)
+		assert.Nil(t, err)
+		assert.Equal(t, block.Hash(), saved.Hash())
+
+		transactions, err := s.GetBlockTransactions(1)
+		assert.Nil(t, err)
+		assert.Len(t, transactions, 0)
+
+		_, _, err = s.PopBlock()
+		assert.Nil(t, err)
+
+		_, err = s.GetBlockIndexByHash(block.Hash())
+		assert.Equal(t, ErrStorageBlockNotFound, err)
+
+		_, err = s.GetBlockByHeight(1)
+		assert.Equal(t, ErrStorageBlockNotFound, err)
+
+		_, err = s.GetBlockTransactions(1)
+		assert.Equal(t, ErrStorageBlockNotFound, err)
+	})
+}
+
+func TestStorage_PushBlockGap(t *testing.T) {
+	runStorageTest(t, func(t *testing.T, s Storage) {
+		genesis, err := s.TopBlock()
+		assert.Nil(t, err)
+
+		block := testStorageBlock(testStorageBlock(genesis))
+		info := &blockInfo{Index: block.Index(), Hash: *block.Hash()}
+
+		assert.Equal(t, ErrStorageBlockNotNext, s.PushBlock(block, info, TransactionsDetails{}))
+		assert.False(t, s.HaveBlock(block.Hash()))
+
+		topIndex, err := s.TopIndex()
+		assert.Nil(t, err)
+		assert.Equal(t, uint32(0), topIndex)
+	})
+
+	// Only genesis block is accepted by the empty storage
+	for name, factory := range storageFactories {
+		t.Run(name+"/empty", func(t *testing.T) {
+			s := factory(t)
+			defer s.Close()
+
+			block := testLoadBlock(t, "./fixtures/block1.dat")
+			info := &blockInfo{Index: block.Index(), Hash: *block.Hash()}
+
+			assert.Equal(t, ErrStorageBlockNotNext, s.PushBlock(block, info, TransactionsDetails{}))
+			assert.False(t, s.HaveBlock(block.Hash()))
+		})
+	}
+}
+
+func TestStorage_PushBlockInfoMismatch(t *testing.T) {
+	runStorageTest(t, func(t *testing.T, s Storage) {
+		block := testLoadBlock(t, "./fixtures/block1.dat")
+
+		info := testBlockInfo(s, block)
+		info.Index = 2
+		assert.NotNil(t, s.PushBlock(block, info, TransactionsDetails{}))
+
+		info = testBlockInfo(s, block)
+		info.Hash = crypto.Hash{}
+		assert.NotNil(t, s.PushBlock(block, info, TransactionsDetails{}))
+
+		assert.False(t, s.HaveBlock(block.Hash()))
+	})
+}
+
+func TestStorage_PopBlock(t *testing.T) {
+	runStorageTest(t, func(t *testing.T, s Storage) {
+		genesisBlock := testGenesisBlock(config.MainNet())
+		block := testLoadBlock(t, "./fixtures/block1.dat")
+
+		assert.Nil(t, s.PushBlock(block, testBlockInfo(s, block), TransactionsDetails{}))
+
+		popped, transactions, err := s.PopBlock()
+		assert.Nil(t, err)
+		assert.Equal(t, block.Hash(), popped.Hash())
+		assert.Len(t, transactions, 0)
+
+		assert.False(t, s.HaveBlock(block.Hash()))
+		assert.Nil(t, s.getBlockInfoAtIndex(1))
+
+		topBlock, err := s.TopBlock()
+		assert.Nil(t, err)
+		assert.Equal(t, genesisBlock.Hash(), topBlock.Hash())
+
+		_, _, err = s.PopBlock()
+		assert.Equal(t, ErrStoragePopGenesis, err)
+
+		// Block can be pushed again after pop
+		assert.Nil(t, s.PushBlock(block, testBlockInfo(s, block), TransactionsDetails{}))
+		assert.True(t, s.HaveBlock(block.Hash()))
+	})
+}
+
+func TestStorage_KeyImages(t *testing.T) {
+	runStorageTest(t, func(t *testing.T, s Storage) {
+		block := testLoadBlock(t, "./fixtures/block1.dat")
+		images := []crypto.KeyImage{{1}, {2}}
+
+		_, spent := s.getKeyImageSpentIndex(images[0])
+		assert.False(t, spent)
+
+		details := TransactionsDetails{spentKeyImages: images}
+		assert.Nil(t, s.PushBlock(block, testBlockInfo(s, block), details))
+
+		for _, image := range images {
+			index, spent := s.getKeyImageSpentIndex(image)
+			assert.True(t, spent)
+			assert.Equal(t, uint32(1), index)
+		}
+
+		_, spent = s.getKeyImageSpentIndex(crypto.KeyImage{3})
+		assert.False(t, spent)
+
+		_, _, err := s.PopBlock()
+		assert.Nil(t, err)
+
+		for _, image := range images {
+			_, spent := s.getKeyImageSpentIndex(image)
+			assert.False(t, spent)
+		}
+	})
+}
+
+func TestStorage_KeyOutputs(t *testing.T) {
+	runStorageTest(t, func(t *testing.T, s Storage) {
+		genesisBlock := testGenesisBlock(config.MainNet())
+		genesisOutputs := blockKeyOutputs(genesisBlock, nil)
+		assert.Len(t, genesisOutputs, 1)
+		assert.Equal(t, &genesisOutputs[0], s.getKeyOutput(genesisOutputs[0].Amount, 0))
+
+		block := testLoadBlock(t, "./fixtures/block1.dat")
+		assert.Nil(t, s.PushBlock(block, testBlockInfo(s, block), TransactionsDetails{}))
+
+		outputs := blockKeyOutputs(block, nil)
+		assert.NotEmpty(t, outputs)
+
+		for _, output := range outputs {
+			globalIndex := uint32(0)
+			if output.Amount == genesisOutputs[0].Amount {
+				globalIndex = 1
+			}
+
+			saved := s.getKeyOutput(output.Amount, globalIndex)
+			assert.Equal(t, &output, saved)
+			assert.Equal(t, uint32(1), saved.BlockIndex)
+			assert.Nil(t, s.getKeyOutput(output.Amount, globalIndex+1))
+		}
+
+		_, _, err := s.PopBlock()
+		assert.Nil(t, err)
+
+		for _, output := range outputs {
+			if output.Amount != genesisOutputs[0].Amount {
+				assert.Nil(t, s.getKeyOutput(output.Amount, 0))
+			}
+		}
+
+		assert.Equal(t, &genesisOutputs[0], s.getKeyOutput(genesisOutputs[0].Amount, 0))
+	})
+}
+
+func TestStorage_MultisignatureOutputs(t *testing.T) {
+	runStorageTest(t, func(t *testing.T, s Storage) {
+		block := testLoadBlock(t, "./fixtures/block1.dat")
+		transaction := Transaction{
+			TransactionPrefix: TransactionPrefix{
+				Version:      config.TransactionVersion1,
+				UnlockHeight: 5,
+				Outputs: []TransactionOutput{
+					{Amount: 10, Target: OutputMultisignature{Keys: []crypto.PublicKey{{1}, {2}}, RequiredSignaturesCount: 2}},
+					{Amount: 10, Target: OutputMultisignature{Keys: []crypto.PublicKey{{3}}, RequiredSignaturesCount: 1}},
+				},
+				Extra: []byte{},
+			},
+		}
+
+		pair := MultisigAmountGlobalOutputIndexPair{Amount: 10, GlobalOutputIndex: 0}
+		details := TransactionsDetails{
+			transactions:                     []Transaction{transaction},
+			spentMultisignatureGlobalIndexes: []MultisigAmountGlobalOutputIndexPair{pair},
+		}
+		assert.Nil(t, s.PushBlock(block, testBlockInfo(s, block), details))
+
+		outputs := blockMultisignatureOutputs(block, details.transactions)
+		assert.Len(t, outputs, 2)
+
+		for i, output := range outputs {
+			saved := s.getMultisignatureOutput(10, uint32(i))
+			assert.Equal(t, &output, saved)
+			assert.Equal(t, uint64(5), saved.UnlockTime)
+			assert.Equal(t, uint32(1), saved.BlockIndex)
+		}
+		assert.Nil(t, s.getMultisignatureOutput(10, 2))
+
+		index, spent := s.getMultisignatureSpentIndex(10, 0)
+		assert.True(t, spent)
+		assert.Equal(t, uint32(1), index)
+
+		_, spent = s.getMultisignatureSpentIndex(10, 1)
+		assert.False(t, spent)
+
+		_, _, err := s.PopBlock()
+		assert.Nil(t, err)
+
+		assert.Nil(t, s.getMultisignatureOutput(10, 0))
+		_, spent = s.getMultisignatureSpentIndex(10, 0)
+		assert.False(t, spent)
+	})
+}
+
+func TestStorage_Transactions(t *testing.T) {
+	runStorageTest(t, func(t *testing.T, s Storage) {
+		block := testLoadBlock(t, "./fixtures/block1.dat")
+		transaction := Transaction{
+			TransactionPrefix: TransactionPrefix{
+				Version: config.TransactionVersion1,
+				Outputs: []TransactionOutput{{Amount: 10, Target: OutputKey{PublicKey: crypto.PublicKey{1}}}},
+				Extra:   []byte{},
+			},
+		}
+
+		assert.False(t, s.HaveTransaction(transaction.Hash()))
+
+		details := TransactionsDetails{transactions: []Transaction{transaction}}
+		assert.Nil(t, s.PushBlock(block, testBlockInfo(s, block), details))
+
+		for _, expected := range []*Transaction{&block.BaseTransaction, &transaction} {
+			assert.True(t, s.HaveTransaction(expected.Hash()))
+
+			saved, savedBlock, err := s.GetTransaction(expected.Hash())
+			assert.Nil(t, err)
+			assert.Equal(t, expected.Hash(), saved.Hash())
+			assert.Equal(t, block.Hash(), savedBlock.Hash())
+		}
+
+		_, _, err := s.GetTransaction(&crypto.Hash{1})
+		assert.Equal(t, ErrStorageTransactionNotFound, err)
+
+		_, _, err = s.PopBlock()
+		assert.Nil(t, err)
+
+		assert.False(t, s.HaveTransaction(block.BaseTransaction.Hash()))
+		_, _, err = s.GetTransaction(transaction.Hash())
+		assert.Equal(t, ErrStorageTransactionNotFound, err)
+	})
+}
+
+func TestStorage_PushPopBlocks(t *testing.T) {
+	runStorageTest(t, func(t *testing.T, s Storage) {
+		var blocks []*Block
+		var details []TransactionsDetails
+
+		prev, err := s.TopBlock()
+		assert.Nil(t, err)
+
+		for i := 1; i <= 3; i++ {
+			block := testStorageBlock(prev)
+			transaction := Transaction{
+				TransactionPrefix: TransactionPrefix{
+					Version:      config.TransactionVersion1,
+					UnlockHeight: uint64(i),
+					Outputs: []TransactionOutput{
+						{Amount: 10, Target: OutputKey{PublicKey: crypto.PublicKey{byte(i)}}},
+						{Amount: 10, Target: OutputMultisignature{Keys: []crypto.PublicKey{{byte(i)}}, RequiredSignaturesCount: 1}},
+					},
+					Extra: []byte{},
+				},
+			}
+
+			// Key image of the first block is spent again, it must stay spent in the first block
+			blockDetails := TransactionsDetails{
+				transactions:                     []Transaction{transaction},
+				spentKeyImages:                   []crypto.KeyImage{{byte(i)}, {1}},
+				spentMultisignatureGlobalIndexes: []MultisigAmountGlobalOutputIndexPair{{Amount: 10, GlobalOutputIndex: uint32(i - 1)}},
+			}
+
+			blocks = append(blocks, block)
+			details = append(details, blockDetails)
+			prev = block
+		}
+
+		original := testStorageState(t, s, blocks, details)
+
+		for i, block := range blocks {
+			assert.Nil(t, s.PushBlock(block, testBlockInfo(s, block), details[i]))
+		}
+
+		assert.NotEqual(t, original, testStorageState(t, s, blocks, details))
+
+		index, spent := s.getKeyImageSpentIndex(crypto.KeyImage{1})
+		assert.True(t, spent)
+		assert.Equal(t, uint32(1), index)
+
+		for i := len(blocks) - 1; i >= 0; i-- {
+			popped, transactions, err := s.PopBlock()
+			assert.Nil(t, err)
+			assert.Equal(t, blocks[i].Hash(), popped.Hash())
+			assert.Len(t, transactions, 1)
+			assert.Equal(t, details[i].transactions[0].Hash(), transactions[0].Hash())
+		}
+
+		assert.Equal(t, original, testStorageState(t, s, blocks, details))
+	})
+}
+
+func TestStorage_Missing(t *testing.T) {
+	runStorageTest(t, func(t *testing.T, s Storage) {
+		hash := crypto.Hash{1}
+
+		assert.False(t, s.HaveBlock(&hash))
+		assert.Nil(t, s.GetBlock(&hash))
+		assert.Nil(t, s.getBlockInfoAtIndex(10))
+
+		hashAtIndex, err := s.HashAtIndex(10)
+		assert.Nil(t, err)
+		assert.Nil(t, hashAtIndex)
+	})
+}
+
+func TestBadgerStorage_Reopen(t *testing.T) {
+	dir := tempStorageDir(t)
+	block := testLoadBlock(t, "./fixtures/block1.dat")
+
+	s := newTestBadgerStorage(t, dir)
+	assert.Nil(t, s.Init(testGenesisBlock(config.MainNet())))
+	assert.Nil(t, s.PushBlock(block, testBlockInfo(s, block), TransactionsDetails{}))
+	assert.Nil(t, s.Close())
+
+	s = newTestBadgerStorage(t, dir)
+	assert.Nil(t, s.Init(testGenesisBlock(config.MainNet())))
+
+	topIndex, err := s.TopIndex()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(1), topIndex)
+	assert.True(t, s.HaveBlock(block.Hash()))
+	assert.Nil(t, s.Close())
+}
+
+func TestBadgerStorage_NetworkMismatch(t *testing.T) {
+	dir := tempStorageDir(t)
+
+	s := newTestBadgerStorage(t, dir)
+	assert.Nil(t, s.Init(testGenesisBlock(config.MainNet())))
+	assert.Nil(t, s.Close())
+
+	s = newTestBadgerStorage(t, dir)
+	assert.Equal(t, ErrStorageNetworkMismatch, s.Init(testGenesisBlock(config.TestNet())))
+	assert.Nil(t, s.Close())
+}
+
+func tempStorageDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "karbo-storage")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		_ = os.RemoveAll(dir)
+	})
+
+	return dir
+}
+
+func newTestBadgerStorage(t *testing.T, dir string) Storage {
+	s, err := NewBadgerStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return s
+}
+
+func testGenesisBlock(network *config.Network) *Block {
+	bc := NewBlockChain(network, nil, logrus.New())
+	block, err := bc.GenesisBlock()
+	if err != nil {
+		panic(err)
+	}
+
+	return block
+}
+
+func testLoadBlock(t *testing.T, path string) *Block {
+	payload, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var block Block
+	if err := block.Deserialize(bytes.NewReader(payload)); err != nil {
+		t.Fatal(err)
+	}
+
+	return &block
+}
+
+// testBlockInfo builds info for the block on top of the previous block saved in storage
+func testBlockInfo(s Storage, block *Block) *blockInfo {
+	prevInfo := s.getBlockInfoAtIndex(block.Index() - 1)
+
+	return &blockInfo{
+		Index:                block.Index(),
+		Hash:                 *block.Hash(),
+		CumulativeDifficulty: prevInfo.CumulativeDifficulty + 1,
+		TotalGeneratedCoins:  prevInfo.TotalGeneratedCoins + block.BaseTransaction.Outputs[0].Amount,
+		Timestamp:            block.Timestamp,
+		Size:                 block.BaseTransaction.Size(),
+	}
+}
+
+// testStorageBlock creates block on top of the prev block, block is not valid for the blockchain
+func testStorageBlock(prev *Block) *Block {
+	index := prev.Index() + 1
+
+	return &Block{
+		BlockHeader: BlockHeader{
+			MajorVersion:      config.BlockMajorVersion1,
+			MinorVersion:      config.BlockMinorVersion0,
+			Timestamp:         prev.Timestamp + uint64(config.DifficultyTarget),
+			PreviousBlockHash: *prev.Hash(),
+		},
+		BaseTransaction: Transaction{
+			TransactionPrefix: TransactionPrefix{
+				Version:      config.TransactionVersion1,
+				UnlockHeight: uint64(index + config.MinedMoneyUnlockWindow),
+				Inputs:       []TransactionInput{InputCoinbase{BlockIndex: index}},
+				Outputs: []TransactionOutput{
+					{Amount: 100, Target: OutputKey{PublicKey: crypto.PublicKey{byte(index)}}},
+				},
+				Extra: []byte{},
+			},
+		},
+	}
+}
+
+// testStorageState collects everything the storage returns about the blocks with their details.
+// Badger storage state includes all the saved keys and values.
+func testStorageState(t *testing.T, s Storage, blocks []*Block, details []TransactionsDetails) map[string]interface{} {
+	state := map[string]interface{}{}
+
+	topBlock, err := s.TopBlock()
+	assert.Nil(t, err)
+	state["top"] = *topBlock.Hash()
+
+	for i, block := range blocks {
+		index := block.Index()
+		state[fmt.Sprintf("block %d", index)] = s.HaveBlock(block.Hash())
+		state[fmt.Sprintf("info %d", index)] = s.getBlockInfoAtIndex(index)
+
+		for _, txHash := range blockTransactionsHashes(block, details[i].transactions) {
+			state[fmt.Sprintf("transaction %s", txHash)] = s.HaveTransaction(&txHash)
+		}
+
+		for _, image := range details[i].spentKeyImages {
+			spentIndex, spent := s.getKeyImageSpentIndex(image)
+			state[fmt.Sprintf("key image %s", image)] = fmt.Sprintf("%d %t", spentIndex, spent)
+		}
+
+		for _, pair := range details[i].spentMultisignatureGlobalIndexes {
+			spentIndex, spent := s.getMultisignatureSpentIndex(pair.Amount, pair.GlobalOutputIndex)
+			state[fmt.Sprintf("multisig spent %d %d", pair.Amount, pair.GlobalOutputIndex)] = fmt.Sprintf("%d %t", spentIndex, spent)
+		}
+
+		for _, output := range blockKeyOutputs(block, details[i].transactions) {
+			for globalIndex := uint32(0); globalIndex <= uint32(len(blocks)); globalIndex++ {
+				state[fmt.Sprintf("key output %d %d", output.Amount, globalIndex)] = s.getKeyOutput(output.Amount, globalIndex)
+			}
+		}
+
+		for _, output := range blockMultisignatureOutputs(block, details[i].transactions) {
+			for globalIndex := uint32(0); globalIndex <= uint32(len(blocks)); globalIndex++ {
+				state[fmt.Sprintf("multisig output %d %d", output.Amount, globalIndex)] = s.getMultisignatureOutput(output.Amount, globalIndex)
+			}
+		}
+	}
+
+	if badgerStorage, ok := s.(*badgerStorage); ok {
+		err := badgerStorage.db.View(func(txn *badger.Txn) error {
+			it := txn.NewIterator(badger.DefaultIteratorOptions)
+			defer it.Close()
+
+			for it.Rewind(); it.Valid(); it.Next() {
+				value, err := it.Item().ValueCopy(nil)
+				if err != nil {
+					return err
+				}
+
+				state[fmt.Sprintf("badger %x", it.Item().Key())] = value
+			}
+
+			return nil
+		})
+		assert.Nil(t, err)
+	}
+
+	return state
+}
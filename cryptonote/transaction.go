@@ -182,6 +182,18 @@ func (tp *TransactionPrefix) serialize() []byte {
 
 			// Write key image
 			_ = binary.Write(&serialized, binary.LittleEndian, inputKey.KeyImage)
+		case InputMultiSignature:
+			inputMultisig := input.(InputMultiSignature)
+			serialized.WriteByte(TxTagMultisignature)
+
+			written = binary.PutUvarint(varIntBuf, inputMultisig.Amount)
+			serialized.Write(varIntBuf[:written])
+
+			written = binary.PutUvarint(varIntBuf, uint64(inputMultisig.SignatureCount))
+			serialized.Write(varIntBuf[:written])
+
+			written = binary.PutUvarint(varIntBuf, uint64(inputMultisig.OutputIndex))
+			serialized.Write(varIntBuf[:written])
 		default:
 		}
 	}
@@ -198,6 +210,19 @@ func (tp *TransactionPrefix) serialize() []byte {
 			outputKey := output.Target.(OutputKey)
 			serialized.WriteByte(TxTagKey)
 			serialized.Write(outputKey.PublicKey[:])
+		case OutputMultisignature:
+			outputMultisig := output.Target.(OutputMultisignature)
+			serialized.WriteByte(TxTagMultisignature)
+
+			written = binary.PutUvarint(varIntBuf, uint64(len(outputMultisig.Keys)))
+			serialized.Write(varIntBuf[:written])
+
+			for _, key := range outputMultisig.Keys {
+				serialized.Write(key[:])
+			}
+
+			written = binary.PutUvarint(varIntBuf, uint64(outputMultisig.RequiredSignaturesCount))
+			serialized.Write(varIntBuf[:written])
 		default:
 		}
 	}
@@ -283,8 +308,26 @@ func (tp *TransactionPrefix) deserialize(br *bytes.Reader) error {
 				KeyImage:      Key,
 			}
 		case TxTagMultisignature:
-			// TODO: Implement multisig
-			return errors.New("not implemented")
+			amount, err := binary.ReadUvarint(br)
+			if err != nil {
+				return err
+			}
+
+			signatureCount, err := binary.ReadUvarint(br)
+			if err != nil {
+				return err
+			}
+
+			outputIndex, err := binary.ReadUvarint(br)
+			if err != nil {
+				return err
+			}
+
+			tp.Inputs[inputIndex] = InputMultiSignature{
+				Amount:         amount,
+				SignatureCount: uint8(signatureCount),
+				OutputIndex:    uint32(outputIndex),
+			}
 		default:
 			return errors.New(fmt.Sprintf("unknown tx input tag: %x", tag))
 		}
@@ -322,8 +365,35 @@ func (tp *TransactionPrefix) deserialize(br *bytes.Reader) error {
 				Target: OutputKey{keyBytes},
 			}
 		case TxTagMultisignature:
-			// TODO: Implement multisig
-			return errors.New("not implemented")
+			size, err := binary.ReadUvarint(br)
+			if err != nil {
+				return err
+			}
+
+			// Every key takes 32 bytes, so the size can't be bigger than the rest of the transaction
+			if size > uint64(br.Len())/32 {
+				return errors.New("invalid multisignature keys count")
+			}
+
+			keys := make([]crypto.PublicKey, size)
+			for i := range keys {
+				if err := binary.Read(br, binary.LittleEndian, &keys[i]); err != nil {
+					return err
+				}
+			}
+
+			requiredSignaturesCount, err := binary.ReadUvarint(br)
+			if err != nil {
+				return err
+			}
+
+			tp.Outputs[outputIndex] = TransactionOutput{
+				Amount: amount,
+				Target: OutputMultisignature{
+					Keys:                    keys,
+					RequiredSignaturesCount: byte(requiredSignaturesCount),
+				},
+			}
 		default:
 			return errors.New(fmt.Sprintf("unknown tx output tag: %x", tag))
 		}
@@ -86,3 +86,30 @@ func TestTransaction_Deserialize(t *testing.T) {
 	hash := transaction.Hash()
 	assert.Equal(t, expectedHash, *hash)
 }
+
+func TestTransaction_SerializeMultisignature(t *testing.T) {
+	transaction := Transaction{
+		TransactionPrefix: TransactionPrefix{
+			Version:      config.TransactionVersion1,
+			UnlockHeight: 10,
+			Inputs: []TransactionInput{
+				InputMultiSignature{Amount: 1000, SignatureCount: 2, OutputIndex: 300},
+			},
+			Outputs: []TransactionOutput{
+				{Amount: 900, Target: OutputMultisignature{Keys: []crypto.PublicKey{{1}, {2}, {3}}, RequiredSignaturesCount: 2}},
+				{Amount: 100, Target: OutputKey{PublicKey: crypto.PublicKey{4}}},
+			},
+			Extra: []byte{},
+		},
+		TransactionSignatures: TransactionSignatures{{{C: crypto.EllipticCurveScalar{1}}, {R: crypto.EllipticCurveScalar{2}}}},
+	}
+
+	payload := transaction.Serialize()
+
+	var deserialized Transaction
+	assert.Nil(t, deserialized.Deserialize(bytes.NewReader(payload)))
+	assert.Equal(t, transaction.Inputs, deserialized.Inputs)
+	assert.Equal(t, transaction.Outputs, deserialized.Outputs)
+	assert.Equal(t, transaction.TransactionSignatures, deserialized.TransactionSignatures)
+	assert.Equal(t, payload, deserialized.Serialize())
+}
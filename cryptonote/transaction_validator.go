@@ -457,14 +457,41 @@ func (validator *blockTransactionsValidator) validateTransactionInputExpensive(t
 			}
 
 		case InputMultiSignature:
-			// input := input.(InputMultiSignature)
+			input := input.(InputMultiSignature)
 			logger := validator.logger.WithFields(log.Fields{
 				"transaction_input_type": "InputMultiSignature",
 			})
 
-			err := ErrTransactionMultiSignaturesNotImplemented
-			logger.Error(err)
-			return err
+			output, _, exists := validator.bc.IsMultiSignatureOutputExists(input.Amount, input.OutputIndex, validator.blockIndex)
+			if !exists {
+				err := ErrTransactionInputInvalidGlobalIndex
+				logger.Error(err)
+				return err
+			}
+
+			sigs := transaction.TransactionSignatures[inputIndex]
+
+			if len(sigs) != int(output.RequiredSignaturesCount) {
+				err := ErrTransactionInputInvalidSignaturesCount
+				logger.Error(err)
+				return err
+			}
+
+			// Signatures must be in the same order as the output keys, keys without signature are skipped
+			keyIndex := 0
+			for sigIndex := range sigs {
+				for keyIndex < len(output.Keys) && !sigs[sigIndex].Check(prefixHash, &output.Keys[keyIndex]) {
+					keyIndex++
+				}
+
+				if keyIndex == len(output.Keys) {
+					err := ErrTransactionInputInvalidSignatures
+					logger.Error(err)
+					return err
+				}
+
+				keyIndex++
+			}
 		default:
 			err := ErrTransactionInputUnknownType
 			logger.Error(err)
@@ -0,0 +1,315 @@
+package cryptonote
+
+import (
+	"github.com/r3volut1oner/go-karbo/config"
+	"github.com/r3volut1oner/go-karbo/crypto"
+	log "github.com/sirupsen/logrus"
+	"sync"
+)
+
+// TxPool keeps transactions that are not included in the blockchain yet.
+//
+// Transactions are validated against the top of the main chain before they are added to the pool.
+// Pool is notified by the blockchain when blocks are pushed or popped,
+// so mined transactions are removed and transactions of the popped blocks are returned back.
+type TxPool struct {
+	// bc is the blockchain transactions are validated against
+	bc *BlockChain
+
+	// transactions in the pool by their hashes
+	transactions map[crypto.Hash]*poolTransaction
+
+	// keyImages spent by the pool transactions, used for rejecting double spends inside the pool
+	keyImages map[crypto.KeyImage]crypto.Hash
+
+	// multisignatureOutputs spent by the pool transactions, used for rejecting double spends inside the pool
+	multisignatureOutputs map[MultisigAmountGlobalOutputIndexPair]crypto.Hash
+
+	logger *log.Logger
+
+	sync.RWMutex
+}
+
+// poolTransaction is the transaction with the pool related details
+type poolTransaction struct {
+	transaction Transaction
+
+	fee uint64
+
+	// receiveTime is the timestamp when transaction was added to the pool
+	receiveTime uint64
+
+	// keptByBlock flags transactions that are returned to the pool from the popped blocks
+	keptByBlock bool
+}
+
+// NewTxPool creates memory pool and attaches it to the blockchain
+func NewTxPool(bc *BlockChain, logger *log.Logger) *TxPool {
+	pool := &TxPool{
+		bc:                    bc,
+		transactions:          map[crypto.Hash]*poolTransaction{},
+		keyImages:             map[crypto.KeyImage]crypto.Hash{},
+		multisignatureOutputs: map[MultisigAmountGlobalOutputIndexPair]crypto.Hash{},
+		logger:                logger,
+	}
+
+	bc.Lock()
+	bc.pool = pool
+	bc.Unlock()
+
+	return pool
+}
+
+// AddTransaction validates transaction against the top of the main chain and adds it to the pool.
+//
+// This function is safe for concurrent access.
+func (pool *TxPool) AddTransaction(transaction *Transaction) error {
+	pool.bc.RLock()
+	defer pool.bc.RUnlock()
+
+	pool.Lock()
+	defer pool.Unlock()
+
+	txHash := transaction.Hash()
+	logger := pool.logger.WithFields(log.Fields{
+		"transaction_hash": txHash.String(),
+	})
+
+	if _, ok := pool.transactions[*txHash]; ok {
+		err := ErrTxPoolTransactionExists
+		logger.Debug(err)
+		return err
+	}
+
+	if pool.bc.hasTransaction(txHash) {
+		err := ErrTxPoolTransactionInChain
+		logger.Debug(err)
+		return err
+	}
+
+	if err := pool.checkInputsConflict(transaction); err != nil {
+		logger.Debug(err)
+		return err
+	}
+
+	validator := NewBlockTransactionsValidator(pool.bc, pool.bc.bestTip.Index()+1, logger.Logger)
+	if err := validator.validate(transaction); err != nil {
+		return err
+	}
+
+	pool.add(&poolTransaction{
+		transaction: *transaction,
+		fee:         validator.cumulativeFee,
+		receiveTime: pool.bc.Network.Timestamp(),
+	})
+
+	logger.WithField("transaction_fee", validator.cumulativeFee).Debug("transaction added to pool")
+
+	return nil
+}
+
+// HaveTransaction returns whether the transaction is in the pool.
+//
+// This function is safe for concurrent access.
+func (pool *TxPool) HaveTransaction(hash *crypto.Hash) bool {
+	pool.RLock()
+	_, ok := pool.transactions[*hash]
+	pool.RUnlock()
+	return ok
+}
+
+// GetTransaction returns transaction from the pool.
+// Returns nil if transaction not found.
+//
+// This function is safe for concurrent access.
+func (pool *TxPool) GetTransaction(hash *crypto.Hash) *Transaction {
+	pool.RLock()
+	defer pool.RUnlock()
+
+	if ptx, ok := pool.transactions[*hash]; ok {
+		transaction := ptx.transaction
+		return &transaction
+	}
+
+	return nil
+}
+
+// TransactionsHashes returns hashes of all the transactions in the pool.
+//
+// This function is safe for concurrent access.
+func (pool *TxPool) TransactionsHashes() []crypto.Hash {
+	pool.RLock()
+	defer pool.RUnlock()
+
+	hashes := make([]crypto.Hash, 0, len(pool.transactions))
+	for hash := range pool.transactions {
+		hashes = append(hashes, hash)
+	}
+
+	return hashes
+}
+
+// Size returns number of transactions in the pool.
+//
+// This function is safe for concurrent access.
+func (pool *TxPool) Size() int {
+	pool.RLock()
+	size := len(pool.transactions)
+	pool.RUnlock()
+	return size
+}
+
+// RemoveExpired removes transactions that are kept in the pool longer than allowed.
+// Returns hashes of the removed transactions.
+//
+// This function is safe for concurrent access.
+func (pool *TxPool) RemoveExpired() []crypto.Hash {
+	pool.Lock()
+	defer pool.Unlock()
+
+	now := pool.bc.Network.Timestamp()
+
+	var removed []crypto.Hash
+	for hash, ptx := range pool.transactions {
+		liveTime := config.MempoolTxLiveTime
+		if ptx.keptByBlock {
+			liveTime = config.MempoolTxFromAltBlockLiveTime
+		}
+
+		if now-ptx.receiveTime > liveTime {
+			pool.remove(&hash)
+			removed = append(removed, hash)
+
+			pool.logger.WithField("transaction_hash", hash.String()).Debug("transaction expired in pool")
+		}
+	}
+
+	return removed
+}
+
+// onBlockPushed removes transactions of the block from the pool with
+// the transactions that spend same key images or multisignature outputs.
+//
+// This function is called by the blockchain and must not lock it.
+func (pool *TxPool) onBlockPushed(transactions []Transaction) {
+	pool.Lock()
+	defer pool.Unlock()
+
+	for i := range transactions {
+		pool.remove(transactions[i].Hash())
+
+		for _, input := range transactions[i].Inputs {
+			switch input := input.(type) {
+			case InputKey:
+				if hash, ok := pool.keyImages[input.KeyImage]; ok {
+					pool.remove(&hash)
+				}
+			case InputMultiSignature:
+				if hash, ok := pool.multisignatureOutputs[multisignatureInputOutput(input)]; ok {
+					pool.remove(&hash)
+				}
+			}
+		}
+	}
+}
+
+// onBlockPopped returns transactions of the popped block to the pool.
+// Transactions are not validated here, they were valid in the popped block.
+//
+// This function is called by the blockchain and must not lock it.
+func (pool *TxPool) onBlockPopped(transactions []Transaction) {
+	pool.Lock()
+	defer pool.Unlock()
+
+	now := pool.bc.Network.Timestamp()
+
+	for i := range transactions {
+		if _, ok := pool.transactions[*transactions[i].Hash()]; ok {
+			continue
+		}
+
+		if pool.checkInputsConflict(&transactions[i]) != nil {
+			continue
+		}
+
+		pool.add(&poolTransaction{
+			transaction: transactions[i],
+			receiveTime: now,
+			keptByBlock: true,
+		})
+	}
+}
+
+// removeTransaction removes transaction from the pool if it exists.
+//
+// This function is called by the blockchain and must not lock it.
+func (pool *TxPool) removeTransaction(hash *crypto.Hash) {
+	pool.Lock()
+	pool.remove(hash)
+	pool.Unlock()
+}
+
+// checkInputsConflict checks if any of the transaction key images or multisignature outputs is spent
+// by the pool transactions
+//
+// This function is NOT safe for concurrent access
+func (pool *TxPool) checkInputsConflict(transaction *Transaction) error {
+	for _, input := range transaction.Inputs {
+		switch input := input.(type) {
+		case InputKey:
+			if _, ok := pool.keyImages[input.KeyImage]; ok {
+				return ErrTxPoolKeyImageConflict
+			}
+		case InputMultiSignature:
+			if _, ok := pool.multisignatureOutputs[multisignatureInputOutput(input)]; ok {
+				return ErrTxPoolMultisignatureConflict
+			}
+		}
+	}
+
+	return nil
+}
+
+// add saves transaction to the pool with its key images and multisignature outputs
+//
+// This function is NOT safe for concurrent access
+func (pool *TxPool) add(ptx *poolTransaction) {
+	hash := *ptx.transaction.Hash()
+
+	pool.transactions[hash] = ptx
+
+	for _, input := range ptx.transaction.Inputs {
+		switch input := input.(type) {
+		case InputKey:
+			pool.keyImages[input.KeyImage] = hash
+		case InputMultiSignature:
+			pool.multisignatureOutputs[multisignatureInputOutput(input)] = hash
+		}
+	}
+}
+
+// remove deletes transaction from the pool with its key images and multisignature outputs
+//
+// This function is NOT safe for concurrent access
+func (pool *TxPool) remove(hash *crypto.Hash) {
+	ptx, ok := pool.transactions[*hash]
+	if !ok {
+		return
+	}
+
+	for _, input := range ptx.transaction.Inputs {
+		switch input := input.(type) {
+		case InputKey:
+			delete(pool.keyImages, input.KeyImage)
+		case InputMultiSignature:
+			delete(pool.multisignatureOutputs, multisignatureInputOutput(input))
+		}
+	}
+
+	delete(pool.transactions, *hash)
+}
+
+// multisignatureInputOutput returns the output spent by the multisignature input
+func multisignatureInputOutput(input InputMultiSignature) MultisigAmountGlobalOutputIndexPair {
+	return MultisigAmountGlobalOutputIndexPair{Amount: input.Amount, GlobalOutputIndex: input.OutputIndex}
+}
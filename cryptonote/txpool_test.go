@@ -0,0 +1,170 @@
+package cryptonote
+
+import (
+	"github.com/r3volut1oner/go-karbo/config"
+	"github.com/r3volut1oner/go-karbo/crypto"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestTxPool_AddTransaction(t *testing.T) {
+	bc, _ := testBlockChain(t)
+	pool := testTxPool(bc)
+
+	block := testMineChain(t, bc, bc.TopBlock(), 1, 1)[0]
+	image := testKeyImage(t)
+
+	transaction := testTransaction(t, bc, block.BaseTransaction.Outputs[0].Amount, image)
+	assert.Nil(t, pool.AddTransaction(&transaction))
+	assert.Equal(t, 1, pool.Size())
+	assert.True(t, pool.HaveTransaction(transaction.Hash()))
+	assert.Equal(t, transaction.Hash(), pool.GetTransaction(transaction.Hash()).Hash())
+	assert.Equal(t, []crypto.Hash{*transaction.Hash()}, pool.TransactionsHashes())
+
+	assert.Equal(t, ErrTxPoolTransactionExists, pool.AddTransaction(&transaction))
+
+	conflict := testTransaction(t, bc, block.BaseTransaction.Outputs[0].Amount, image)
+	assert.Equal(t, ErrTxPoolKeyImageConflict, pool.AddTransaction(&conflict))
+
+	invalid := testTransaction(t, bc, block.BaseTransaction.Outputs[0].Amount, testKeyImage(t))
+	invalid.Inputs = []TransactionInput{}
+	invalid.TransactionSignatures = TransactionSignatures{}
+	assert.Equal(t, ErrTransactionEmptyInputs, pool.AddTransaction(&invalid))
+
+	assert.Equal(t, 1, pool.Size())
+	assert.Nil(t, pool.GetTransaction(conflict.Hash()))
+}
+
+func TestTxPool_RemoveExpired(t *testing.T) {
+	bc, _ := testBlockChain(t)
+	pool := testTxPool(bc)
+
+	block := testMineChain(t, bc, bc.TopBlock(), 1, 1)[0]
+	amount := block.BaseTransaction.Outputs[0].Amount
+
+	expired := testTransaction(t, bc, amount, testKeyImage(t))
+	assert.Nil(t, pool.AddTransaction(&expired))
+	pool.transactions[*expired.Hash()].receiveTime -= config.MempoolTxLiveTime + 1
+
+	kept := testTransaction(t, bc, amount, testKeyImage(t))
+	assert.Nil(t, pool.AddTransaction(&kept))
+
+	assert.Equal(t, []crypto.Hash{*expired.Hash()}, pool.RemoveExpired())
+	assert.False(t, pool.HaveTransaction(expired.Hash()))
+	assert.True(t, pool.HaveTransaction(kept.Hash()))
+
+	// Key image of the expired transaction can be spent again
+	respent := testTransaction(t, bc, amount, expired.Inputs[0].(InputKey).KeyImage)
+	assert.Nil(t, pool.AddTransaction(&respent))
+}
+
+func TestTxPool_BlockPushedAndPopped(t *testing.T) {
+	bc, _ := testBlockChain(t)
+	pool := testTxPool(bc)
+
+	prev := testMineChain(t, bc, bc.TopBlock(), 1, 1)[0]
+	amount := prev.BaseTransaction.Outputs[0].Amount
+	image := testKeyImage(t)
+
+	pooled := testTransaction(t, bc, amount, image)
+	assert.Nil(t, pool.AddTransaction(&pooled))
+
+	// Mined transaction spends same key image, so the pool transaction is removed as double spend
+	mined := testTransaction(t, bc, amount, image)
+
+	block := testMineBlockWithTransactions(t, bc, prev, 1, []Transaction{mined}, bc.Network.MinimalFeeValidator(2))
+	assert.Nil(t, bc.AddBlock(block, [][]byte{mined.Serialize()}))
+	assert.Equal(t, 0, pool.Size())
+	assert.False(t, pool.HaveTransaction(pooled.Hash()))
+
+	// Heavier alternative chain pops the block and returns its transaction to the pool
+	testMineChain(t, bc, prev, 2, 2)
+	assert.Equal(t, uint32(3), bc.TopBlock().Index())
+	assert.True(t, pool.HaveTransaction(mined.Hash()))
+	assert.True(t, pool.transactions[*mined.Hash()].keptByBlock)
+}
+
+func TestTxPool_MultisignatureConflict(t *testing.T) {
+	bc, _ := testBlockChain(t)
+	pool := testTxPool(bc)
+
+	spend := func(amount uint64, outputIndex uint32, change uint64) Transaction {
+		return Transaction{
+			TransactionPrefix: TransactionPrefix{
+				Version: config.TransactionVersion1,
+				Inputs:  []TransactionInput{InputMultiSignature{Amount: amount, SignatureCount: 1, OutputIndex: outputIndex}},
+				Outputs: []TransactionOutput{{Amount: change, Target: OutputKey{}}},
+				Extra:   []byte{},
+			},
+			TransactionSignatures: TransactionSignatures{[]crypto.Signature{{}}},
+		}
+	}
+
+	pooled := spend(10, 0, 1)
+	pool.add(&poolTransaction{transaction: pooled})
+
+	// Same multisignature output can't be spent twice in the pool
+	conflict := spend(10, 0, 2)
+	assert.Equal(t, ErrTxPoolMultisignatureConflict, pool.AddTransaction(&conflict))
+
+	otherIndex, otherAmount := spend(10, 1, 2), spend(20, 0, 2)
+	assert.Nil(t, pool.checkInputsConflict(&otherIndex))
+	assert.Nil(t, pool.checkInputsConflict(&otherAmount))
+
+	// Mined transaction spends same output, so the pool transaction is removed as double spend
+	pool.onBlockPushed([]Transaction{conflict})
+	assert.Equal(t, 0, pool.Size())
+	assert.Empty(t, pool.multisignatureOutputs)
+
+	// Only one of the popped transactions spending same output is returned to the pool
+	pool.onBlockPopped([]Transaction{pooled, conflict})
+	assert.Equal(t, 1, pool.Size())
+	assert.True(t, pool.HaveTransaction(pooled.Hash()))
+	assert.Equal(t, map[MultisigAmountGlobalOutputIndexPair]crypto.Hash{{Amount: 10, GlobalOutputIndex: 0}: *pooled.Hash()}, pool.multisignatureOutputs)
+}
+
+// testTxPool creates memory pool attached to the blockchain
+func testTxPool(bc *BlockChain) *TxPool {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	return NewTxPool(bc, logger)
+}
+
+// testKeyImage generates key image of the random key
+func testKeyImage(t *testing.T) crypto.KeyImage {
+	secretKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	publicKey, err := crypto.PublicFromSecret(&secretKey)
+	assert.Nil(t, err)
+	image, err := crypto.GenerateKeyImage(publicKey, &secretKey)
+	assert.Nil(t, err)
+
+	return *image
+}
+
+// testTransaction creates transaction spending the first output with the amount and paying minimal fee.
+// Signatures are not valid, so the transaction can be used only in the checkpoints zone.
+func testTransaction(t *testing.T, bc *BlockChain, amount uint64, image crypto.KeyImage) Transaction {
+	secretKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	publicKey, err := crypto.PublicFromSecret(&secretKey)
+	assert.Nil(t, err)
+
+	fee := bc.Network.MinimalFeeValidator(bc.TopBlock().Index() + 1)
+
+	return Transaction{
+		TransactionPrefix: TransactionPrefix{
+			Version: config.TransactionVersion1,
+			Inputs: []TransactionInput{
+				InputKey{Amount: amount, OutputIndexes: []uint32{0}, KeyImage: image},
+			},
+			Outputs: []TransactionOutput{
+				{Amount: amount - fee, Target: OutputKey{PublicKey: *publicKey}},
+			},
+			Extra: []byte{},
+		},
+		TransactionSignatures: TransactionSignatures{{crypto.Signature{}}},
+	}
+}
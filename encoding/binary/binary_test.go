@@ -72,3 +72,18 @@ func TestSimpleTestElement(t *testing.T)  {
 	assert.Equal(t, testData1, decoded)
 }
 
+
+type testPackedElement struct {
+	Value  uint32    `binary:"value"`
+	Hashes [][4]byte `binary:"hashes,binary"`
+}
+
+func TestEmptyBinaryLastField(t *testing.T) {
+	encoded, err := Marshal(testPackedElement{Value: 1})
+	assert.Nil(t, err)
+
+	var decoded testPackedElement
+	assert.Nil(t, Unmarshal(encoded, &decoded))
+	assert.Equal(t, uint32(1), decoded.Value)
+	assert.Empty(t, decoded.Hashes)
+}
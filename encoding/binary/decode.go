@@ -131,7 +131,7 @@ func (d *Decoder) readValue(value reflect.Value, typeByte byte) error {
 		}
 
 		b := make([]byte, size)
-		if _, err := d.r.Read(b); err != nil {
+		if _, err := io.ReadFull(d.r, b); err != nil {
 			return err
 		}
 
@@ -216,7 +216,7 @@ func (d *Decoder) readVarInt() (uint64, error) {
 	allBytes := make([]byte, 8)
 	allBytes[0] = sizeBytes[0]
 
-	if _, err := d.r.Read(allBytes[1:bytesLeft+1]); err != nil {
+	if _, err := io.ReadFull(d.r, allBytes[1:bytesLeft+1]); err != nil {
 		return 0, err
 	}
 
@@ -4,11 +4,9 @@ import (
 	"errors"
 	"fmt"
 	"github.com/google/uuid"
-	"github.com/r3volut1oner/go-karbo/config"
 	"github.com/r3volut1oner/go-karbo/crypto"
 	"github.com/r3volut1oner/go-karbo/cryptonote"
 	"github.com/r3volut1oner/go-karbo/encoding/binary"
-	"math/rand"
 	"reflect"
 	"time"
 )
@@ -54,6 +52,9 @@ type TimedSyncResponse struct {
 
 type PingRequest struct{}
 
+// PingStatusOK is the status of the successful ping response
+const PingStatusOK = "OK"
+
 type PingResponse struct {
 	Status string `binary:"status"`
 	PeerID uint64 `binary:"peer_id"`
@@ -62,6 +63,11 @@ type PingResponse struct {
 var mapCommandStructs = map[uint32]interface{}{
 	CommandHandshake: HandshakeRequest{},
 	CommandTimedSync: TimedSyncRequest{},
+	CommandPing:      PingRequest{},
+
+	CommandRequestStatInfo:     StatInfoRequest{},
+	CommandRequestNetworkState: NetworkStateRequest{},
+	CommandRequestPeerID:       PeerIDRequest{},
 }
 
 func newTimedSyncResponse(n *Node) (*TimedSyncResponse, error) {
@@ -72,13 +78,14 @@ func newTimedSyncResponse(n *Node) (*TimedSyncResponse, error) {
 	}, nil
 }
 
-func newBasicNodeData(n *config.Network) BasicNodeData {
+// newBasicNodeData returns data of our node, port is the port we are listening for the incoming connections
+func newBasicNodeData(n *Node) BasicNodeData {
 	return BasicNodeData{
-		NetworkID: n.NetworkID,
-		Version:   n.P2PCurrentVersion,
+		NetworkID: n.Config.Network.NetworkID,
+		Version:   n.Config.Network.P2PCurrentVersion,
 		LocalTime: uint64(time.Now().Unix()),
-		PeerID:    uint64(rand.Int63()),
-		MyPort:    32347,
+		PeerID:    n.Config.PeerID,
+		MyPort:    n.listenPort(),
 	}
 }
 
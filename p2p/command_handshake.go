@@ -2,7 +2,6 @@ package p2p
 
 import (
 	"errors"
-	"github.com/r3volut1oner/go-karbo/cryptonote"
 	"go.uber.org/zap"
 	"strconv"
 )
@@ -23,22 +22,24 @@ var (
 	ErrHandshakeNotIncoming     = errors.New("handshake not from incoming connection")
 	ErrHandshakeHasID           = errors.New("ID for the connecting peer already set")
 	ErrHandshakeProcessSyncData = errors.New("failed to process sync data")
+	ErrHandshakeSelf            = errors.New("handshake from our own node")
+	ErrHandshakeDuplicatePeer   = errors.New("peer with same ID already connected")
 )
 
 // NewHandshakeRequest returns new struct to be sent as handshake request command to new peer.
-func NewHandshakeRequest(bc *cryptonote.BlockChain) HandshakeRequest {
+func NewHandshakeRequest(n *Node) HandshakeRequest {
 	return HandshakeRequest{
-		NodeData:    newBasicNodeData(bc.Network),
-		PayloadData: *newSyncData(bc),
+		NodeData:    newBasicNodeData(n),
+		PayloadData: *newSyncData(n.Blockchain),
 	}
 }
 
 // NewHandshakeResponse returns new struct to make response for handshake request
-func NewHandshakeResponse(bc *cryptonote.BlockChain, peerEntries []PeerEntry) HandshakeResponse {
+func NewHandshakeResponse(n *Node) HandshakeResponse {
 	return HandshakeResponse{
-		NodeData:    newBasicNodeData(bc.Network),
-		PayloadData: *newSyncData(bc),
-		Peers:       peerEntries,
+		NodeData:    newBasicNodeData(n),
+		PayloadData: *newSyncData(n.Blockchain),
+		Peers:       n.ps.toPeerEntries(),
 	}
 }
 
@@ -75,8 +76,18 @@ func (n *Node) HandleHandshake(p *Peer, req HandshakeRequest) error {
 		return err
 	}
 
+	if req.NodeData.PeerID == n.Config.PeerID {
+		err := ErrHandshakeSelf
+		p.logger.Error(err)
+		p.Shutdown()
+		return err
+	}
+
 	p.SetID(req.NodeData.PeerID)
 
+	// Peer accepts connections on its own port, not on the port of this connection
+	p.address.Port = req.NodeData.MyPort
+
 	p.logger.Debug("handshake received")
 
 	if err := n.processSyncData(p, req.PayloadData, true); err != nil {
@@ -85,7 +96,12 @@ func (n *Node) HandleHandshake(p *Peer, req HandshakeRequest) error {
 		return err
 	}
 
-	// TODO: Send ping and make sure we can connect to the peer and add it to the white list.
+	if err := n.ps.addConnected(p); err != nil {
+		err := ErrHandshakeDuplicatePeer
+		p.logger.Error(err)
+		p.Shutdown()
+		return err
+	}
 
 	return nil
 }
@@ -0,0 +1,281 @@
+package p2p
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"github.com/r3volut1oner/go-karbo/crypto"
+	"net"
+	"runtime"
+	"time"
+)
+
+// ProofOfTrust is the request signature made with the trusted key.
+// Proof is valid for the single node and can't be reused.
+type ProofOfTrust struct {
+	PeerID uint64   `binary:"peer_id"`
+	Time   uint64   `binary:"time"`
+	Sign   [64]byte `binary:"sign"`
+}
+
+type StatInfoRequest struct {
+	Trust ProofOfTrust `binary:"tr"`
+}
+
+type CoreStatInfo struct {
+	TxPoolSize        uint64 `binary:"tx_pool_size"`
+	BlockchainHeight  uint64 `binary:"blockchain_height"`
+	MiningSpeed       uint64 `binary:"mining_speed"`
+	AlternativeBlocks uint64 `binary:"alternative_blocks"`
+	TopBlockHash      string `binary:"top_block_id_str"`
+}
+
+type StatInfoResponse struct {
+	Version                  string       `binary:"version"`
+	OSVersion                string       `binary:"os_version"`
+	ConnectionsCount         uint64       `binary:"connections_count"`
+	IncomingConnectionsCount uint64       `binary:"incoming_connections_count"`
+	PayloadInfo              CoreStatInfo `binary:"payload_info"`
+}
+
+type NetworkStateRequest struct {
+	Trust ProofOfTrust `binary:"tr"`
+}
+
+type ConnectionEntry struct {
+	Address    NetworkAddress
+	ID         uint64
+	IsIncoming bool
+}
+
+type NetworkStateResponse struct {
+	WhitePeers  []PeerEntry       `binary:"local_peerlist_white,binary"`
+	GreyPeers   []PeerEntry       `binary:"local_peerlist_gray,binary"`
+	Connections []ConnectionEntry `binary:"connections_list,binary"`
+	PeerID      uint64            `binary:"my_id"`
+	LocalTime   uint64            `binary:"local_time"`
+}
+
+type PeerIDRequest struct{}
+
+type PeerIDResponse struct {
+	PeerID uint64 `binary:"my_id"`
+}
+
+var (
+	ErrTrustNoKey       = errors.New("trusted public key is not configured")
+	ErrTrustTimeDelta   = errors.New("proof of trust time is too far from the local time")
+	ErrTrustTimeReused  = errors.New("proof of trust time is not after the last trusted request")
+	ErrTrustWrongPeerID = errors.New("proof of trust is made for the other peer")
+	ErrTrustSignature   = errors.New("proof of trust signature is invalid")
+)
+
+// NewProofOfTrust signs the proof for the node with the peer ID
+func NewProofOfTrust(peerID uint64, now time.Time, secretKey *crypto.SecretKey) (*ProofOfTrust, error) {
+	tr := ProofOfTrust{
+		PeerID: peerID,
+		Time:   uint64(now.Unix()),
+	}
+
+	hash := tr.Hash()
+	sig, err := hash.Sign(secretKey)
+	if err != nil {
+		return nil, err
+	}
+
+	copy(tr.Sign[:32], sig.C[:])
+	copy(tr.Sign[32:], sig.R[:])
+
+	return &tr, nil
+}
+
+// Hash returns the signed hash of the peer ID and the time
+func (tr *ProofOfTrust) Hash() crypto.Hash {
+	var b [16]byte
+	binary.LittleEndian.PutUint64(b[:8], tr.PeerID)
+	binary.LittleEndian.PutUint64(b[8:], tr.Time)
+
+	return crypto.HashFromBytes(b[:])
+}
+
+func (tr *ProofOfTrust) signature() *crypto.Signature {
+	var sig crypto.Signature
+	copy(sig.C[:], tr.Sign[:32])
+	copy(sig.R[:], tr.Sign[32:])
+
+	return &sig
+}
+
+// checkTrust verifies the proof is signed by the trusted key for our node.
+// Time of the proof must be after the time of the last trusted request, so the proof can't be replayed.
+//
+// This method is safe for concurrent access.
+func (n *Node) checkTrust(tr ProofOfTrust, now time.Time) error {
+	if n.Config.TrustedPublicKey == nil {
+		return ErrTrustNoKey
+	}
+
+	localTime := uint64(now.Unix())
+
+	delta := localTime - tr.Time
+	if tr.Time > localTime {
+		delta = tr.Time - localTime
+	}
+
+	if delta > uint64(ProofOfTrustMaxTimeDelta/time.Second) {
+		return ErrTrustTimeDelta
+	}
+
+	if tr.PeerID != n.Config.PeerID {
+		return ErrTrustWrongPeerID
+	}
+
+	hash := tr.Hash()
+	if !tr.signature().Check(&hash, n.Config.TrustedPublicKey) {
+		return ErrTrustSignature
+	}
+
+	n.trustMutex.Lock()
+	defer n.trustMutex.Unlock()
+
+	if tr.Time <= n.lastTrustTime {
+		return ErrTrustTimeReused
+	}
+
+	n.lastTrustTime = tr.Time
+
+	return nil
+}
+
+// HandleStatInfo returns statistics of our node to the trusted requester.
+func (n *Node) HandleStatInfo(p *Peer, req StatInfoRequest) (*StatInfoResponse, error) {
+	if err := n.checkTrust(req.Trust, time.Now()); err != nil {
+		p.logger.Error(err)
+		p.Shutdown()
+		return nil, err
+	}
+
+	var connections, incoming uint64
+	for _, connected := range n.ps.connected() {
+		connections++
+
+		if connected.isIncoming {
+			incoming++
+		}
+	}
+
+	return &StatInfoResponse{
+		Version:                  n.Config.Version,
+		OSVersion:                runtime.GOOS + "/" + runtime.GOARCH,
+		ConnectionsCount:         connections,
+		IncomingConnectionsCount: incoming,
+		PayloadInfo: CoreStatInfo{
+			TxPoolSize:        uint64(n.TxPool.Size()),
+			BlockchainHeight:  uint64(n.Blockchain.Height()),
+			AlternativeBlocks: uint64(n.Blockchain.AlternativeBlocksCount()),
+			TopBlockHash:      n.Blockchain.TopBlock().Hash().String(),
+		},
+	}, nil
+}
+
+// HandleNetworkState returns our peer lists and connections to the trusted requester.
+func (n *Node) HandleNetworkState(p *Peer, req NetworkStateRequest) (*NetworkStateResponse, error) {
+	if err := n.checkTrust(req.Trust, time.Now()); err != nil {
+		p.logger.Error(err)
+		p.Shutdown()
+		return nil, err
+	}
+
+	n.ps.RLock()
+	res := NetworkStateResponse{
+		WhitePeers: n.ps.white.sorted(),
+		GreyPeers:  n.ps.grey.sorted(),
+		PeerID:     n.Config.PeerID,
+		LocalTime:  uint64(time.Now().Unix()),
+	}
+
+	for _, connected := range n.ps.connections {
+		res.Connections = append(res.Connections, ConnectionEntry{
+			Address:    connected.address,
+			ID:         connected.ID,
+			IsIncoming: connected.isIncoming,
+		})
+	}
+	n.ps.RUnlock()
+
+	return &res, nil
+}
+
+// StatClient queries statistics of the node with the requests signed by the trusted key.
+type StatClient struct {
+	protocol  *LevinProtocol
+	secretKey crypto.SecretKey
+}
+
+// DialStatClient connects to the node
+func DialStatClient(ctx context.Context, address string, secretKey crypto.SecretKey) (*StatClient, error) {
+	var dialer net.Dialer
+
+	conn, err := dialer.DialContext(ctx, "tcp4", address)
+	if err != nil {
+		return nil, err
+	}
+
+	return &StatClient{
+		protocol:  NewLevinProtocol(conn),
+		secretKey: secretKey,
+	}, nil
+}
+
+// PeerID requests ID of the node, proof of trust is made for this ID.
+func (c *StatClient) PeerID(ctx context.Context) (uint64, error) {
+	var res PeerIDResponse
+	if err := c.protocol.Invoke(ctx, CommandRequestPeerID, PeerIDRequest{}, &res); err != nil {
+		return 0, err
+	}
+
+	return res.PeerID, nil
+}
+
+// StatInfo requests statistics of the node
+func (c *StatClient) StatInfo(ctx context.Context) (*StatInfoResponse, error) {
+	tr, err := c.proofOfTrust(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var res StatInfoResponse
+	if err := c.protocol.Invoke(ctx, CommandRequestStatInfo, StatInfoRequest{Trust: *tr}, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+// NetworkState requests peer lists and connections of the node
+func (c *StatClient) NetworkState(ctx context.Context) (*NetworkStateResponse, error) {
+	tr, err := c.proofOfTrust(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var res NetworkStateResponse
+	if err := c.protocol.Invoke(ctx, CommandRequestNetworkState, NetworkStateRequest{Trust: *tr}, &res); err != nil {
+		return nil, err
+	}
+
+	return &res, nil
+}
+
+func (c *StatClient) Close() error {
+	return c.protocol.Close()
+}
+
+func (c *StatClient) proofOfTrust(ctx context.Context) (*ProofOfTrust, error) {
+	peerID, err := c.PeerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewProofOfTrust(peerID, time.Now(), &c.secretKey)
+}
@@ -0,0 +1,99 @@
+package p2p
+
+import (
+	"context"
+	"github.com/r3volut1oner/go-karbo/crypto"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNode_CheckTrust(t *testing.T) {
+	n := testNode(t)
+	secretKey, publicKey := testKeys(t)
+	now := time.Now()
+
+	tr, err := NewProofOfTrust(n.Config.PeerID, now, &secretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, ErrTrustNoKey, n.checkTrust(*tr, now))
+
+	n.Config.TrustedPublicKey = publicKey
+
+	wrongPeer, err := NewProofOfTrust(n.Config.PeerID+1, now, &secretKey)
+	assert.Nil(t, err)
+	assert.Equal(t, ErrTrustWrongPeerID, n.checkTrust(*wrongPeer, now))
+
+	otherKey, _ := testKeys(t)
+	wrongKey, err := NewProofOfTrust(n.Config.PeerID, now, &otherKey)
+	assert.Nil(t, err)
+	assert.Equal(t, ErrTrustSignature, n.checkTrust(*wrongKey, now))
+
+	assert.Equal(t, ErrTrustTimeDelta, n.checkTrust(*tr, now.Add(ProofOfTrustMaxTimeDelta+time.Minute)))
+
+	assert.Nil(t, n.checkTrust(*tr, now))
+
+	// Proof can't be used twice
+	assert.Equal(t, ErrTrustTimeReused, n.checkTrust(*tr, now))
+}
+
+func TestStatClient(t *testing.T) {
+	secretKey, publicKey := testKeys(t)
+
+	n := testNode(t)
+	n.Config.BindAddr = testFreeAddr(t)
+	n.Config.SeedNodes = []string{}
+	n.Config.TrustedPublicKey = publicKey
+	n.Config.Version = "test"
+	n.ps.white.Add(PeerEntry{ID: 2, Address: NetworkAddress{IP: 2, Port: 2}, LastSeen: 10})
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		assert.Nil(t, n.Run(ctx))
+	}()
+
+	testWaitListening(t, n.Config.BindAddr)
+
+	clientCtx, clientCancel := context.WithTimeout(ctx, time.Second*10)
+	defer clientCancel()
+
+	client, err := DialStatClient(clientCtx, n.Config.BindAddr, secretKey)
+	assert.Nil(t, err)
+
+	peerID, err := client.PeerID(clientCtx)
+	assert.Nil(t, err)
+	assert.Equal(t, n.Config.PeerID, peerID)
+
+	info, err := client.StatInfo(clientCtx)
+	assert.Nil(t, err)
+	assert.Equal(t, "test", info.Version)
+	assert.Equal(t, uint64(1), info.PayloadInfo.BlockchainHeight)
+	assert.Equal(t, n.Blockchain.TopBlock().Hash().String(), info.PayloadInfo.TopBlockHash)
+
+	// Proof time has seconds precision, next proof must be made later
+	time.Sleep(time.Second)
+
+	state, err := client.NetworkState(clientCtx)
+	assert.Nil(t, err)
+	assert.Equal(t, n.Config.PeerID, state.PeerID)
+	assert.Equal(t, []PeerEntry{{ID: 2, Address: NetworkAddress{IP: 2, Port: 2}, LastSeen: 10}}, state.WhitePeers)
+	assert.Empty(t, state.Connections)
+
+	assert.Nil(t, client.Close())
+
+	cancel()
+	wg.Wait()
+}
+
+func testKeys(t *testing.T) (crypto.SecretKey, *crypto.PublicKey) {
+	secretKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	publicKey, err := crypto.PublicFromSecret(&secretKey)
+	assert.Nil(t, err)
+
+	return secretKey, publicKey
+}
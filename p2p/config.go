@@ -1,5 +1,82 @@
 package p2p
 
+import (
+	"github.com/r3volut1oner/go-karbo/config"
+	"time"
+)
+
 const (
 	MaxBlockSynchronization = 128
+
+	// BlockDownloadTimeout is the max time for the peer to respond with the requested blocks
+	BlockDownloadTimeout = time.Second * 30
+
+	// BlockDownloadCheckInterval is the interval of checking the blocks requests timeout
+	BlockDownloadCheckInterval = time.Second
+
+	// MaxBlockIdsSynchronization is the max number of block hashes sent in the response chain entry
+	MaxBlockIdsSynchronization = 10000
+
+	// DefaultConnectionsCount is the default number of the outgoing connections the node keeps
+	DefaultConnectionsCount = 8
+
+	// DefaultIncomingConnectionsCount is the default max number of the incoming connections the node accepts
+	DefaultIncomingConnectionsCount = 64
+
+	// DefaultIncomingConnectionsPerIP is the default max number of the incoming connections accepted from the same IP
+	DefaultIncomingConnectionsPerIP = 5
+
+	// MisbehaviorBanScore is the misbehavior score of the IP after which it is banned
+	MisbehaviorBanScore = 100
+
+	// MisbehaviorInvalidBlock is the score added for the block failed validation
+	MisbehaviorInvalidBlock = 100
+
+	// MisbehaviorProtocolViolation is the score added for the wrong notification
+	MisbehaviorProtocolViolation = 25
+
+	// MisbehaviorForgiveTime is the time without offences after which the misbehavior score of the IP is reset
+	MisbehaviorForgiveTime = time.Hour * 6
+
+	// BanDuration is the time the misbehaving IP is not accepted and not dialed
+	BanDuration = time.Hour * 24
+
+	// ConnectionsMakerInterval is the interval of checking the outgoing connections count
+	ConnectionsMakerInterval = time.Second
+
+	// HandshakeTimeout is the max time for the handshake with the new outgoing peer
+	HandshakeTimeout = time.Second * 5
+
+	// TimedSyncInterval is the interval of sending the timed sync to the connected peers
+	TimedSyncInterval = time.Minute
+
+	// PeerIdleTimeout is the time without any packets from the peer after which the connection is dropped
+	PeerIdleTimeout = TimedSyncInterval * 3
+
+	// ProofOfTrustMaxTimeDelta is the max difference between the proof of trust time and the local time
+	ProofOfTrustMaxTimeDelta = time.Hour * 24
+
+	// HostFailBackoff is the delay before next connection to the failed host, it is doubled on each next fail
+	HostFailBackoff = time.Second * 10
+
+	// HostFailMaxBackoff is the max delay before next connection to the failed host
+	HostFailMaxBackoff = time.Hour
+
+	// WhitePeerListLimit is the max number of the peers we were connected to kept by the node
+	WhitePeerListLimit = 1000
+
+	// GreyPeerListLimit is the max number of the peers learned from the other nodes kept by the node
+	GreyPeerListLimit = 5000
+
+	// PeersInHandshake is the max number of the peers sent in the handshake and timed sync responses
+	PeersInHandshake = 250
+
+	// PeerStoreSaveInterval is the interval of saving the peer lists to the disk
+	PeerStoreSaveInterval = time.Minute * 5
+
+	// LiteBlocksPropagationVersion is the minimal peer version that receives new blocks as the lite blocks
+	LiteBlocksPropagationVersion = config.P2PVersion3
+
+	// TxPoolCleanupInterval is the interval of removing expired transactions from the memory pool
+	TxPoolCleanupInterval = time.Minute
 )
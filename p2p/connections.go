@@ -0,0 +1,212 @@
+package p2p
+
+import (
+	"context"
+	"net"
+	"strconv"
+	"time"
+)
+
+// runConnectionsMaker keeps the number of the outgoing connections at the configured level.
+// Connections are closed when the node is stopped.
+func (n *Node) runConnectionsMaker() {
+	defer n.wg.Done()
+
+	ticker := time.NewTicker(ConnectionsMakerInterval)
+	defer ticker.Stop()
+
+	for {
+		n.ps.removeExpired(time.Now())
+		n.makeConnections()
+
+		select {
+		case <-n.context.Done():
+			for _, p := range n.ps.connected() {
+				_ = p.protocol.Close()
+			}
+
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// makeConnections dials the known peers and the seed nodes until the outgoing connections count reaches the target.
+func (n *Node) makeConnections() {
+	needed := n.Config.MaxOutgoingConnections - n.ps.outgoingCount()
+	if needed <= 0 {
+		return
+	}
+
+	for _, address := range n.ps.candidates(n.seedAddresses(), time.Now()) {
+		if needed == 0 {
+			break
+		}
+
+		if !n.ps.startDialing(address) {
+			continue
+		}
+
+		needed--
+
+		n.wg.Add(1)
+		go n.connect(address)
+	}
+}
+
+// connect establishes the outgoing connection with the peer and handles it until disconnect.
+func (n *Node) connect(address NetworkAddress) {
+	defer n.wg.Done()
+
+	peer, err := n.handshake(address)
+	n.ps.stopDialing(address)
+
+	if err != nil {
+		n.logger.Debugf("[%s] failed to connect: %s", address.String(), err)
+		n.addHostFail(address)
+		return
+	}
+
+	defer peer.protocol.Close()
+
+	n.ps.resetHostFails(address)
+	n.logger.Debugf("[%s] handshake established", peer)
+
+	n.connectionHandler(peer)
+
+	if err := n.ps.removeConnected(peer); err != nil {
+		n.logger.Debugf("[%s] peer not removed from connections: %s", peer, err)
+	}
+
+	n.logger.Debugf("[%16x] sync closed", peer.ID)
+}
+
+// handshake dials the address and makes the handshake, connected peer is added to the white list.
+func (n *Node) handshake(address NetworkAddress) (*Peer, error) {
+	ctx, cancel := context.WithTimeout(n.context, HandshakeTimeout)
+	defer cancel()
+
+	peer, err := NewPeerFromTCPAddress(ctx, n, address.String())
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := peer.handshake(ctx, n); err != nil {
+		_ = peer.protocol.Close()
+		return nil, err
+	}
+
+	if peer.ID == n.Config.PeerID {
+		_ = peer.protocol.Close()
+		return nil, ErrHandshakeSelf
+	}
+
+	if err := n.ps.addConnected(peer); err != nil {
+		_ = peer.protocol.Close()
+		return nil, ErrHandshakeDuplicatePeer
+	}
+
+	return peer, nil
+}
+
+// runPeerScheduler sends timed sync to the peer periodically until the done is closed.
+// Connection is closed if nothing is received from the peer for too long.
+func (n *Node) runPeerScheduler(p *Peer, done <-chan struct{}) {
+	ticker := time.NewTicker(TimedSyncInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-done:
+			return
+		case <-n.context.Done():
+			return
+		case now := <-ticker.C:
+			if p.isIdle(now) {
+				n.logger.Debugf("[%s] peer is idle, dropping connection", p)
+				_ = p.protocol.Close()
+				return
+			}
+
+			if !p.isHandshaked() {
+				continue
+			}
+
+			if err := p.requestTimedSync(n); err != nil {
+				n.logger.Debugf("[%s] failed to send timed sync: %s", p, err)
+			}
+		}
+	}
+}
+
+// verifyPeer pings the incoming peer on the port it accepts connections on.
+// Peer is moved to the white list if it responds with its own ID.
+func (n *Node) verifyPeer(entry PeerEntry) {
+	defer n.wg.Done()
+
+	if entry.Address.Port == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(n.context, HandshakeTimeout)
+	defer cancel()
+
+	peer, err := NewPeerFromTCPAddress(ctx, n, entry.Address.String())
+	if err != nil {
+		n.logger.Debugf("[%s] failed to connect for ping: %s", entry.Address.String(), err)
+		return
+	}
+	defer peer.protocol.Close()
+
+	res, err := peer.ping(ctx)
+	if err != nil {
+		n.logger.Debugf("[%s] failed to ping: %s", entry.Address.String(), err)
+		return
+	}
+
+	if res.Status != PingStatusOK || res.PeerID != entry.ID {
+		n.logger.Debugf("[%s] wrong ping response, status: %s, peer id: %d", entry.Address.String(), res.Status, res.PeerID)
+		return
+	}
+
+	n.ps.toWhite(entry)
+}
+
+// learnPeers adds the peers from the remote peer list to the grey list
+func (n *Node) learnPeers(entries []PeerEntry) {
+	if added := n.ps.addEntries(n.Config.PeerID, entries); added > 0 {
+		n.logger.Debugf("learned %d new peers", added)
+	}
+}
+
+// seedAddresses resolves the configured seed nodes
+func (n *Node) seedAddresses() []NetworkAddress {
+	var addresses []NetworkAddress
+
+	for _, seed := range n.Config.SeedNodes {
+		addr, err := net.ResolveTCPAddr("tcp4", seed)
+		if err != nil {
+			n.logger.Warnf("failed to resolve seed node %s: %s", seed, err)
+			continue
+		}
+
+		addresses = append(addresses, NetworkAddressFromTCPAddr(addr))
+	}
+
+	return addresses
+}
+
+// listenPort returns port we are accepting the incoming connections on
+func (n *Node) listenPort() uint32 {
+	_, port, err := net.SplitHostPort(n.Config.BindAddr)
+	if err != nil {
+		return 0
+	}
+
+	p, err := strconv.ParseUint(port, 10, 32)
+	if err != nil {
+		return 0
+	}
+
+	return uint32(p)
+}
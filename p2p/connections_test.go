@@ -0,0 +1,211 @@
+package p2p
+
+import (
+	"context"
+	"fmt"
+	"github.com/r3volut1oner/go-karbo/encoding/binary"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestNode_Connections(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+	run := func(n *Node) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, n.Run(ctx))
+		}()
+	}
+
+	a, b, c := testNode(t), testNode(t), testNode(t)
+	for _, n := range []*Node{a, b, c} {
+		n.Config.BindAddr = testFreeAddr(t)
+		n.Config.SeedNodes = []string{a.Config.BindAddr}
+	}
+
+	run(a)
+	testWaitListening(t, a.Config.BindAddr)
+	run(b)
+
+	// Node connects to the seed, seed connection to itself is dropped
+	testWaitFor(t, func() bool {
+		return a.ps.outgoingCount() == 0 && len(a.ps.connected()) == 1 && b.ps.outgoingCount() == 1
+	})
+
+	// Seed pings the incoming peer and shares it with the others
+	testWaitFor(t, func() bool {
+		a.ps.RLock()
+		defer a.ps.RUnlock()
+
+		return a.ps.white.Has(b.Config.PeerID)
+	})
+
+	// Node learns about other peers from the seed and connects to them
+	run(c)
+
+	testWaitFor(t, func() bool {
+		return len(c.ps.connected()) == 2 && len(b.ps.connected()) == 2
+	})
+
+	for _, n := range []*Node{a, b, c} {
+		assert.Len(t, n.ps.connected(), 2)
+	}
+
+	assert.True(t, c.ps.white.Has(a.Config.PeerID))
+	assert.True(t, c.ps.white.Has(b.Config.PeerID))
+
+	cancel()
+	wg.Wait()
+}
+
+func TestNode_ConnectionsDuplicatePeer(t *testing.T) {
+	n := testNode(t)
+
+	p, remote := testPeer(t, n)
+	defer remote.Conn.Close()
+	p.ID = 1
+	assert.Nil(t, n.ps.addConnected(p))
+
+	duplicate, remote := testPeer(t, n)
+	defer remote.Conn.Close()
+
+	req := NewHandshakeRequest(testNode(t))
+	req.NodeData.PeerID = 1
+
+	assert.Equal(t, ErrHandshakeDuplicatePeer, n.HandleHandshake(duplicate, req))
+	assert.Equal(t, PeerStateShutdown, duplicate.state)
+	assert.Equal(t, p, n.ps.connections[1])
+
+	// Disconnect of the duplicate doesn't affect connected peer
+	assert.Equal(t, ErrPeerStoreNotConnected, n.ps.removeConnected(duplicate))
+	assert.Equal(t, p, n.ps.connections[1])
+
+	self, remote := testPeer(t, n)
+	defer remote.Conn.Close()
+
+	req.NodeData.PeerID = n.Config.PeerID
+	assert.Equal(t, ErrHandshakeSelf, n.HandleHandshake(self, req))
+}
+
+func TestNode_Misbehave(t *testing.T) {
+	n := testNode(t)
+
+	p, remote := testPeer(t, n)
+	defer remote.Conn.Close()
+
+	wrong := &LevinCommand{Command: NotificationRequestChainID, IsNotify: true, Payload: []byte{1, 2, 3}}
+	invalid, err := binary.Marshal(NotificationRequestChain{})
+	assert.Nil(t, err)
+
+	// Peer shut down by the handler is scored as well as the wrong payload
+	assert.NotNil(t, n.handleNotification(p, &LevinCommand{Command: NotificationRequestChainID, IsNotify: true, Payload: invalid}))
+	for i := 0; i < MisbehaviorBanScore/MisbehaviorProtocolViolation-2; i++ {
+		assert.NotNil(t, n.handleNotification(p, wrong))
+	}
+	assert.False(t, n.ps.isBanned(p.address.IP, time.Now()))
+
+	assert.NotNil(t, n.handleNotification(p, wrong))
+	assert.True(t, n.ps.isBanned(p.address.IP, time.Now()))
+
+	// Connection of the banned peer is closed
+	_, err = p.protocol.Conn.Write([]byte{1})
+	assert.NotNil(t, err)
+}
+
+func TestNode_TimedSync(t *testing.T) {
+	n := testNode(t)
+
+	p, remote := testPeer(t, n)
+	defer remote.Conn.Close()
+
+	go func() {
+		assert.Nil(t, p.requestTimedSync(n))
+	}()
+
+	cmd, err := remote.read()
+	assert.Nil(t, err)
+	assert.Equal(t, uint32(CommandTimedSync), cmd.Command)
+	assert.False(t, cmd.IsNotify)
+
+	var req TimedSyncRequest
+	assert.Nil(t, binary.Unmarshal(cmd.Payload, &req))
+	assert.Equal(t, n.Blockchain.Height(), req.PayloadData.CurrentHeight)
+
+	// Peers from the response are added to the grey list
+	p.state = PeerStateNormal
+	payload, err := binary.Marshal(TimedSyncResponse{
+		PayloadData: *newSyncData(n.Blockchain),
+		Peers:       []PeerEntry{{ID: 2, Address: NetworkAddress{IP: 2, Port: 2}, LastSeen: 10}},
+	})
+	assert.Nil(t, err)
+
+	assert.Nil(t, n.handleResponse(p, &LevinCommand{Command: CommandTimedSync, IsResponse: true, Payload: payload}))
+	assert.True(t, n.ps.grey.Has(2))
+
+	p.received(time.Now())
+	assert.False(t, p.isIdle(time.Now()))
+	assert.True(t, p.isIdle(time.Now().Add(PeerIdleTimeout+time.Second)))
+}
+
+func TestNode_HandlePing(t *testing.T) {
+	n := testNode(t)
+
+	p, remote := testPeer(t, n)
+	defer remote.Conn.Close()
+
+	payload, err := binary.Marshal(PingRequest{})
+	assert.Nil(t, err)
+
+	go func() {
+		assert.Nil(t, n.handleCommand(p, &LevinCommand{Command: CommandPing, Payload: payload}))
+	}()
+
+	cmd, err := remote.read()
+	assert.Nil(t, err)
+	assert.True(t, cmd.IsResponse)
+
+	var res PingResponse
+	assert.Nil(t, binary.Unmarshal(cmd.Payload, &res))
+	assert.Equal(t, PingResponse{Status: PingStatusOK, PeerID: n.Config.PeerID}, res)
+}
+
+// testFreeAddr returns loopback address with the port free for listening
+func testFreeAddr(t *testing.T) string {
+	listener, err := net.Listen("tcp4", "127.0.0.1:0")
+	assert.Nil(t, err)
+	defer listener.Close()
+
+	return fmt.Sprintf("127.0.0.1:%d", listener.Addr().(*net.TCPAddr).Port)
+}
+
+// testWaitListening waits until the node accepts connections on the address
+func testWaitListening(t *testing.T, addr string) {
+	testWaitFor(t, func() bool {
+		conn, err := net.Dial("tcp4", addr)
+		if err != nil {
+			return false
+		}
+
+		_ = conn.Close()
+		return true
+	})
+}
+
+// testWaitFor waits until the condition is met, test fails on timeout
+func testWaitFor(t *testing.T, condition func() bool) {
+	deadline := time.Now().Add(time.Second * 15)
+
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition is not met in time")
+		}
+
+		time.Sleep(time.Millisecond * 50)
+	}
+}
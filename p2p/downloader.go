@@ -0,0 +1,347 @@
+package p2p
+
+import (
+	"errors"
+	"github.com/r3volut1oner/go-karbo/crypto"
+	"github.com/r3volut1oner/go-karbo/cryptonote"
+	"sync"
+	"time"
+)
+
+var (
+	ErrDownloadNotRequested = errors.New("block is not requested from the peer")
+	ErrDownloadNotReceived  = errors.New("not all requested blocks are received from the peer")
+)
+
+// download is the block missing in our blockchain
+type download struct {
+	hash   crypto.Hash
+	height uint32
+
+	// source is the peer block is requested from, nil if block is not requested
+	source    *Peer
+	requested time.Time
+
+	// block with the transactions is set when block is received from the source
+	block        *cryptonote.Block
+	transactions [][]byte
+}
+
+// downloader schedules the blocks missing in our blockchain between the synchronizing peers.
+// Every peer has at most one request of the batch size blocks in flight.
+// Blocks are added to the blockchain in the order of the chain, blocks received out of order wait for the previous ones.
+//
+// This struct is safe for concurrent access.
+type downloader struct {
+	// blocks are the blocks being downloaded in the chain order
+	blocks []*download
+	hashes map[crypto.Hash]*download
+
+	// sources are the peers blocks are downloaded from with their blockchain height
+	sources map[*Peer]uint32
+
+	// stalled are the sources not responded in time, blocks are requested from them only when other sources are not busy
+	stalled map[*Peer]bool
+
+	// batchSize is the max number of the blocks requested from the peer at once
+	batchSize int
+
+	// processMutex makes sure received blocks are added to the blockchain by the single caller
+	processMutex sync.Mutex
+
+	sync.Mutex
+}
+
+func newDownloader() *downloader {
+	return &downloader{
+		hashes:    map[crypto.Hash]*download{},
+		sources:   map[*Peer]uint32{},
+		stalled:   map[*Peer]bool{},
+		batchSize: MaxBlockSynchronization,
+	}
+}
+
+// add registers the peer as the source of the blocks up to the remote height and queues the blocks not known yet.
+// Heights of the hashes start from the start height.
+func (d *downloader) add(p *Peer, remoteHeight, startHeight uint32, hashes []crypto.Hash) {
+	d.Lock()
+	defer d.Unlock()
+
+	d.sources[p] = remoteHeight
+
+	for i, hash := range hashes {
+		if _, ok := d.hashes[hash]; ok {
+			continue
+		}
+
+		b := &download{hash: hash, height: startHeight + uint32(i)}
+		d.blocks = append(d.blocks, b)
+		d.hashes[hash] = b
+	}
+}
+
+// schedule assigns not requested blocks to the sources without request in flight.
+// Source is assigned only the blocks below its height.
+// Stalled sources are used again only when no other source has a request in flight, so the download continues
+// when every source stalled once.
+func (d *downloader) schedule(now time.Time) map[*Peer][]crypto.Hash {
+	d.Lock()
+	defer d.Unlock()
+
+	busy := map[*Peer]bool{}
+	for _, b := range d.blocks {
+		if b.source != nil && b.block == nil {
+			busy[b.source] = true
+		}
+	}
+
+	requests := map[*Peer][]crypto.Hash{}
+	for p, height := range d.sources {
+		if busy[p] || d.stalled[p] {
+			continue
+		}
+
+		d.assign(p, height, now, requests)
+	}
+
+	for p := range d.sources {
+		if busy[p] || len(requests[p]) > 0 {
+			return requests
+		}
+	}
+
+	for p := range d.stalled {
+		if busy[p] {
+			continue
+		}
+
+		if d.assign(p, d.sources[p], now, requests) {
+			delete(d.stalled, p)
+		}
+	}
+
+	return requests
+}
+
+// assign adds not requested blocks below the height to the request of the peer, returns true if any block is assigned
+func (d *downloader) assign(p *Peer, height uint32, now time.Time, requests map[*Peer][]crypto.Hash) bool {
+	for _, b := range d.blocks {
+		if len(requests[p]) == d.batchSize {
+			break
+		}
+
+		if b.source != nil || b.height >= height {
+			continue
+		}
+
+		b.source = p
+		b.requested = now
+		requests[p] = append(requests[p], b.hash)
+	}
+
+	return len(requests[p]) > 0
+}
+
+// receive saves the blocks received from the peer, all blocks requested from the peer must be received.
+func (d *downloader) receive(p *Peer, blocks []*cryptonote.Block, transactions map[crypto.Hash][][]byte) error {
+	d.Lock()
+	defer d.Unlock()
+
+	for _, block := range blocks {
+		b, ok := d.hashes[*block.Hash()]
+		if !ok || b.source != p || b.block != nil {
+			return ErrDownloadNotRequested
+		}
+	}
+
+	for _, block := range blocks {
+		b := d.hashes[*block.Hash()]
+		b.block = block
+		b.transactions = transactions[b.hash]
+	}
+
+	for _, b := range d.blocks {
+		if b.source == p && b.block == nil {
+			return ErrDownloadNotReceived
+		}
+	}
+
+	return nil
+}
+
+// next removes and returns the first block if it is received
+func (d *downloader) next() *download {
+	d.Lock()
+	defer d.Unlock()
+
+	if len(d.blocks) == 0 || d.blocks[0].block == nil {
+		return nil
+	}
+
+	b := d.blocks[0]
+	d.blocks = d.blocks[1:]
+	delete(d.hashes, b.hash)
+
+	return b
+}
+
+// expire releases the blocks requested before the deadline, so they are requested from the other sources.
+// Peers not responded in time are used only when the other sources are not busy.
+func (d *downloader) expire(deadline time.Time) []*Peer {
+	d.Lock()
+	defer d.Unlock()
+
+	expired := map[*Peer]bool{}
+	for _, b := range d.blocks {
+		if b.source != nil && b.block == nil && b.requested.Before(deadline) {
+			expired[b.source] = true
+		}
+	}
+
+	var peers []*Peer
+	for p := range expired {
+		d.release(p)
+		d.stalled[p] = true
+		peers = append(peers, p)
+	}
+
+	return peers
+}
+
+// remove releases the blocks requested from the peer and stops using it as the source
+func (d *downloader) remove(p *Peer) {
+	d.Lock()
+	defer d.Unlock()
+
+	delete(d.sources, p)
+	delete(d.stalled, p)
+	d.release(p)
+}
+
+func (d *downloader) release(p *Peer) {
+	for _, b := range d.blocks {
+		if b.source == p && b.block == nil {
+			b.source = nil
+		}
+	}
+}
+
+// finish returns the sources if there is nothing to download, sources are not used anymore
+func (d *downloader) finish() []*Peer {
+	d.Lock()
+	defer d.Unlock()
+
+	if len(d.blocks) > 0 {
+		return nil
+	}
+
+	var peers []*Peer
+	for p := range d.sources {
+		peers = append(peers, p)
+	}
+
+	d.sources = map[*Peer]uint32{}
+	d.stalled = map[*Peer]bool{}
+
+	return peers
+}
+
+// reset drops all blocks and returns the sources, so the download can be started again
+func (d *downloader) reset() []*Peer {
+	d.Lock()
+	defer d.Unlock()
+
+	var peers []*Peer
+	for p := range d.sources {
+		peers = append(peers, p)
+	}
+
+	d.blocks = nil
+	d.hashes = map[crypto.Hash]*download{}
+	d.sources = map[*Peer]uint32{}
+	d.stalled = map[*Peer]bool{}
+
+	return peers
+}
+
+// downloadBlocks sends requests of the missing blocks to the sources without request in flight
+func (n *Node) downloadBlocks() {
+	for p, hashes := range n.downloader.schedule(time.Now()) {
+		p.logger.Debugf("request %d blocks", len(hashes))
+
+		if err := p.protocol.Notify(NotificationRequestGetObjectsID, NotificationRequestGetObjects{Blocks: hashes}); err != nil {
+			p.logger.Debugf("failed to request blocks: %s", err)
+			n.downloader.remove(p)
+		}
+	}
+}
+
+// processDownloaded adds received blocks to the blockchain in the chain order.
+// Download is started again if the block failed to be added, peer sent invalid block is dropped by processNewObjects.
+// When everything is downloaded the sources are asked for the next chain entry.
+func (n *Node) processDownloaded() {
+	n.downloader.processMutex.Lock()
+	defer n.downloader.processMutex.Unlock()
+
+	for b := n.downloader.next(); b != nil; b = n.downloader.next() {
+		// Block could be received from the other peer as the new block
+		if n.Blockchain.HaveBlock(&b.hash) {
+			continue
+		}
+
+		transactions := map[crypto.Hash][][]byte{b.hash: b.transactions}
+		err := n.processNewObjects(b.source, []*cryptonote.Block{b.block}, transactions)
+
+		// Block is in the blockchain already, only its source stopped the synchronization
+		if errors.Is(err, cryptonote.ErrCategoryAlreadyExists) {
+			continue
+		}
+
+		if err != nil {
+			b.source.logger.Errorf("failed to add downloaded block %d: %s", b.height, err)
+
+			// Blocks after the failed one can't be added, download is started again from our top block
+			for _, p := range n.downloader.reset() {
+				n.requestNextChain(p)
+			}
+
+			return
+		}
+	}
+
+	for _, p := range n.downloader.finish() {
+		n.requestNextChain(p)
+	}
+}
+
+// requestNextChain asks the source for the chain after our top block, peer switches to the normal state
+// after the chain entry without unknown blocks.
+func (n *Node) requestNextChain(p *Peer) {
+	if err := n.NotifyRequestChain(p); err != nil {
+		p.logger.Debugf("failed to request chain: %s", err)
+	}
+}
+
+// runDownloadScheduler re-assigns blocks not received in time to the other sources
+func (n *Node) runDownloadScheduler() {
+	defer n.wg.Done()
+
+	ticker := time.NewTicker(BlockDownloadCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.context.Done():
+			return
+		case now := <-ticker.C:
+			expired := n.downloader.expire(now.Add(-BlockDownloadTimeout))
+			for _, p := range expired {
+				p.logger.Debugf("blocks are not received in time, requesting from the other peers")
+			}
+
+			if len(expired) > 0 {
+				n.downloadBlocks()
+			}
+		}
+	}
+}
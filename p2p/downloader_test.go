@@ -0,0 +1,166 @@
+package p2p
+
+import (
+	"context"
+	"github.com/r3volut1oner/go-karbo/crypto"
+	"github.com/r3volut1oner/go-karbo/cryptonote"
+	"github.com/stretchr/testify/assert"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestDownloader(t *testing.T) {
+	n := testNode(t)
+
+	var blocks []*cryptonote.Block
+	var hashes []crypto.Hash
+	transactions := map[crypto.Hash][][]byte{}
+	for i := 0; i < 5; i++ {
+		block := testMineBlock(t, n.Blockchain)
+		blocks = append(blocks, block)
+		hashes = append(hashes, *block.Hash())
+		transactions[*block.Hash()] = nil
+	}
+
+	a, remoteA := testPeer(t, n)
+	defer remoteA.Conn.Close()
+	b, remoteB := testPeer(t, n)
+	defer remoteB.Conn.Close()
+
+	now := time.Now()
+
+	d := newDownloader()
+	d.batchSize = 2
+
+	d.add(a, 6, 1, hashes)
+	assert.Equal(t, map[*Peer][]crypto.Hash{a: hashes[0:2]}, d.schedule(now))
+
+	// Next blocks are requested from the other peer while the first request is in flight
+	d.add(b, 6, 1, hashes)
+	assert.Equal(t, map[*Peer][]crypto.Hash{b: hashes[2:4]}, d.schedule(now))
+	assert.Empty(t, d.schedule(now))
+
+	// Blocks received out of order wait for the previous ones
+	assert.Nil(t, d.receive(b, blocks[2:4], transactions))
+	assert.Nil(t, d.next())
+
+	// Blocks not received in time are requested from the other peer
+	assert.Equal(t, []*Peer{a}, d.expire(now.Add(time.Second)))
+	assert.Equal(t, map[*Peer][]crypto.Hash{b: hashes[0:2]}, d.schedule(now))
+
+	assert.Equal(t, ErrDownloadNotRequested, d.receive(a, blocks[0:2], transactions))
+	assert.Equal(t, ErrDownloadNotReceived, d.receive(b, blocks[0:1], transactions))
+	assert.Nil(t, d.receive(b, blocks[1:2], transactions))
+
+	for i := 0; i < 4; i++ {
+		next := d.next()
+		assert.Equal(t, hashes[i], next.hash)
+		assert.Equal(t, uint32(i+1), next.height)
+	}
+	assert.Nil(t, d.next())
+	assert.Empty(t, d.finish())
+
+	// Disconnected peer requests are released, stalled peer is used again when no other source is busy
+	assert.Equal(t, map[*Peer][]crypto.Hash{b: hashes[4:]}, d.schedule(now))
+	d.remove(b)
+	assert.Equal(t, map[*Peer][]crypto.Hash{a: hashes[4:]}, d.schedule(now))
+	assert.Empty(t, d.schedule(now))
+
+	assert.Equal(t, []*Peer{a}, d.expire(now.Add(time.Second)))
+	d.add(b, 6, 5, hashes[4:])
+	assert.Equal(t, map[*Peer][]crypto.Hash{b: hashes[4:]}, d.schedule(now))
+	assert.Nil(t, d.receive(b, blocks[4:], transactions))
+	assert.Equal(t, hashes[4], d.next().hash)
+
+	assert.ElementsMatch(t, []*Peer{a, b}, d.finish())
+}
+
+func TestDownloaderStalled(t *testing.T) {
+	n := testNode(t)
+
+	a, remoteA := testPeer(t, n)
+	defer remoteA.Conn.Close()
+	b, remoteB := testPeer(t, n)
+	defer remoteB.Conn.Close()
+
+	now := time.Now()
+	d := newDownloader()
+	hashes := []crypto.Hash{{1}, {2}}
+
+	// Single source is asked again after it stalled
+	d.add(a, 3, 1, hashes)
+	assert.Equal(t, map[*Peer][]crypto.Hash{a: hashes}, d.schedule(now))
+	assert.Equal(t, []*Peer{a}, d.expire(now.Add(time.Second)))
+	assert.Equal(t, map[*Peer][]crypto.Hash{a: hashes}, d.schedule(now))
+
+	// Stalled source waits while the other source is busy
+	d.add(b, 3, 1, hashes)
+	assert.Equal(t, []*Peer{a}, d.expire(now.Add(time.Second)))
+	assert.Equal(t, map[*Peer][]crypto.Hash{b: hashes}, d.schedule(now))
+	assert.Empty(t, d.schedule(now))
+
+	// Both sources stalled, blocks are requested again
+	assert.Equal(t, []*Peer{b}, d.expire(now.Add(time.Second)))
+	requests := d.schedule(now)
+	assert.Len(t, requests, 1)
+	for _, requested := range requests {
+		assert.Equal(t, hashes, requested)
+	}
+}
+
+func TestDownloaderHeight(t *testing.T) {
+	n := testNode(t)
+
+	p, remote := testPeer(t, n)
+	defer remote.Conn.Close()
+
+	d := newDownloader()
+	hashes := []crypto.Hash{{1}, {2}, {3}}
+
+	// Peer is asked only for the blocks it has
+	d.add(p, 3, 1, hashes)
+	assert.Equal(t, map[*Peer][]crypto.Hash{p: hashes[0:2]}, d.schedule(time.Now()))
+}
+
+func TestNode_DownloadBlocks(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+
+	var wg sync.WaitGroup
+	run := func(n *Node) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, n.Run(ctx))
+		}()
+	}
+
+	a, b, c := testNode(t), testNode(t), testNode(t)
+	for i := 0; i < MaxBlockSynchronization*3; i++ {
+		block := testMineBlock(t, a.Blockchain)
+		assert.Nil(t, b.Blockchain.AddBlock(block, nil))
+	}
+
+	for _, n := range []*Node{a, b, c} {
+		n.Config.BindAddr = testFreeAddr(t)
+	}
+
+	a.Config.SeedNodes = []string{}
+	b.Config.SeedNodes = []string{a.Config.BindAddr}
+	c.Config.SeedNodes = []string{a.Config.BindAddr, b.Config.BindAddr}
+
+	run(a)
+	run(b)
+	testWaitListening(t, a.Config.BindAddr)
+	testWaitListening(t, b.Config.BindAddr)
+	run(c)
+
+	testWaitFor(t, func() bool {
+		return c.Blockchain.Height() == a.Blockchain.Height()
+	})
+
+	assert.Equal(t, a.Blockchain.TopBlock().Hash(), c.Blockchain.TopBlock().Hash())
+
+	cancel()
+	wg.Wait()
+}
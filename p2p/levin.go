@@ -1,12 +1,14 @@
 package p2p
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
 	p2pbinary "github.com/r3volut1oner/go-karbo/encoding/binary"
 	"io"
 	"net"
+	"sync"
 )
 
 const (
@@ -31,16 +33,59 @@ const (
 	LevinProtocolVersion1 uint32 = 1
 
 	LevinHeadSize = 33
+
+	// LevinWriteQueueSize is the max number of the packets waiting to be written to the connection
+	LevinWriteQueueSize = 128
+
+	// LevinReadQueueSize is the max number of the received packets waiting to be handled
+	LevinReadQueueSize = 128
+)
+
+// LevinError is the negative return code of the response
+type LevinError int32
+
+// Return codes of the levin responses
+// src/P2p/LevinProtocol.h
+const (
+	LevinOK                               int32      = 0
+	LevinErrorConnection                  LevinError = -1
+	LevinErrorConnectionNotFound          LevinError = -2
+	LevinErrorConnectionDestroyed         LevinError = -3
+	LevinErrorConnectionTimedOut          LevinError = -4
+	LevinErrorConnectionNoDuplexProtocol  LevinError = -5
+	LevinErrorConnectionHandlerNotDefined LevinError = -6
+	LevinErrorFormat                      LevinError = -7
+)
+
+var (
+	ErrLevinClosed   = errors.New("levin connection closed")
+	ErrLevinNoReader = errors.New("levin connection is not read by the protocol")
 )
 
 type LevinProtocol struct {
 	Conn net.Conn
+
+	// queue contains packets waiting to be written by the writer goroutine.
+	// Packets are written directly to the connection if queue is nil.
+	queue chan []byte
+
+	// commands contains received packets except the responses to the invocations
+	commands chan *LevinCommand
+
+	// pending contains invocations waiting for the response by the command ID, first invoked is first responded
+	pending      map[uint32][]chan *LevinCommand
+	pendingMutex sync.Mutex
+
+	// closed is closed with the connection and stops the writer goroutine
+	closed    chan struct{}
+	closeOnce sync.Once
 }
 
 type LevinCommand struct {
 	Command    uint32
 	IsNotify   bool
 	IsResponse bool
+	ReturnCode int32
 	Payload    []byte
 }
 
@@ -54,31 +99,176 @@ type bucketHead struct {
 	ProtocolVersion  uint32
 }
 
-// Invoke sends command and waits for response
-func (p *LevinProtocol) Invoke(command uint32, req, res interface{}) error {
-	reqBytes, err := p2pbinary.Marshal(req)
-	if err != nil {
-		return err
+// NewLevinProtocol returns protocol reading and writing packets in the separate goroutines.
+// Packets are never written concurrently and writing doesn't block the caller.
+// Responses are passed to the invocations waiting for them, other packets are available in the Commands.
+func NewLevinProtocol(conn net.Conn) *LevinProtocol {
+	p := &LevinProtocol{
+		Conn:     conn,
+		queue:    make(chan []byte, LevinWriteQueueSize),
+		commands: make(chan *LevinCommand, LevinReadQueueSize),
+		pending:  map[uint32][]chan *LevinCommand{},
+		closed:   make(chan struct{}),
 	}
 
-	if _, err := p.write(command, reqBytes, true, LevinPacketRequest, 0); err != nil {
-		return err
+	go p.runWriter()
+	go p.runReader()
+
+	return p
+}
+
+func (e LevinError) Error() string {
+	switch e {
+	case LevinErrorConnection:
+		return "levin connection error"
+	case LevinErrorConnectionNotFound:
+		return "levin connection not found"
+	case LevinErrorConnectionDestroyed:
+		return "levin connection destroyed"
+	case LevinErrorConnectionTimedOut:
+		return "levin connection timed out"
+	case LevinErrorConnectionNoDuplexProtocol:
+		return "levin connection has no duplex protocol"
+	case LevinErrorConnectionHandlerNotDefined:
+		return "levin command handler not defined"
+	case LevinErrorFormat:
+		return "levin wrong format"
 	}
 
-	commandRsp, err := p.read()
-	if err != nil {
+	return fmt.Sprintf("levin error code %d", int32(e))
+}
+
+// Commands returns channel of the received packets except the responses to the invocations.
+// Channel is closed when the connection is closed.
+func (p *LevinProtocol) Commands() <-chan *LevinCommand {
+	return p.commands
+}
+
+// Close closes the connection and stops the writer, packets waiting in the queue are dropped.
+func (p *LevinProtocol) Close() error {
+	p.closeOnce.Do(func() {
+		if p.closed != nil {
+			close(p.closed)
+		}
+	})
+
+	return p.Conn.Close()
+}
+
+// runWriter writes queued packets until the protocol is closed, connection is closed on the write error.
+func (p *LevinProtocol) runWriter() {
+	for {
+		select {
+		case message := <-p.queue:
+			if _, err := p.Conn.Write(message); err != nil {
+				_ = p.Close()
+				return
+			}
+		case <-p.closed:
+			return
+		}
+	}
+}
+
+// runReader reads packets until the read error, connection is closed on the error.
+// Reader never waits for the commands handler, so responses to the invocations are delivered while the handler is busy.
+// Connection is closed when the handler falls behind by more than LevinReadQueueSize commands.
+func (p *LevinProtocol) runReader() {
+	defer close(p.commands)
+
+	for {
+		cmd, err := p.read()
+		if err != nil {
+			_ = p.Close()
+			return
+		}
+
+		if cmd.IsResponse && p.respond(cmd) {
+			continue
+		}
+
+		select {
+		case p.commands <- cmd:
+		default:
+			_ = p.Close()
+			return
+		}
+	}
+}
+
+// Invoke sends command and waits for response until the context is done.
+// Negative return code of the response is returned as LevinError.
+func (p *LevinProtocol) Invoke(ctx context.Context, command uint32, req, res interface{}) error {
+	if p.pending == nil {
+		return ErrLevinNoReader
+	}
+
+	response := make(chan *LevinCommand, 1)
+	p.addPending(command, response)
+
+	if err := p.Request(command, req); err != nil {
+		p.removePending(command, response)
 		return err
 	}
 
-	if command != commandRsp.Command {
-		return errors.New(fmt.Sprintf("wrong command response code: %v", commandRsp.Command))
+	select {
+	case cmd := <-response:
+		if cmd.ReturnCode < LevinOK {
+			return LevinError(cmd.ReturnCode)
+		}
+
+		return p2pbinary.Unmarshal(cmd.Payload, res)
+	case <-ctx.Done():
+		p.removePending(command, response)
+		return ctx.Err()
+	case <-p.closed:
+		return ErrLevinClosed
 	}
+}
+
+func (p *LevinProtocol) addPending(command uint32, response chan *LevinCommand) {
+	p.pendingMutex.Lock()
+	p.pending[command] = append(p.pending[command], response)
+	p.pendingMutex.Unlock()
+}
+
+func (p *LevinProtocol) removePending(command uint32, response chan *LevinCommand) {
+	p.pendingMutex.Lock()
+	defer p.pendingMutex.Unlock()
+
+	pending := p.pending[command]
+	for i := range pending {
+		if pending[i] == response {
+			p.pending[command] = append(pending[:i], pending[i+1:]...)
+			break
+		}
+	}
+}
+
+// respond passes response to the first invocation waiting for it, returns false if nobody waits for the response
+func (p *LevinProtocol) respond(cmd *LevinCommand) bool {
+	p.pendingMutex.Lock()
+	defer p.pendingMutex.Unlock()
 
-	if !commandRsp.IsResponse {
-		return errors.New("not response returned")
+	pending := p.pending[cmd.Command]
+	if len(pending) == 0 {
+		return false
 	}
 
-	if err := p2pbinary.Unmarshal(commandRsp.Payload, res); err != nil {
+	pending[0] <- cmd
+	p.pending[cmd.Command] = pending[1:]
+
+	return true
+}
+
+// Request sends command without waiting for response, response is read with the other packets
+func (p *LevinProtocol) Request(command uint32, req interface{}) error {
+	reqBytes, err := p2pbinary.Marshal(req)
+	if err != nil {
+		return err
+	}
+
+	if _, err := p.write(command, reqBytes, true, LevinPacketRequest, 0); err != nil {
 		return err
 	}
 
@@ -137,6 +327,7 @@ func (p *LevinProtocol) read() (*LevinCommand, error) {
 
 	return &LevinCommand{
 		Command:    head.Command,
+		ReturnCode: head.ReturnCode,
 		Payload:    payload,
 		IsNotify:   !head.HaveToReturnData,
 		IsResponse: (head.Flags & LevinPacketResponse) == LevinPacketResponse,
@@ -167,7 +358,22 @@ func (p *LevinProtocol) write(
 	copy(message[0:33], headBytes[:])
 	copy(message[33:], payload)
 
-	return p.Conn.Write(message)
+	if p.queue == nil {
+		return p.Conn.Write(message)
+	}
+
+	select {
+	case <-p.closed:
+		return 0, ErrLevinClosed
+	default:
+	}
+
+	select {
+	case p.queue <- message:
+		return len(message), nil
+	case <-p.closed:
+		return 0, ErrLevinClosed
+	}
 }
 
 func (head *bucketHead) encode() [LevinHeadSize]byte {
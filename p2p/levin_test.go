@@ -0,0 +1,126 @@
+package p2p
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestLevinProtocol_Writer(t *testing.T) {
+	local, remote := net.Pipe()
+	defer remote.Close()
+
+	protocol := NewLevinProtocol(local)
+	reader := &LevinProtocol{Conn: remote}
+
+	// Packets are queued without waiting for the remote side
+	for i := uint32(0); i < 3; i++ {
+		assert.Nil(t, protocol.Notify(NotificationTxPoolID+i, NotificationTxPool{}))
+	}
+
+	for i := uint32(0); i < 3; i++ {
+		cmd, err := reader.read()
+		assert.Nil(t, err)
+		assert.Equal(t, NotificationTxPoolID+i, cmd.Command)
+	}
+
+	assert.Nil(t, protocol.Close())
+	assert.Nil(t, protocol.Close())
+	assert.Equal(t, ErrLevinClosed, protocol.Notify(NotificationTxPoolID, NotificationTxPool{}))
+}
+
+func TestLevinProtocol_Invoke(t *testing.T) {
+	local, remote := net.Pipe()
+
+	protocol := NewLevinProtocol(local)
+	defer protocol.Close()
+
+	server := NewLevinProtocol(remote)
+	defer server.Close()
+
+	go func() {
+		for cmd := range server.Commands() {
+			switch cmd.Command {
+			case CommandPing:
+				// Notification sent before the response is not taken as the response
+				assert.Nil(t, server.Notify(NotificationTxPoolID, NotificationTxPool{}))
+				assert.Nil(t, server.Reply(cmd.Command, PingResponse{Status: PingStatusOK, PeerID: 1}, 1))
+			case CommandRequestPeerID:
+				assert.Nil(t, server.Reply(cmd.Command, struct{}{}, int32(LevinErrorFormat)))
+			}
+		}
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	var ping PingResponse
+	assert.Nil(t, protocol.Invoke(ctx, CommandPing, PingRequest{}, &ping))
+	assert.Equal(t, PingResponse{Status: PingStatusOK, PeerID: 1}, ping)
+
+	cmd := <-protocol.Commands()
+	assert.Equal(t, uint32(NotificationTxPoolID), cmd.Command)
+
+	var peerID PeerIDResponse
+	err := protocol.Invoke(ctx, CommandRequestPeerID, PeerIDRequest{}, &peerID)
+	assert.True(t, errors.Is(err, LevinErrorFormat))
+
+	// Remote side doesn't respond to the command
+	timeoutCtx, timeoutCancel := context.WithTimeout(ctx, time.Millisecond*100)
+	defer timeoutCancel()
+
+	var stat StatInfoResponse
+	assert.Equal(t, context.DeadlineExceeded, protocol.Invoke(timeoutCtx, CommandRequestStatInfo, StatInfoRequest{}, &stat))
+	assert.Empty(t, protocol.pending[CommandRequestStatInfo])
+
+	assert.Nil(t, server.Close())
+	_, ok := <-protocol.Commands()
+	assert.False(t, ok)
+	assert.Equal(t, ErrLevinClosed, protocol.Invoke(ctx, CommandPing, PingRequest{}, &ping))
+
+	assert.Equal(t, ErrLevinNoReader, (&LevinProtocol{Conn: local}).Invoke(ctx, CommandPing, PingRequest{}, &ping))
+}
+
+func TestLevinProtocol_ReadQueue(t *testing.T) {
+	local, remote := net.Pipe()
+
+	protocol := NewLevinProtocol(local)
+	defer protocol.Close()
+
+	server := NewLevinProtocol(remote)
+	defer server.Close()
+
+	go func() {
+		cmd := <-server.Commands()
+
+		// Commands are not handled while the invocation waits for the response
+		for i := 0; i < LevinReadQueueSize; i++ {
+			assert.Nil(t, server.Notify(NotificationTxPoolID, NotificationTxPool{}))
+		}
+		assert.Nil(t, server.Reply(cmd.Command, PingResponse{Status: PingStatusOK, PeerID: 1}, 1))
+
+		// Queue is full, connection is closed
+		assert.Nil(t, server.Notify(NotificationTxPoolID, NotificationTxPool{}))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second*5)
+	defer cancel()
+
+	var ping PingResponse
+	assert.Nil(t, protocol.Invoke(ctx, CommandPing, PingRequest{}, &ping))
+
+	select {
+	case <-protocol.closed:
+	case <-ctx.Done():
+		t.Fatal("connection is not closed")
+	}
+
+	count := 0
+	for range protocol.Commands() {
+		count++
+	}
+	assert.Equal(t, LevinReadQueueSize, count)
+}
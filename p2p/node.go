@@ -7,8 +7,8 @@ import (
 	"github.com/r3volut1oner/go-karbo/config"
 	"github.com/r3volut1oner/go-karbo/crypto"
 	"github.com/r3volut1oner/go-karbo/cryptonote"
+	"github.com/r3volut1oner/go-karbo/encoding/binary"
 	"go.uber.org/zap"
-	"io"
 	"math"
 	"math/rand"
 	"net"
@@ -22,36 +22,68 @@ type HostConfig struct {
 	BindAddr string
 	Network  *config.Network
 
+	// SeedNodes used for the peers discovery, network seed nodes are used by default
+	SeedNodes []string
+
+	// MaxOutgoingConnections is the number of the outgoing connections the node keeps
+	MaxOutgoingConnections int
+
+	// MaxIncomingConnections is the max number of the incoming connections the node accepts
+	MaxIncomingConnections int
+
+	// MaxIncomingConnectionsPerIP is the max number of the incoming connections accepted from the same IP
+	MaxIncomingConnectionsPerIP int
+
+	// PeerStorePath is the file the peer lists are saved to, lists are not saved if path is empty
+	PeerStorePath string
+
+	// TrustedPublicKey verifies the statistics requests, requests are rejected if key is not set
+	TrustedPublicKey *crypto.PublicKey
+
+	// Version of the node reported in the statistics
+	Version string
+
 	ListenConfig *net.ListenConfig
 }
 
 type Node struct {
 	Config     HostConfig
 	Blockchain *cryptonote.BlockChain
+	TxPool     *cryptonote.TxPool
 
 	dialer *net.Dialer
 	logger *zap.SugaredLogger
 	wg     *sync.WaitGroup
 	ps     *peerStore
 
+	// downloader schedules blocks missing in our blockchain between the synchronizing peers
+	downloader *downloader
+
 	context context.Context
 
 	listener *net.TCPListener
+
+	// lastTrustTime is the time of the last statistics request signed by the trusted key
+	lastTrustTime uint64
+	trustMutex    *sync.Mutex
 }
 
 // NewNode creates instance of the node
-func NewNode(core *cryptonote.BlockChain, cfg HostConfig, logger *zap.Logger) Node {
+func NewNode(core *cryptonote.BlockChain, pool *cryptonote.TxPool, cfg HostConfig, logger *zap.Logger) Node {
 	var wg sync.WaitGroup
 
 	h := Node{
 		Config:     cfg,
 		Blockchain: core,
+		TxPool:     pool,
 		logger:     logger.Sugar(),
 	}
 
 	h.defaults()
 	h.ps = NewPeerStore()
+	h.downloader = newDownloader()
 	h.wg = &wg
+	h.trustMutex = &sync.Mutex{}
 
 	return h
 }
@@ -76,13 +108,26 @@ func (n *Node) Run(ctx context.Context) error {
 
 	n.context = ctx
 
+	if n.Config.PeerStorePath != "" {
+		if err := n.ps.Load(n.Config.PeerStorePath); err != nil {
+			n.logger.Warnf("failed to load peer lists: %s", err)
+		}
+
+		n.wg.Add(1)
+		go n.runPeerStoreSaver()
+	}
+
 	n.wg.Add(1)
 	go n.runListener()
 
-	// TODO: Disabled only for check the listener
-	//for _, seedAddr := range n.Config.Network.SeedNodes {
-	//	go n.syncWithAddr(seedAddr)
-	//}
+	n.wg.Add(1)
+	go n.runTxPoolCleaner()
+
+	n.wg.Add(1)
+	go n.runConnectionsMaker()
+
+	n.wg.Add(1)
+	go n.runDownloadScheduler()
 
 	n.wg.Wait()
 	return nil
@@ -100,15 +145,25 @@ func (n *Node) runListener() {
 			n.wg.Done()
 			return
 		default:
-			_ = n.listener.SetDeadline(time.Now().Add(time.Second * 5))
+			_ = n.listener.SetDeadline(time.Now().Add(time.Second))
 
 			conn, err := n.listener.AcceptTCP()
 			if err != nil {
-				if opErr, ok := err.(*net.OpError); ok && opErr.Timeout() {
-					time.Sleep(time.Second)
-					continue
+				if opErr, ok := err.(*net.OpError); !ok || !opErr.Timeout() {
+					n.logger.Errorf("failed to accept connection: %s", err)
 				}
-				n.logger.Errorf("failed to accept connection: %s", err)
+
+				continue
+			}
+
+			address := NetworkAddressFromTCPAddr(conn.RemoteAddr().(*net.TCPAddr))
+			err = n.ps.acceptIncoming(
+				address.IP, n.Config.MaxIncomingConnections, n.Config.MaxIncomingConnectionsPerIP, time.Now(),
+			)
+			if err != nil {
+				n.logger.Debugf("[%s] incoming connection rejected: %s", address.String(), err)
+				_ = conn.Close()
+				continue
 			}
 
 			go n.handleIncomingConnection(conn)
@@ -116,30 +171,88 @@ func (n *Node) runListener() {
 	}
 }
 
+// runTxPoolCleaner removes expired transactions from the memory pool periodically.
+func (n *Node) runTxPoolCleaner() {
+	defer n.wg.Done()
+
+	ticker := time.NewTicker(TxPoolCleanupInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.context.Done():
+			return
+		case <-ticker.C:
+			if removed := n.TxPool.RemoveExpired(); len(removed) > 0 {
+				n.logger.Debugf("removed %d expired transactions from pool", len(removed))
+			}
+		}
+	}
+}
+
+// runPeerStoreSaver saves the peer lists periodically and on the node stop.
+func (n *Node) runPeerStoreSaver() {
+	defer n.wg.Done()
+
+	ticker := time.NewTicker(PeerStoreSaveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-n.context.Done():
+			n.savePeerStore()
+			return
+		case <-ticker.C:
+			n.savePeerStore()
+		}
+	}
+}
+
+func (n *Node) savePeerStore() {
+	if err := n.ps.Save(n.Config.PeerStorePath); err != nil {
+		n.logger.Errorf("failed to save peer lists: %s", err)
+	}
+}
+
 func (n *Node) handleIncomingConnection(conn *net.TCPConn) {
 	// TODO: Enabling handling incoming connections
 	//return
 
 	peer := NewPeerFromIncomingConnection(n, conn)
 
-	//// TODO: Add peer to peerstore. Make sure it is not exists.
-	//
 	n.wg.Add(1)
 	defer n.wg.Done()
+	defer n.ps.releaseIncoming(peer.address.IP)
+	defer peer.protocol.Close()
 
+	// Peer is added to the connections after the handshake
 	n.connectionHandler(peer)
 
-	if err := n.ps.toGrey(peer); err != nil {
-		n.logger.Warnf("peer remove failed: %s", err)
+	if err := n.ps.removeConnected(peer); err != nil {
+		n.logger.Debugf("[%s] peer not removed from connections: %s", peer, err)
 	}
 
 	n.logger.Debugf("[%16x] sync closed", peer.ID)
 }
 
+// connectionHandler handles commands received from the peer until the connection is closed or peer is shut down.
+// Responses to the invocations are not passed to the handler.
 func (n *Node) connectionHandler(p *Peer) {
+	done := make(chan struct{})
+	defer close(done)
+
+	// Blocks requested from the peer are requested from the other sources after disconnect
+	defer func() {
+		n.downloader.remove(p)
+		n.downloadBlocks()
+	}()
+
+	p.received(time.Now())
+	go n.runPeerScheduler(p, done)
+
 	for {
-		// Peer state changes asynchronously after handling some commands.
-		// Here we are taking care of handle different peer statuses.
+		// Peer state changes after handling some commands.
+		// Here we are taking care of handle different peer statuses before the next command.
 		switch p.state {
 		// Our node must be synchronized with the peer
 		case PeerStateSyncRequired:
@@ -149,6 +262,14 @@ func (n *Node) connectionHandler(p *Peer) {
 				n.logger.Errorf("failed to write request chain: %s", err)
 			}
 
+		// Connection is synchronized, request transactions missing in our pool
+		case PeerStatePoolSyncRequired:
+			p.state = PeerStateNormal
+
+			if err := n.NotifyTxPool(p); err != nil {
+				n.logger.Errorf("failed to write tx pool: %s", err)
+			}
+
 		// Peer shutdown.
 		// Stop listening for commands.
 		case PeerStateShutdown:
@@ -156,27 +277,31 @@ func (n *Node) connectionHandler(p *Peer) {
 			return
 		}
 
-		// Wait 1 second before read next command from the connection
+		// Wait for the next command from the connection
 		// Stop listening for the commands by return from the function
+		var cmd *LevinCommand
 		select {
-		case <-time.After(time.Second):
+		case received, ok := <-p.protocol.Commands():
+			// Connection is closed, peer is removed from the connections by the caller
+			if !ok {
+				n.logger.Debugf("[%s] connection closed", p)
+				return
+			}
+
+			cmd = received
 		case <-n.context.Done():
 			return
 		}
 
-		// Read a next command from the connection
-		cmd, err := p.protocol.read()
+		p.received(time.Now())
 
-		// No command received continue to next loop cycle
-		if err == io.EOF {
-			continue
-		}
+		// Responses to the requests sent by our node
+		if cmd.IsResponse {
+			if err := n.handleResponse(p, cmd); err != nil {
+				n.logger.Errorf("failed to handle response %d: %s", cmd.Command, err)
+			}
 
-		// On any error we move the peer to the grey list
-		if err != nil {
-			n.logger.Errorf("error on read command: %s", err)
-			_ = n.ps.toGrey(p)
-			break
+			continue
 		}
 
 		// There is special command type as "notification" we handle them with a separate method.
@@ -217,98 +342,59 @@ func (n *Node) handleNotification(p *Peer, cmd *LevinCommand) error {
 
 	nt, err := parseNotification(cmd)
 	if err != nil {
+		n.misbehave(p, MisbehaviorProtocolViolation)
 		return err
 	}
 
-	switch nt.(type) {
-	case NotificationTxPool: // 2008
-		notification := nt.(NotificationTxPool)
-
-		p.logger.Debugf("notification tx pool, size: %d", len(notification.Transactions))
-	case NotificationRequestChain:
-		notification := nt.(NotificationRequestChain)
-
-		// Verify more than 0 requested blocks
-		if len(notification.Blocks) == 0 {
-			p.Shutdown()
-			return errors.New(fmt.Sprintf("[%s] request chain with 0 blocks", p))
+	// Peers are shut down by the handlers for the wrong data
+	if err := n.dispatchNotification(p, nt); err != nil {
+		if p.state == PeerStateShutdown {
+			n.misbehave(p, MisbehaviorProtocolViolation)
 		}
 
-		genesisBlock, err := n.Blockchain.GenesisBlock()
-		if err != nil {
-			return fmt.Errorf("[%s] unexpected error: %w", p, err)
-		}
-
-		// Make sure genesis blocks belongs to same network
-		if *genesisBlock.Hash() != notification.Blocks[len(notification.Blocks)-1] {
-			p.Shutdown()
-			return errors.New(fmt.Sprintf("[%s] request chain genesis block not match", p))
-		}
-
-		// Get max index of this node blockchain
-		//topIndex, err := p.node.Blockchain.TopIndex()
-		//if err != nil {
-		//	return fmt.Errorf("[%s] unexpected error: %w", p, err)
-		//}
-
-		// TODO: Build response chain entry and response to requested peer
-		//responseChainEntry := &NotificationResponseChainEntry{
-		//	TotalHeight: topIndex + 1,
-		//}
+		return err
+	}
 
-		n.logger.Debugf("[%s] request chain %d blocks.", p, len(notification.Blocks))
-	case NotificationResponseChainEntry: // 2007
-		notification := nt.(NotificationResponseChainEntry)
+	return nil
+}
 
-		p.logger.Debugf(
-			"notification response chain entry, start: %d, blocks: %d, total: %d",
-			notification.StartHeight, len(notification.BlocksHashes), notification.TotalHeight,
-		)
+// dispatchNotification calls handler of the notification type
+func (n *Node) dispatchNotification(p *Peer, nt interface{}) error {
+	switch nt.(type) {
+	case NotificationNewBlock: // 2001
+		notification := nt.(NotificationNewBlock)
 
-		if len(notification.BlocksHashes) == 0 {
-			p.Shutdown()
-			// TODO: Create new error instance
-			return errors.New(fmt.Sprintf("[%s] received empty blocks in response chain enrty", p))
-		}
+		return n.HandleNewBlock(p, notification)
+	case NotificationNewTransactions: // 2002
+		notification := nt.(NotificationNewTransactions)
 
-		firstHash := notification.BlocksHashes[0]
-		hasFirstBlock := n.Blockchain.HaveBlock(&firstHash)
+		return n.HandleNewTransactions(p, notification)
+	case NotificationTxPool: // 2008
+		notification := nt.(NotificationTxPool)
 
-		if !hasFirstBlock {
-			p.Shutdown()
-			// TODO: Create new error instance
-			return errors.New(fmt.Sprintf("[%s] hash %s missing in our blockchain", p, firstHash.String()))
-		}
+		p.logger.Debugf("notification tx pool, size: %d", len(notification.Transactions))
 
-		p.remoteHeight = notification.TotalHeight
-		p.lastResponseHeight = notification.StartHeight + uint32(len(notification.BlocksHashes)-1)
+		return n.HandleTxPool(p, notification)
+	case NotificationNewLiteBlock: // 2009
+		notification := nt.(NotificationNewLiteBlock)
 
-		if p.lastResponseHeight > p.remoteHeight {
-			// TODO: Create new error instance
-			p.Shutdown()
-			return errors.New(
-				fmt.Sprintf(
-					"[%s] sent wrong response chain entry, with TotalHeight = %d, StartHeight = %d, blocks = %d", p,
-					notification.StartHeight,
-					notification.TotalHeight,
-					len(notification.BlocksHashes),
-				),
-			)
-		}
+		return n.HandleNewLiteBlock(p, notification, nil)
+	case NotificationMissingTxs: // 2010
+		notification := nt.(NotificationMissingTxs)
 
-		allBlockKnown := true
-		for _, bh := range notification.BlocksHashes {
-			hasBlock := n.Blockchain.HaveBlock(&bh)
+		return n.HandleMissingTxs(p, notification)
+	case NotificationRequestGetObjects: // 2003
+		notification := nt.(NotificationRequestGetObjects)
 
-			if allBlockKnown && hasBlock {
-				continue
-			}
+		return n.HandleRequestGetObjects(p, notification)
+	case NotificationRequestChain: // 2006
+		notification := nt.(NotificationRequestChain)
 
-			allBlockKnown = false
-			p.neededBlocks = append(p.neededBlocks, bh)
-		}
+		return n.HandleRequestChain(p, notification)
+	case NotificationResponseChainEntry: // 2007
+		notification := nt.(NotificationResponseChainEntry)
 
-		return p.requestMissingBlocks(n, false)
+		return n.HandleResponseChainEntry(p, notification)
 	case NotificationResponseGetObjects: // 2004
 		notification := nt.(NotificationResponseGetObjects)
 
@@ -323,6 +409,16 @@ func (n *Node) handleNotification(p *Peer, cmd *LevinCommand) error {
 func (n *Node) handleCommand(p *Peer, cmd *LevinCommand) error {
 	c, err := parseCommand(cmd)
 	if err != nil {
+		// Invoker must not wait for the response until timeout
+		code := LevinErrorFormat
+		if _, ok := mapCommandStructs[cmd.Command]; !ok {
+			code = LevinErrorConnectionHandlerNotDefined
+		}
+
+		if err := p.protocol.Reply(cmd.Command, struct{}{}, int32(code)); err != nil {
+			return err
+		}
+
 		return err
 	}
 
@@ -332,10 +428,14 @@ func (n *Node) handleCommand(p *Peer, cmd *LevinCommand) error {
 			return err
 		}
 
-		rsp := NewHandshakeResponse(n.Blockchain, n.ps.toPeerEntries())
+		rsp := NewHandshakeResponse(n)
 		if err := p.protocol.Reply(cmd.Command, rsp, 1); err != nil {
 			return err
 		}
+
+		// Peer is shared with the others only after we can connect to it
+		n.wg.Add(1)
+		go n.verifyPeer(p.PeerEntry())
 	case TimedSyncRequest:
 		command := c.(TimedSyncRequest)
 		if err := n.processSyncData(p, command.PayloadData, false); err != nil {
@@ -352,6 +452,34 @@ func (n *Node) handleCommand(p *Peer, cmd *LevinCommand) error {
 		}
 
 		n.logger.Infof("[%s] sync request %d", p, command.PayloadData.CurrentHeight)
+	case PingRequest:
+		rsp := PingResponse{Status: PingStatusOK, PeerID: n.Config.PeerID}
+		if err := p.protocol.Reply(cmd.Command, rsp, 1); err != nil {
+			return err
+		}
+	case StatInfoRequest:
+		rsp, err := n.HandleStatInfo(p, c.(StatInfoRequest))
+		if err != nil {
+			return err
+		}
+
+		if err := p.protocol.Reply(cmd.Command, *rsp, 1); err != nil {
+			return err
+		}
+	case NetworkStateRequest:
+		rsp, err := n.HandleNetworkState(p, c.(NetworkStateRequest))
+		if err != nil {
+			return err
+		}
+
+		if err := p.protocol.Reply(cmd.Command, *rsp, 1); err != nil {
+			return err
+		}
+	case PeerIDRequest:
+		rsp := PeerIDResponse{PeerID: n.Config.PeerID}
+		if err := p.protocol.Reply(cmd.Command, rsp, 1); err != nil {
+			return err
+		}
 	default:
 		n.logger.Errorf("received unknown commands type: %s", reflect.TypeOf(c))
 	}
@@ -359,6 +487,27 @@ func (n *Node) handleCommand(p *Peer, cmd *LevinCommand) error {
 	return nil
 }
 
+// handleResponse handles response to the request sent by our node
+func (n *Node) handleResponse(p *Peer, cmd *LevinCommand) error {
+	switch cmd.Command {
+	case CommandTimedSync:
+		var res TimedSyncResponse
+		if err := binary.Unmarshal(cmd.Payload, &res); err != nil {
+			return err
+		}
+
+		if err := n.processSyncData(p, res.PayloadData, false); err != nil {
+			return err
+		}
+
+		n.learnPeers(res.Peers)
+	default:
+		n.logger.Errorf("received unexpected response: %d", cmd.Command)
+	}
+
+	return nil
+}
+
 // processSyncData processing remote sync data
 //
 // This method is safe for concurrent calls.
@@ -424,45 +573,92 @@ func (n *Node) processSyncData(p *Peer, syncData SyncData, isInitial bool) error
 	return nil
 }
 
-func (n *Node) processNewObjects(blocks []*cryptonote.Block, transactions map[crypto.Hash][][]byte) error {
+// processNewObjects adds blocks received from the peer to the blockchain, error of the first failed block is returned.
+// Peer sent invalid block is dropped and banned, peer sent block not connected to our chain is dropped.
+// Peer sent known block switches to the idle state and its requests are released.
+func (n *Node) processNewObjects(p *Peer, blocks []*cryptonote.Block, transactions map[crypto.Hash][][]byte) error {
 	for i, block := range blocks {
 		if _, ok := transactions[*block.Hash()]; !ok {
 			return errors.New(fmt.Sprintf("transactions for block at index %d not found", i))
 		}
 
-		if err := n.Blockchain.AddBlock(block, transactions[*block.Hash()]); err != nil {
+		err := n.Blockchain.AddBlock(block, transactions[*block.Hash()])
+
+		switch {
+		case err == nil:
+		case errors.Is(err, cryptonote.ErrCategoryValidationFailed), errors.Is(err, cryptonote.ErrCategoryDeserialization):
+			p.logger.Debugf("block verification failed, dropping connection: %s", err)
+			n.misbehave(p, MisbehaviorInvalidBlock)
+			_ = p.protocol.Close()
+			return err
+		case errors.Is(err, cryptonote.ErrCategoryRejected):
+			p.logger.Debugf("block received at sync phase was marked as orphaned, dropping connection: %s", err)
+			_ = p.protocol.Close()
+			return err
+		case errors.Is(err, cryptonote.ErrCategoryAlreadyExists):
+			p.logger.Debugf("block already exists, switching to idle state: %s", err)
+			p.Lock()
+			p.state = PeerStateIdle
+			p.Unlock()
+
+			n.downloader.remove(p)
+			return err
+		default:
 			return err
-			// TODO: Process proper error
-			//
-			//if (addResult == error::AddBlockErrorCondition::BLOCK_VALIDATION_FAILED ||
-			//	addResult == error::AddBlockErrorCondition::TRANSACTION_VALIDATION_FAILED ||
-			//	addResult == error::AddBlockErrorCondition::DESERIALIZATION_FAILED) {
-			//	logger(Logging::DEBUGGING) << context << "Block verification failed, dropping connection: " << addResult.message();
-			//	m_p2p->drop_connection(context, true);
-			//	return 1;
-			//} else if (addResult == error::AddBlockErrorCondition::BLOCK_REJECTED) {
-			//	logger(Logging::DEBUGGING) << context << "Block received at sync phase was marked as orphaned, dropping connection: " << addResult.message();
-			//	m_p2p->drop_connection(context, true);
-			//	return 1;
-			//} else if (addResult == error::AddBlockErrorCode::ALREADY_EXISTS) {
-			//	logger(Logging::DEBUGGING) << context << "Block already exists, switching to idle state: " << addResult.message();
-			//	context.m_state = CryptoNoteConnectionContext::state_idle;
-			//	context.m_needed_objects.clear();
-			//	context.m_requested_objects.clear();
-			//	return 1;
-			//}
 		}
 	}
 
 	return nil
 }
 
+// RelayTransactions sends raw transactions to all the connected peers.
+func (n *Node) RelayTransactions(rawTransactions [][]byte) {
+	n.relayNotification(nil, NotificationNewTransactionsID, NotificationNewTransactions{
+		Transactions: rawTransactions,
+	})
+}
+
+// relayNotification sends notification to all the connected peers except the source one.
+// Source can be nil when notification is originated by our node.
+func (n *Node) relayNotification(source *Peer, command uint32, notification interface{}) {
+	for _, p := range n.ps.connected() {
+		if source != nil && (p == source || p.ID == source.ID) {
+			continue
+		}
+
+		if err := p.protocol.Notify(command, notification); err != nil {
+			n.logger.Warnf("[%s] failed to relay notification %d: %s", p, command, err)
+		}
+	}
+}
+
 // TODO: Implement CryptoNoteProtocolHandler::updateObservedHeight
 func (n *Node) updateObservedHeight(p *Peer, height uint32) {
 }
 
-// TODO: Implement NodeServer::add_host_fail and rename this method
+// misbehave raises misbehavior score of the peer IP.
+// All connections with the IP are closed when it is banned.
+func (n *Node) misbehave(p *Peer, score int) {
+	if !n.ps.misbehave(p.address.IP, score, time.Now()) {
+		return
+	}
+
+	p.logger.Warnf("[%s] banned for misbehavior", p)
+
+	for _, connected := range n.ps.connected() {
+		if connected.address.IP == p.address.IP {
+			_ = connected.protocol.Close()
+		}
+	}
+
+	_ = p.protocol.Close()
+}
+
+// addHostFail postpones next connection to the host
 func (n *Node) addHostFail(address NetworkAddress) {
+	delay := n.ps.addHostFail(address, time.Now())
+
+	n.logger.Debugf("[%s] host failed, next connection in %s", address.String(), delay)
 }
 
 // TODO: Implement on_connection_synchronized
@@ -476,57 +672,35 @@ func (n *Node) defaults() {
 		n.Config.PeerID = rand.Uint64()
 	}
 
-	if n.Config.ListenConfig == nil {
-		n.Config.ListenConfig = &net.ListenConfig{}
+	if n.Config.Network == nil {
+		n.Config.Network = n.Blockchain.Network
 	}
 
-	if n.dialer == nil {
-		n.dialer = &net.Dialer{
-			//LocalAddr: n.Config.BindAddr,
-			Timeout: time.Second,
-		}
+	if n.Config.SeedNodes == nil {
+		n.Config.SeedNodes = n.Config.Network.SeedNodes
 	}
-}
 
-func (n *Node) syncWithAddr(addr string) {
-	ctx, cancel := context.WithCancel(n.context)
-	defer cancel()
-
-	peer, err := NewPeerFromTCPAddress(ctx, n, addr)
-	if err != nil {
-		// n.logger.Errorf("failed to dial to peer: %s", err)
-		cancel()
-		return
+	if n.Config.MaxOutgoingConnections == 0 {
+		n.Config.MaxOutgoingConnections = DefaultConnectionsCount
 	}
 
-	//handshake, err := peer.handshake(n)
-	_, err = peer.handshake(n)
-	if err != nil {
-		n.logger.Errorf("failed handshake: %s", err)
-		cancel()
-		return
+	if n.Config.MaxIncomingConnections == 0 {
+		n.Config.MaxIncomingConnections = DefaultIncomingConnectionsCount
 	}
 
-	n.logger.Debugf("[%s] handshake established", peer)
-
-	if err := n.ps.toWhite(peer); err != nil {
-		n.logger.Error("failed to add peer to the store")
-		cancel()
-		return
+	if n.Config.MaxIncomingConnectionsPerIP == 0 {
+		n.Config.MaxIncomingConnectionsPerIP = DefaultIncomingConnectionsPerIP
 	}
 
-	n.wg.Add(1)
-	defer n.wg.Done()
-
-	//for _, pe := range handshake.Peers {
-	//	go n.syncWithAddr(c, pe.Address.String())
-	//}
-
-	n.connectionHandler(peer)
-
-	if err := n.ps.toGrey(peer); err != nil {
-		n.logger.Warnf("peer remove failed: %s", err)
+	if n.Config.ListenConfig == nil {
+		n.Config.ListenConfig = &net.ListenConfig{}
 	}
 
-	n.logger.Debugf("[%16x] sync closed", peer.ID)
+	if n.dialer == nil {
+		n.dialer = &net.Dialer{
+			//LocalAddr: n.Config.BindAddr,
+			Timeout: time.Second,
+		}
+	}
 }
+
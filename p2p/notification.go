@@ -35,8 +35,8 @@ type NotificationNewBlock struct {
 }
 
 type NotificationNewTransactions struct {
-	Stem         bool          `binary:"stem"`
-	Transactions []crypto.Hash `binary:"txs,binary"`
+	Stem         bool     `binary:"stem"`
+	Transactions [][]byte `binary:"txs,array"`
 }
 
 type NotificationRequestGetObjects struct {
@@ -45,7 +45,7 @@ type NotificationRequestGetObjects struct {
 }
 
 type NotificationTxPool struct {
-	Transactions []crypto.Hash `binary:"txs"`
+	Transactions []crypto.Hash `binary:"txs,binary"`
 }
 
 type NotificationNewLiteBlock struct {
@@ -54,6 +54,12 @@ type NotificationNewLiteBlock struct {
 	Block                   []byte `binary:"block"`
 }
 
+type NotificationMissingTxs struct {
+	CurrentBlockchainHeight uint32        `binary:"current_blockchain_height"`
+	BlockHash               crypto.Hash   `binary:"blockHash,binary"`
+	Transactions            []crypto.Hash `binary:"missing_txs,binary"`
+}
+
 // NotificationResponseChainEntry = 2007
 type NotificationResponseChainEntry struct {
 	StartHeight  uint32        `binary:"start_height"`
@@ -70,6 +76,7 @@ var mapNotificationID = map[uint32]interface{}{
 	NotificationResponseChainEntryID: NotificationResponseChainEntry{},
 	NotificationTxPoolID:             NotificationTxPool{},
 	NotificationNewLiteBlockID:       NotificationNewLiteBlock{},
+	NotificationMissingTxsID:         NotificationMissingTxs{},
 }
 
 func parseNotification(lc *LevinCommand) (interface{}, error) {
@@ -0,0 +1,202 @@
+package p2p
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"github.com/r3volut1oner/go-karbo/crypto"
+	"github.com/r3volut1oner/go-karbo/cryptonote"
+)
+
+// pendingLiteBlock is the lite block received from the peer which transactions are requested from the same peer
+type pendingLiteBlock struct {
+	notification NotificationNewLiteBlock
+	missed       map[crypto.Hash]bool
+}
+
+// HandleNewBlock adds block received from the peer to the blockchain and relays it to the other peers.
+func (n *Node) HandleNewBlock(p *Peer, nt NotificationNewBlock) error {
+	if p.state != PeerStateNormal {
+		return nil
+	}
+
+	n.updateObservedHeight(p, nt.CurrentBlockchainHeight)
+	p.remoteHeight = nt.CurrentBlockchainHeight
+
+	block, err := nt.Block.ToBlock()
+	if err != nil {
+		p.Shutdown()
+		return fmt.Errorf("[%s] failed to deserialize new block: %w", p, err)
+	}
+
+	relay, err := n.addNewBlock(p, block, nt.Block.Transactions)
+	if err != nil || !relay {
+		return err
+	}
+
+	nt.Hop++
+	n.relayBlock(p, nt)
+
+	return nil
+}
+
+// HandleNewLiteBlock adds block which transactions are taken from our pool.
+// Transactions missing in the pool are requested from the peer, block is added when they are received.
+// Transactions received from the peer by the hash are passed in the received.
+func (n *Node) HandleNewLiteBlock(p *Peer, nt NotificationNewLiteBlock, received map[crypto.Hash][]byte) error {
+	if p.state != PeerStateNormal {
+		return nil
+	}
+
+	n.updateObservedHeight(p, nt.CurrentBlockchainHeight)
+	p.remoteHeight = nt.CurrentBlockchainHeight
+
+	block := cryptonote.Block{}
+	if err := block.Deserialize(bytes.NewReader(nt.Block)); err != nil {
+		p.Shutdown()
+		return fmt.Errorf("[%s] failed to deserialize new lite block: %w", p, err)
+	}
+
+	if p.pendingLiteBlock != nil {
+		for hash := range p.pendingLiteBlock.missed {
+			if _, ok := received[hash]; !ok {
+				p.Shutdown()
+				return errors.New(fmt.Sprintf("[%s] peer didn't send requested transaction %s", p, hash))
+			}
+		}
+
+		p.pendingLiteBlock = nil
+	}
+
+	var rawTransactions [][]byte
+	var missed []crypto.Hash
+	for i := range block.TransactionsHashes {
+		hash := block.TransactionsHashes[i]
+
+		if rawTransaction, ok := received[hash]; ok {
+			rawTransactions = append(rawTransactions, rawTransaction)
+		} else if transaction := n.TxPool.GetTransaction(&hash); transaction != nil {
+			rawTransactions = append(rawTransactions, transaction.Serialize())
+		} else {
+			missed = append(missed, hash)
+		}
+	}
+
+	if len(missed) > 0 {
+		p.pendingLiteBlock = &pendingLiteBlock{
+			notification: nt,
+			missed:       map[crypto.Hash]bool{},
+		}
+
+		for _, hash := range missed {
+			p.pendingLiteBlock.missed[hash] = true
+		}
+
+		p.logger.Debugf("lite block %s misses %d transactions, requesting them", block.Hash(), len(missed))
+
+		return p.protocol.Notify(NotificationMissingTxsID, NotificationMissingTxs{
+			CurrentBlockchainHeight: n.Blockchain.Height(),
+			BlockHash:               *block.Hash(),
+			Transactions:            missed,
+		})
+	}
+
+	relay, err := n.addNewBlock(p, &block, rawTransactions)
+	if err != nil || !relay {
+		return err
+	}
+
+	n.relayBlock(p, NotificationNewBlock{
+		Block:                   RawBlock{Block: nt.Block, Transactions: rawTransactions},
+		CurrentBlockchainHeight: nt.CurrentBlockchainHeight,
+		Hop:                     nt.Hop + 1,
+	})
+
+	return nil
+}
+
+// HandleMissingTxs replies with the transactions the peer misses for the lite block.
+func (n *Node) HandleMissingTxs(p *Peer, nt NotificationMissingTxs) error {
+	var transactions [][]byte
+	for i := range nt.Transactions {
+		transaction := n.TxPool.GetTransaction(&nt.Transactions[i])
+		if transaction == nil {
+			p.logger.Debugf("requested transaction %s of block %s not found", nt.Transactions[i], nt.BlockHash)
+			continue
+		}
+
+		transactions = append(transactions, transaction.Serialize())
+	}
+
+	return p.protocol.Notify(NotificationNewTransactionsID, NotificationNewTransactions{
+		Transactions: transactions,
+	})
+}
+
+// RelayBlock sends block accepted by our node to all the connected peers.
+func (n *Node) RelayBlock(block *cryptonote.Block, rawTransactions [][]byte) {
+	n.relayBlock(nil, NotificationNewBlock{
+		Block:                   RawBlock{Block: block.Serialize(), Transactions: rawTransactions},
+		CurrentBlockchainHeight: n.Blockchain.Height(),
+	})
+}
+
+// addNewBlock adds block received from the peer to the blockchain.
+// Returns true if block is added to the main chain and must be relayed.
+func (n *Node) addNewBlock(p *Peer, block *cryptonote.Block, rawTransactions [][]byte) (bool, error) {
+	err := n.Blockchain.AddBlock(block, rawTransactions)
+
+	switch {
+	case err == nil:
+	case errors.Is(err, cryptonote.ErrCategoryAlreadyExists):
+		return false, nil
+	case errors.Is(err, cryptonote.ErrCategoryRejected):
+		// We are behind the peer, synchronize with it
+		p.logger.Debugf("new block %s rejected as orphaned, synchronization required", block.Hash())
+		p.state = PeerStateSyncRequired
+		return false, nil
+	case errors.Is(err, cryptonote.ErrCategoryValidationFailed), errors.Is(err, cryptonote.ErrCategoryDeserialization):
+		// Connection is closed with the ban
+		n.misbehave(p, MisbehaviorInvalidBlock)
+		return false, fmt.Errorf("[%s] new block %s: %w", p, block.Hash(), err)
+	default:
+		return false, fmt.Errorf("[%s] new block %s: %w", p, block.Hash(), err)
+	}
+
+	// Blocks of the alternative chains are not relayed
+	if _, err := n.Blockchain.BlockIndexByHash(block.Hash()); err != nil {
+		p.logger.Debugf("new block %s added to alternative chain", block.Hash())
+		return false, nil
+	}
+
+	p.logger.Infof("new block %s added, height: %d", block.Hash(), n.Blockchain.Height())
+
+	return true, nil
+}
+
+// relayBlock sends new block to all the connected peers except the source one.
+// Peers supporting lite blocks receive the block without transactions.
+func (n *Node) relayBlock(source *Peer, nt NotificationNewBlock) {
+	lite := NotificationNewLiteBlock{
+		CurrentBlockchainHeight: nt.CurrentBlockchainHeight,
+		Hop:                     nt.Hop,
+		Block:                   nt.Block.Block,
+	}
+
+	for _, p := range n.ps.connected() {
+		if source != nil && (p == source || p.ID == source.ID) {
+			continue
+		}
+
+		var err error
+		if p.version >= LiteBlocksPropagationVersion {
+			err = p.protocol.Notify(NotificationNewLiteBlockID, lite)
+		} else {
+			err = p.protocol.Notify(NotificationNewBlockID, nt)
+		}
+
+		if err != nil {
+			n.logger.Warnf("[%s] failed to relay block: %s", p, err)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package p2p
+
+import (
+	"github.com/r3volut1oner/go-karbo/config"
+	"github.com/r3volut1oner/go-karbo/crypto"
+	"github.com/r3volut1oner/go-karbo/cryptonote"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func TestNode_HandleNewBlock(t *testing.T) {
+	n := testNode(t)
+	block := testNewBlock(t, n.Blockchain, nil)
+
+	source, sourceRemote := testPeer(t, n)
+	defer sourceRemote.Conn.Close()
+	source.ID = 1
+	source.state = PeerStateNormal
+
+	// Block must not be relayed back to the source peer, write would fail on timeout
+	assert.Nil(t, source.protocol.Conn.SetWriteDeadline(time.Now().Add(time.Second)))
+	assert.Nil(t, n.ps.addConnected(source))
+
+	target, targetRemote := testPeer(t, n)
+	defer targetRemote.Conn.Close()
+	target.ID = 2
+	target.version = config.P2PVersion4
+	assert.Nil(t, n.ps.addConnected(target))
+
+	nt := NotificationNewBlock{
+		Block:                   RawBlock{Block: block.Serialize()},
+		CurrentBlockchainHeight: 2,
+		Hop:                     1,
+	}
+
+	var relayed NotificationNewLiteBlock
+	testNotify(t, targetRemote, NotificationNewLiteBlockID, &relayed, func() {
+		assert.Nil(t, n.HandleNewBlock(source, nt))
+	})
+
+	assert.Equal(t, block.Hash(), n.Blockchain.TopBlock().Hash())
+	assert.Equal(t, uint32(2), source.remoteHeight)
+	assert.Equal(t, uint32(2), relayed.Hop)
+	assert.Equal(t, block.Serialize(), relayed.Block)
+
+	// Already known block is not relayed again
+	assert.Nil(t, n.HandleNewBlock(source, nt))
+}
+
+func TestNode_HandleNewBlockOrphaned(t *testing.T) {
+	n := testNode(t)
+	block := testNewBlock(t, n.Blockchain, nil)
+	block.PreviousBlockHash = crypto.Hash{1}
+
+	p, remote := testPeer(t, n)
+	defer remote.Conn.Close()
+	p.state = PeerStateNormal
+
+	assert.Nil(t, n.HandleNewBlock(p, NotificationNewBlock{Block: RawBlock{Block: block.Serialize()}}))
+	assert.Equal(t, PeerStateSyncRequired, p.state)
+}
+
+func TestNode_HandleNewLiteBlock(t *testing.T) {
+	n := testNode(t)
+	testMineBlock(t, n.Blockchain)
+
+	transaction := testTransaction(t, n.Blockchain)
+	block := testNewBlock(t, n.Blockchain, []cryptonote.Transaction{transaction})
+
+	p, remote := testPeer(t, n)
+	defer remote.Conn.Close()
+	p.state = PeerStateNormal
+
+	nt := NotificationNewLiteBlock{Block: block.Serialize(), CurrentBlockchainHeight: 3}
+
+	// Transaction is not in our pool, so it is requested from the peer
+	var missing NotificationMissingTxs
+	testNotify(t, remote, NotificationMissingTxsID, &missing, func() {
+		assert.Nil(t, n.HandleNewLiteBlock(p, nt, nil))
+	})
+
+	assert.Equal(t, *block.Hash(), missing.BlockHash)
+	assert.Equal(t, []crypto.Hash{*transaction.Hash()}, missing.Transactions)
+	assert.NotNil(t, p.pendingLiteBlock)
+
+	// Pending block is added when the peer sends the transaction
+	assert.Nil(t, n.HandleNewTransactions(p, NotificationNewTransactions{
+		Transactions: [][]byte{transaction.Serialize()},
+	}))
+
+	assert.Nil(t, p.pendingLiteBlock)
+	assert.Equal(t, block.Hash(), n.Blockchain.TopBlock().Hash())
+	assert.Equal(t, 0, n.TxPool.Size())
+}
+
+func TestNode_HandleMissingTxs(t *testing.T) {
+	n := testNode(t)
+	testMineBlock(t, n.Blockchain)
+
+	transaction := testTransaction(t, n.Blockchain)
+	assert.Nil(t, n.TxPool.AddTransaction(&transaction))
+
+	p, remote := testPeer(t, n)
+	defer remote.Conn.Close()
+
+	var response NotificationNewTransactions
+	testNotify(t, remote, NotificationNewTransactionsID, &response, func() {
+		assert.Nil(t, n.HandleMissingTxs(p, NotificationMissingTxs{
+			Transactions: []crypto.Hash{*transaction.Hash(), {1}},
+		}))
+	})
+
+	assert.Equal(t, [][]byte{transaction.Serialize()}, response.Transactions)
+}
@@ -0,0 +1,49 @@
+package p2p
+
+import (
+	"bytes"
+	"github.com/r3volut1oner/go-karbo/crypto"
+	"github.com/r3volut1oner/go-karbo/cryptonote"
+)
+
+// HandleNewTransactions adds received transactions to the memory pool.
+// Transactions accepted by the pool are relayed to the other peers.
+// Pending lite block of the peer is added when its missing transactions are received.
+func (n *Node) HandleNewTransactions(p *Peer, nt NotificationNewTransactions) error {
+	// Transactions received before synchronization can't be validated against our chain
+	if p.state != PeerStateNormal {
+		return nil
+	}
+
+	var relay [][]byte
+	received := map[crypto.Hash][]byte{}
+	for i, rawTransaction := range nt.Transactions {
+		transaction := cryptonote.Transaction{}
+		if err := transaction.Deserialize(bytes.NewReader(rawTransaction)); err != nil {
+			p.logger.Debugf("failed to deserialize transaction #%d: %s", i, err)
+			continue
+		}
+
+		received[*transaction.Hash()] = rawTransaction
+
+		if err := n.TxPool.AddTransaction(&transaction); err != nil {
+			p.logger.Debugf("transaction %s not added to pool: %s", transaction.Hash(), err)
+			continue
+		}
+
+		relay = append(relay, rawTransaction)
+	}
+
+	if len(relay) > 0 {
+		n.relayNotification(p, NotificationNewTransactionsID, NotificationNewTransactions{
+			Stem:         nt.Stem,
+			Transactions: relay,
+		})
+	}
+
+	if p.pendingLiteBlock != nil {
+		return n.HandleNewLiteBlock(p, p.pendingLiteBlock.notification, received)
+	}
+
+	return nil
+}
@@ -1,6 +1,8 @@
 package p2p
 
 import (
+	"errors"
+	"fmt"
 	"github.com/r3volut1oner/go-karbo/crypto"
 	"github.com/r3volut1oner/go-karbo/cryptonote"
 )
@@ -32,3 +34,40 @@ func (n *Node) NotifyRequestChain(p *Peer) error {
 
 	return nil
 }
+
+// HandleRequestChain finds first common block with the peer sparse chain and replies with the hashes
+// of our main chain blocks starting from it.
+func (n *Node) HandleRequestChain(p *Peer, nt NotificationRequestChain) error {
+	// Verify more than 0 requested blocks
+	if len(nt.Blocks) == 0 {
+		p.Shutdown()
+		return errors.New(fmt.Sprintf("[%s] request chain with 0 blocks", p))
+	}
+
+	genesisBlock, err := n.Blockchain.GenesisBlock()
+	if err != nil {
+		return fmt.Errorf("[%s] unexpected error: %w", p, err)
+	}
+
+	// Make sure genesis blocks belongs to same network
+	if *genesisBlock.Hash() != nt.Blocks[len(nt.Blocks)-1] {
+		p.Shutdown()
+		return errors.New(fmt.Sprintf("[%s] request chain genesis block not match", p))
+	}
+
+	startHeight, totalHeight, hashes, err := n.Blockchain.FindBlockchainSupplement(nt.Blocks, MaxBlockIdsSynchronization)
+	if err != nil {
+		return fmt.Errorf("[%s] failed to find blockchain supplement: %w", p, err)
+	}
+
+	p.logger.Debugf(
+		"request chain %d blocks, response start: %d, blocks: %d, total: %d",
+		len(nt.Blocks), startHeight, len(hashes), totalHeight,
+	)
+
+	return p.protocol.Notify(NotificationResponseChainEntryID, NotificationResponseChainEntry{
+		StartHeight:  startHeight,
+		TotalHeight:  totalHeight,
+		BlocksHashes: hashes,
+	})
+}
@@ -0,0 +1,144 @@
+package p2p
+
+import (
+	"github.com/r3volut1oner/go-karbo/config"
+	"github.com/r3volut1oner/go-karbo/crypto"
+	"github.com/r3volut1oner/go-karbo/cryptonote"
+	"github.com/r3volut1oner/go-karbo/encoding/binary"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net"
+	"testing"
+)
+
+func TestNode_HandleRequestChain(t *testing.T) {
+	n := testNode(t)
+	genesisHash := *n.Blockchain.TopBlock().Hash()
+
+	var blocks []crypto.Hash
+	for i := 0; i < 3; i++ {
+		blocks = append(blocks, *testMineBlock(t, n.Blockchain).Hash())
+	}
+
+	p, remote := testPeer(t, n)
+	defer remote.Conn.Close()
+
+	// Remote peer knows only first block of our chain
+	request := NotificationRequestChain{Blocks: []crypto.Hash{{1}, blocks[0], genesisHash}}
+
+	var response NotificationResponseChainEntry
+	testNotify(t, remote, NotificationResponseChainEntryID, &response, func() {
+		assert.Nil(t, n.HandleRequestChain(p, request))
+	})
+
+	assert.Equal(t, uint32(1), response.StartHeight)
+	assert.Equal(t, uint32(4), response.TotalHeight)
+	assert.Equal(t, blocks, response.BlocksHashes)
+}
+
+func TestNode_HandleRequestChainWrongGenesis(t *testing.T) {
+	n := testNode(t)
+
+	p, remote := testPeer(t, n)
+	defer remote.Conn.Close()
+
+	assert.NotNil(t, n.HandleRequestChain(p, NotificationRequestChain{}))
+	assert.Equal(t, PeerStateShutdown, p.state)
+
+	p, remote = testPeer(t, n)
+	defer remote.Conn.Close()
+
+	assert.NotNil(t, n.HandleRequestChain(p, NotificationRequestChain{Blocks: []crypto.Hash{{1}}}))
+	assert.Equal(t, PeerStateShutdown, p.state)
+}
+
+// testNode returns node with initialized mainnet blockchain in memory storage.
+// Checkpoint far in the future is added, so proof of work is not checked for the test blocks.
+func testNode(t *testing.T) *Node {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	bc := cryptonote.NewBlockChain(config.MainNet(), cryptonote.NewMemoryStorage(), logger)
+	assert.Nil(t, bc.Checkpoints.AddCheckpoint(1000, crypto.Hash{}))
+	assert.Nil(t, bc.Init())
+
+	n := NewNode(bc, cryptonote.NewTxPool(bc, logger), HostConfig{}, zap.NewNop())
+
+	return &n
+}
+
+// testPeer returns peer connected to the node through in-memory connection and the remote side of the connection
+func testPeer(t *testing.T, n *Node) (*Peer, *LevinProtocol) {
+	local, remote := net.Pipe()
+
+	p := NewPeer(n.logger, &LevinProtocol{Conn: local}, NetworkAddress{IP: 1, Port: 32347}, true)
+
+	return p, &LevinProtocol{Conn: remote}
+}
+
+// testNotify runs the handler and decodes notification it sends to the remote side
+func testNotify(t *testing.T, remote *LevinProtocol, command uint32, notification interface{}, handler func()) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		handler()
+	}()
+
+	cmd, err := remote.read()
+	assert.Nil(t, err)
+	assert.Equal(t, command, cmd.Command)
+	assert.True(t, cmd.IsNotify)
+	assert.Nil(t, binary.Unmarshal(cmd.Payload, notification))
+
+	<-done
+}
+
+// testMineBlock adds block without transactions on top of the chain
+func testMineBlock(t *testing.T, bc *cryptonote.BlockChain) *cryptonote.Block {
+	block := testNewBlock(t, bc, nil)
+	assert.Nil(t, bc.AddBlock(block, nil))
+
+	return block
+}
+
+// testNewBlock creates block with the transactions on top of the chain, each transaction pays minimal fee
+func testNewBlock(t *testing.T, bc *cryptonote.BlockChain, transactions []cryptonote.Transaction) *cryptonote.Block {
+	secretKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	publicKey, err := crypto.PublicFromSecret(&secretKey)
+	assert.Nil(t, err)
+
+	prev := bc.TopBlock()
+	index := prev.Index() + 1
+	fee := uint64(len(transactions)) * bc.Network.MinimalFeeValidator(index)
+
+	reward, _, err := bc.Network.GetBlockReward(config.BlockMajorVersion1, 0, 0, bc.BlockInfo(prev.Index()).TotalGeneratedCoins, fee)
+	assert.Nil(t, err)
+
+	block := &cryptonote.Block{
+		BlockHeader: cryptonote.BlockHeader{
+			MajorVersion:      config.BlockMajorVersion1,
+			MinorVersion:      config.BlockMinorVersion0,
+			Timestamp:         prev.Timestamp + config.DifficultyTarget,
+			PreviousBlockHash: *prev.Hash(),
+		},
+		BaseTransaction: cryptonote.Transaction{
+			TransactionPrefix: cryptonote.TransactionPrefix{
+				Version:      config.TransactionVersion1,
+				UnlockHeight: uint64(index + bc.Network.MinedMoneyUnlockWindow()),
+				Inputs:       []cryptonote.TransactionInput{cryptonote.InputCoinbase{BlockIndex: index}},
+				Outputs: []cryptonote.TransactionOutput{
+					{Amount: reward, Target: cryptonote.OutputKey{PublicKey: *publicKey}},
+				},
+				Extra: []byte{},
+			},
+		},
+	}
+
+	for i := range transactions {
+		block.TransactionsHashes = append(block.TransactionsHashes, *transactions[i].Hash())
+	}
+
+	return block
+}
@@ -0,0 +1,69 @@
+package p2p
+
+import (
+	"github.com/r3volut1oner/go-karbo/cryptonote"
+)
+
+// HandleRequestGetObjects replies with the requested main chain blocks and the pool or main chain transactions.
+// Objects we don't have are returned as missed, at most MaxBlockSynchronization blocks are served.
+func (n *Node) HandleRequestGetObjects(p *Peer, nt NotificationRequestGetObjects) error {
+	blocks := nt.Blocks
+	if len(blocks) > MaxBlockSynchronization {
+		p.logger.Debugf("requested %d blocks, only %d will be sent", len(blocks), MaxBlockSynchronization)
+		blocks = blocks[:MaxBlockSynchronization]
+	}
+
+	rsp := NotificationResponseGetObjects{
+		CurrentBlockchainHeight: n.Blockchain.Height(),
+	}
+
+	for i := range blocks {
+		block, transactions, err := n.Blockchain.BlockWithTransactions(&blocks[i])
+		if err == cryptonote.ErrStorageBlockNotFound {
+			rsp.MissedIds = append(rsp.MissedIds, blocks[i])
+			continue
+		}
+
+		if err != nil {
+			return err
+		}
+
+		rsp.Blocks = append(rsp.Blocks, newRawBlock(block, transactions))
+	}
+
+	for i := range nt.Transactions {
+		transaction := n.TxPool.GetTransaction(&nt.Transactions[i])
+		if transaction == nil {
+			var err error
+			transaction, _, err = n.Blockchain.GetTransaction(&nt.Transactions[i])
+			if err == cryptonote.ErrStorageTransactionNotFound {
+				rsp.MissedIds = append(rsp.MissedIds, nt.Transactions[i])
+				continue
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		rsp.Transactions = append(rsp.Transactions, transaction.Serialize())
+	}
+
+	p.logger.Debugf(
+		"request get objects, blocks: %d, transactions: %d, missed: %d",
+		len(rsp.Blocks), len(rsp.Transactions), len(rsp.MissedIds),
+	)
+
+	return p.protocol.Notify(NotificationResponseGetObjectsID, rsp)
+}
+
+// newRawBlock serializes the block with its transactions
+func newRawBlock(block *cryptonote.Block, transactions []cryptonote.Transaction) RawBlock {
+	rawBlock := RawBlock{Block: block.Serialize()}
+
+	for i := range transactions {
+		rawBlock.Transactions = append(rawBlock.Transactions, transactions[i].Serialize())
+	}
+
+	return rawBlock
+}
@@ -0,0 +1,79 @@
+package p2p
+
+import (
+	"github.com/r3volut1oner/go-karbo/config"
+	"github.com/r3volut1oner/go-karbo/crypto"
+	"github.com/r3volut1oner/go-karbo/cryptonote"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestNode_HandleRequestGetObjects(t *testing.T) {
+	n := testNode(t)
+	block := testMineBlock(t, n.Blockchain)
+
+	transaction := testTransaction(t, n.Blockchain)
+	assert.Nil(t, n.TxPool.AddTransaction(&transaction))
+
+	p, remote := testPeer(t, n)
+	defer remote.Conn.Close()
+
+	request := NotificationRequestGetObjects{
+		Blocks:       []crypto.Hash{*block.Hash(), {1}},
+		Transactions: []crypto.Hash{*transaction.Hash(), *block.BaseTransaction.Hash(), {2}},
+	}
+
+	var response NotificationResponseGetObjects
+	testNotify(t, remote, NotificationResponseGetObjectsID, &response, func() {
+		assert.Nil(t, n.HandleRequestGetObjects(p, request))
+	})
+
+	assert.Equal(t, uint32(2), response.CurrentBlockchainHeight)
+	assert.Equal(t, []RawBlock{{Block: block.Serialize()}}, response.Blocks)
+	assert.Equal(t, [][]byte{transaction.Serialize(), block.BaseTransaction.Serialize()}, response.Transactions)
+	assert.Equal(t, []crypto.Hash{{1}, {2}}, response.MissedIds)
+}
+
+func TestNode_HandleRequestGetObjectsLimit(t *testing.T) {
+	n := testNode(t)
+
+	p, remote := testPeer(t, n)
+	defer remote.Conn.Close()
+
+	request := NotificationRequestGetObjects{Blocks: make([]crypto.Hash, MaxBlockSynchronization+10)}
+
+	var response NotificationResponseGetObjects
+	testNotify(t, remote, NotificationResponseGetObjectsID, &response, func() {
+		assert.Nil(t, n.HandleRequestGetObjects(p, request))
+	})
+
+	assert.Len(t, response.MissedIds, MaxBlockSynchronization)
+}
+
+// testTransaction creates transaction spending the top block reward.
+// Signatures are not valid, so the transaction can be used only in the checkpoints zone.
+func testTransaction(t *testing.T, bc *cryptonote.BlockChain) cryptonote.Transaction {
+	secretKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	publicKey, err := crypto.PublicFromSecret(&secretKey)
+	assert.Nil(t, err)
+	image, err := crypto.GenerateKeyImage(publicKey, &secretKey)
+	assert.Nil(t, err)
+
+	amount := bc.TopBlock().BaseTransaction.Outputs[0].Amount
+	fee := bc.Network.MinimalFeeValidator(bc.TopBlock().Index() + 1)
+
+	return cryptonote.Transaction{
+		TransactionPrefix: cryptonote.TransactionPrefix{
+			Version: config.TransactionVersion1,
+			Inputs: []cryptonote.TransactionInput{
+				cryptonote.InputKey{Amount: amount, OutputIndexes: []uint32{0}, KeyImage: *image},
+			},
+			Outputs: []cryptonote.TransactionOutput{
+				{Amount: amount - fee, Target: cryptonote.OutputKey{PublicKey: *publicKey}},
+			},
+			Extra: []byte{},
+		},
+		TransactionSignatures: cryptonote.TransactionSignatures{{crypto.Signature{}}},
+	}
+}
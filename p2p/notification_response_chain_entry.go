@@ -0,0 +1,80 @@
+package p2p
+
+import (
+	"errors"
+	"fmt"
+	"github.com/r3volut1oner/go-karbo/crypto"
+)
+
+// HandleResponseChainEntry queues blocks of the peer chain missing in our blockchain to the downloader.
+// Next chain entry is requested if all blocks are known, peer is synchronized when its top block is reached.
+func (n *Node) HandleResponseChainEntry(p *Peer, nt NotificationResponseChainEntry) error {
+	p.logger.Debugf(
+		"notification response chain entry, start: %d, blocks: %d, total: %d",
+		nt.StartHeight, len(nt.BlocksHashes), nt.TotalHeight,
+	)
+
+	if len(nt.BlocksHashes) == 0 {
+		p.Shutdown()
+		// TODO: Create new error instance
+		return errors.New(fmt.Sprintf("[%s] received empty blocks in response chain enrty", p))
+	}
+
+	firstHash := nt.BlocksHashes[0]
+	hasFirstBlock := n.Blockchain.HaveBlock(&firstHash)
+
+	if !hasFirstBlock {
+		p.Shutdown()
+		// TODO: Create new error instance
+		return errors.New(fmt.Sprintf("[%s] hash %s missing in our blockchain", p, firstHash.String()))
+	}
+
+	p.remoteHeight = nt.TotalHeight
+	p.lastResponseHeight = nt.StartHeight + uint32(len(nt.BlocksHashes)-1)
+
+	if p.lastResponseHeight > p.remoteHeight {
+		// TODO: Create new error instance
+		p.Shutdown()
+		return errors.New(
+			fmt.Sprintf(
+				"[%s] sent wrong response chain entry, with TotalHeight = %d, StartHeight = %d, blocks = %d", p,
+				nt.StartHeight,
+				nt.TotalHeight,
+				len(nt.BlocksHashes),
+			),
+		)
+	}
+
+	startHeight := nt.StartHeight
+	var needed []crypto.Hash
+	for i := range nt.BlocksHashes {
+		if len(needed) == 0 && n.Blockchain.HaveBlock(&nt.BlocksHashes[i]) {
+			startHeight = nt.StartHeight + uint32(i) + 1
+			continue
+		}
+
+		needed = append(needed, nt.BlocksHashes[i])
+	}
+
+	if len(needed) > 0 {
+		n.downloader.add(p, nt.TotalHeight, startHeight, needed)
+		n.downloadBlocks()
+
+		return nil
+	}
+
+	if p.lastResponseHeight < (p.remoteHeight - 1) {
+		return n.NotifyRequestChain(p)
+	}
+
+	// TODO: Request missing pool transactions
+	// src/CryptoNoteProtocol/CryptoNoteProtocolHandler.cpp:907
+
+	p.state = PeerStateNormal
+	p.logger.Debugf("[%s] syncronized", p)
+
+	// TODO: On connection synchronized
+	// src/CryptoNoteProtocol/CryptoNoteProtocolHandler.cpp:911
+
+	return nil
+}
@@ -11,8 +11,8 @@ import (
 
 // NotificationResponseGetObjects == 2004
 type NotificationResponseGetObjects struct {
-	// Exists in old legacy code in definition but not exists in notification
-	Transactions            []string      `binary:"txs,omitempty"`
+	// Requested transactions, blocks are requested only during synchronization, so usually it is empty
+	Transactions            [][]byte      `binary:"txs,array,omitempty"`
 	Blocks                  []RawBlock    `binary:"blocks,array"`
 	CurrentBlockchainHeight uint32        `binary:"current_blockchain_height"`
 	MissedIds               []crypto.Hash `binary:"missed_ids,binary,omitempty"`
@@ -59,11 +59,6 @@ func (n *Node) HandleResponseGetObjects(p *Peer, nt NotificationResponseGetObjec
 		}
 
 		hash := block.Hash()
-		if !p.requestedBlocks.Has(hash) {
-			p.Shutdown()
-			return errors.New(fmt.Sprintf("[%s] got not requested block #%d '%s'", p, i, hash.String()))
-		}
-
 		if len(block.TransactionsHashes) != len(rawBlock.Transactions) {
 			p.Shutdown()
 			return errors.New(fmt.Sprintf(
@@ -72,24 +67,21 @@ func (n *Node) HandleResponseGetObjects(p *Peer, nt NotificationResponseGetObjec
 			))
 		}
 
-		p.requestedBlocks.Remove(hash)
 		transactions[*hash] = rawBlock.Transactions
 		orderedBlocks[i] = &block
 	}
 
-	if len(p.requestedBlocks) > 0 {
+	if err := n.downloader.receive(p, orderedBlocks, transactions); err != nil {
 		p.Shutdown()
-		return errors.New(fmt.Sprintf(
-			"[%s] got not all requested objectes, missing %d", p, len(p.requestedBlocks),
-		))
+		return fmt.Errorf("[%s] wrong blocks received: %w", p, err)
 	}
 
-	if err := n.processNewObjects(orderedBlocks, transactions); err != nil {
-		return err
-	}
+	n.processDownloaded()
 
 	height := n.Blockchain.Height()
 	p.logger.Infof("process block, total height: %d", height)
 
-	return p.requestMissingBlocks(n, true)
+	n.downloadBlocks()
+
+	return nil
 }
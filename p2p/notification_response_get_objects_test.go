@@ -1,10 +1,16 @@
 package p2p
 
 import (
+	"errors"
+	"github.com/r3volut1oner/go-karbo/config"
+	"github.com/r3volut1oner/go-karbo/crypto"
+	"github.com/r3volut1oner/go-karbo/cryptonote"
 	"github.com/r3volut1oner/go-karbo/encoding/binary"
 	"github.com/stretchr/testify/assert"
+	"io"
 	"io/ioutil"
 	"testing"
+	"time"
 )
 
 func TestDecodeResponseGetObject(t *testing.T) {
@@ -38,3 +44,64 @@ func TestDecodeResponseGetObject(t *testing.T) {
 
 	assert.Equal(t, rsp, dec)
 }
+
+func TestNode_ProcessNewObjects(t *testing.T) {
+	n := testNode(t)
+
+	p, remote := testPeer(t, n)
+	defer remote.Conn.Close()
+
+	block := testNewBlock(t, n.Blockchain, nil)
+	alternative := testNewBlock(t, n.Blockchain, nil)
+	transactions := map[crypto.Hash][][]byte{*block.Hash(): nil}
+	assert.Nil(t, n.processNewObjects(p, []*cryptonote.Block{block}, transactions))
+
+	// Known block doesn't drop the peer, peer switches to idle and its requests are released
+	n.downloader.add(p, 10, 2, []crypto.Hash{{2}})
+	assert.Len(t, n.downloader.schedule(time.Now()), 1)
+
+	p.state = PeerStateSynchronizing
+	err := n.processNewObjects(p, []*cryptonote.Block{block}, transactions)
+	assert.True(t, errors.Is(err, cryptonote.ErrCategoryAlreadyExists))
+	assert.Equal(t, PeerStateIdle, p.state)
+	assert.Empty(t, n.downloader.schedule(time.Now()))
+	assert.Nil(t, n.downloader.hashes[crypto.Hash{2}].source)
+	assert.Nil(t, p.protocol.Conn.SetWriteDeadline(time.Now().Add(time.Millisecond)))
+	_, err = p.protocol.Conn.Write([]byte{1})
+	assert.NotEqual(t, io.ErrClosedPipe, err)
+
+	// Orphaned block drops the peer without the ban
+	orphan := testNewBlock(t, n.Blockchain, nil)
+	orphan.PreviousBlockHash = crypto.Hash{1}
+	err = n.processNewObjects(p, []*cryptonote.Block{orphan}, map[crypto.Hash][][]byte{*orphan.Hash(): nil})
+	assert.True(t, errors.Is(err, cryptonote.ErrCategoryRejected))
+	_, err = p.protocol.Conn.Write([]byte{1})
+	assert.Equal(t, io.ErrClosedPipe, err)
+	assert.False(t, n.ps.isBanned(p.address.IP, time.Now()))
+
+	// Invalid block drops and bans the peer
+	p, remote = testPeer(t, n)
+	defer remote.Conn.Close()
+
+	invalid := testNewBlock(t, n.Blockchain, nil)
+	err = n.processNewObjects(p, []*cryptonote.Block{invalid}, map[crypto.Hash][][]byte{*invalid.Hash(): {{1}}})
+	assert.True(t, errors.Is(err, cryptonote.ErrCategoryDeserialization))
+	_, err = p.protocol.Conn.Write([]byte{1})
+	assert.Equal(t, io.ErrClosedPipe, err)
+	assert.True(t, n.ps.isBanned(p.address.IP, time.Now()))
+
+	// Block violating the checkpoint drops and bans the peer
+	other := testNode(t)
+	p, remote = testPeer(t, other)
+	defer remote.Conn.Close()
+
+	assert.Nil(t, other.Blockchain.AddBlock(block, nil))
+	assert.Nil(t, other.Blockchain.Checkpoints.AddCheckpoint(block.Index(), *block.Hash()))
+	err = other.processNewObjects(p, []*cryptonote.Block{alternative}, map[crypto.Hash][][]byte{*alternative.Hash(): nil})
+	assert.True(t, errors.Is(err, cryptonote.ErrCategoryValidationFailed))
+	assert.True(t, errors.Is(err, config.ErrCheckpointsAltBeforeCheckpoint))
+	_ = p.protocol.Conn.SetWriteDeadline(time.Now().Add(time.Millisecond))
+	_, err = p.protocol.Conn.Write([]byte{1})
+	assert.Equal(t, io.ErrClosedPipe, err)
+	assert.True(t, other.ps.isBanned(p.address.IP, time.Now()))
+}
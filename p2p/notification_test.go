@@ -1,7 +1,9 @@
 package p2p
 
 import (
+	"bytes"
 	"github.com/r3volut1oner/go-karbo/config"
+	"github.com/r3volut1oner/go-karbo/cryptonote"
 	"github.com/r3volut1oner/go-karbo/encoding/binary"
 	"github.com/stretchr/testify/assert"
 	"io/ioutil"
@@ -30,6 +32,37 @@ func TestDecodeNewLiteObject(t *testing.T) {
 	assert.Equal(t, n, dec)
 }
 
+func TestDecodeNewTransactions(t *testing.T) {
+	payload, err := ioutil.ReadFile("./fixtures/2002.dat")
+	assert.Nil(t, err)
+
+	var n NotificationNewTransactions
+	err = binary.Unmarshal(payload, &n)
+	assert.Nil(t, err)
+
+	assert.False(t, n.Stem)
+	assert.Len(t, n.Transactions, 1)
+
+	var transaction cryptonote.Transaction
+	assert.Nil(t, transaction.Deserialize(bytes.NewReader(n.Transactions[0])))
+	assert.Equal(t, n.Transactions[0], transaction.Serialize())
+}
+
+func TestDecodeTxPool(t *testing.T) {
+	payload, err := ioutil.ReadFile("./fixtures/2008.dat")
+	assert.Nil(t, err)
+
+	var n NotificationTxPool
+	err = binary.Unmarshal(payload, &n)
+	assert.Nil(t, err)
+
+	assert.Len(t, n.Transactions, 4)
+
+	enc, err := binary.Marshal(n)
+	assert.Nil(t, err)
+	assert.Equal(t, payload, enc)
+}
+
 func TestRawBlock_ToBlock20(t *testing.T) {
 	blockPayload, err := ioutil.ReadFile("./fixtures/block_20.dat")
 	assert.Nil(t, err)
@@ -0,0 +1,47 @@
+package p2p
+
+import (
+	"github.com/r3volut1oner/go-karbo/crypto"
+)
+
+// NotifyTxPool sends hashes of our pool transactions, so the peer can reply with the transactions we are missing.
+func (n *Node) NotifyTxPool(p *Peer) error {
+	notification := NotificationTxPool{
+		Transactions: n.TxPool.TransactionsHashes(),
+	}
+
+	p.logger.Debugf("request tx pool (%d transactions)", len(notification.Transactions))
+
+	if err := p.protocol.Notify(NotificationTxPoolID, notification); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// HandleTxPool replies with the pool transactions that are missing in the peer pool.
+func (n *Node) HandleTxPool(p *Peer, nt NotificationTxPool) error {
+	known := map[crypto.Hash]bool{}
+	for _, hash := range nt.Transactions {
+		known[hash] = true
+	}
+
+	var missing [][]byte
+	for _, hash := range n.TxPool.TransactionsHashes() {
+		if known[hash] {
+			continue
+		}
+
+		if transaction := n.TxPool.GetTransaction(&hash); transaction != nil {
+			missing = append(missing, transaction.Serialize())
+		}
+	}
+
+	if len(missing) == 0 {
+		return nil
+	}
+
+	return p.protocol.Notify(NotificationNewTransactionsID, NotificationNewTransactions{
+		Transactions: missing,
+	})
+}
@@ -4,8 +4,6 @@ import (
 	"context"
 	"errors"
 	"fmt"
-	"github.com/r3volut1oner/go-karbo/crypto"
-	"github.com/r3volut1oner/go-karbo/cryptonote"
 	"github.com/signalsciences/ipv4"
 	"go.uber.org/zap"
 	"net"
@@ -46,11 +44,17 @@ type Peer struct {
 
 	protocol *LevinProtocol
 
+	// lastSeen is the unix time when the peer was seen connected
+	lastSeen uint64
+
+	// lastReceived is the time of the last packet received from the peer
+	lastReceived time.Time
+
 	remoteHeight       uint32
 	lastResponseHeight uint32
 
-	neededBlocks    crypto.HashList
-	requestedBlocks crypto.HashList
+	// pendingLiteBlock is the lite block waiting for the missing transactions requested from the peer
+	pendingLiteBlock *pendingLiteBlock
 
 	// procMutex blocked when peer processing some command or notification
 	procMutex sync.Mutex
@@ -82,14 +86,14 @@ func NewPeerFromTCPAddress(ctx context.Context, n *Node, addr string) (*Peer, er
 
 	address := NetworkAddressFromTCPAddr(tcpAddr)
 
-	return NewPeer(n.logger, &LevinProtocol{conn}, address, false), nil
+	return NewPeer(n.logger, NewLevinProtocol(conn), address, false), nil
 }
 
 // NewPeerFromIncomingConnection returns new seed from some incoming connection.
 func NewPeerFromIncomingConnection(n *Node, conn *net.TCPConn) *Peer {
 	address := NetworkAddressFromTCPAddr(conn.RemoteAddr().(*net.TCPAddr))
 
-	return NewPeer(n.logger, &LevinProtocol{conn}, address, true)
+	return NewPeer(n.logger, NewLevinProtocol(conn), address, true)
 }
 
 func NewPeer(logger *zap.SugaredLogger, protocol *LevinProtocol, address NetworkAddress, isIncoming bool) *Peer {
@@ -122,7 +126,7 @@ func (p *Peer) PeerEntry() PeerEntry {
 	return PeerEntry{
 		ID:       p.ID,
 		Address:  p.address,
-		LastSeen: uint64(time.Now().Unix()),
+		LastSeen: p.lastSeen,
 	}
 }
 
@@ -135,13 +139,13 @@ func (p *Peer) String() string {
 	return fmt.Sprintf("%s", p.address.String())
 }
 
-func (p *Peer) handshake(n *Node) (*HandshakeResponse, error) {
+func (p *Peer) handshake(ctx context.Context, n *Node) (*HandshakeResponse, error) {
 	if p.state != PeerStateBeforeHandshake {
 		return nil, errors.New("state is not before handshake")
 	}
 
 	var res HandshakeResponse
-	if err := p.protocol.Invoke(CommandHandshake, NewHandshakeRequest(n.Blockchain), &res); err != nil {
+	if err := p.protocol.Invoke(ctx, CommandHandshake, NewHandshakeRequest(n), &res); err != nil {
 		return nil, err
 	}
 
@@ -149,7 +153,7 @@ func (p *Peer) handshake(n *Node) (*HandshakeResponse, error) {
 		return nil, errors.New("wrong network id received")
 	}
 
-	if n.Config.Network.P2PMinimumVersion < res.NodeData.Version {
+	if res.NodeData.Version < n.Config.Network.P2PMinimumVersion {
 		return nil, errors.New("node data version not match minimal")
 	}
 
@@ -157,99 +161,51 @@ func (p *Peer) handshake(n *Node) (*HandshakeResponse, error) {
 		return nil, err
 	}
 
-	p.version = res.NodeData.Version
-	p.ID = res.NodeData.PeerID
+	p.SetVersion(res.NodeData.Version)
+	p.SetID(res.NodeData.PeerID)
 
-	// TODO: Handle new peerlist
+	n.learnPeers(res.Peers)
 
 	return &res, nil
 }
 
-func (p *Peer) ping() (*PingResponse, error) {
-	req := PingRequest{}
-	res := PingResponse{}
-
-	if err := p.protocol.Invoke(CommandPing, req, &res); err != nil {
-		return nil, err
-	}
+// requestTimedSync sends our sync data to the peer, response is handled by the connection handler
+func (p *Peer) requestTimedSync(n *Node) error {
+	return p.protocol.Request(CommandTimedSync, TimedSyncRequest{PayloadData: *newSyncData(n.Blockchain)})
+}
 
-	return &res, nil
+// received marks that packet is received from the peer
+func (p *Peer) received(now time.Time) {
+	p.Lock()
+	p.lastReceived = now
+	p.Unlock()
 }
 
-func (p *Peer) requestChain(bc *cryptonote.BlockChain) error {
-	n, err := NewRequestChain(bc)
-	if err != nil {
-		return err
-	}
+// isIdle checks if nothing is received from the peer for too long
+func (p *Peer) isIdle(now time.Time) bool {
+	p.RLock()
+	defer p.RUnlock()
 
-	p.logger.Debugf("[%s] request chain %d (%d blocks) ", p, p.lastResponseHeight, len(n.Blocks))
+	return now.Sub(p.lastReceived) > PeerIdleTimeout
+}
 
-	if err := p.protocol.Notify(NotificationRequestChainID, *n); err != nil {
-		return err
-	}
+// isHandshaked checks if peer ID is known after the handshake
+func (p *Peer) isHandshaked() bool {
+	p.RLock()
+	defer p.RUnlock()
 
-	return nil
+	return p.ID != 0
 }
 
-func (p *Peer) requestMissingBlocks(n *Node, checkHavingBlocks bool) error {
-	if len(p.neededBlocks) > 0 {
-		neededBlocks := p.neededBlocks
-		requestBlocks := crypto.HashList{}
-
-		for len(neededBlocks) > 0 && len(requestBlocks) < MaxBlockSynchronization {
-			nb := neededBlocks[0]
-
-			haveBlock := n.Blockchain.HaveBlock(&nb)
-
-			if !(checkHavingBlocks && haveBlock) {
-				requestBlocks = append(requestBlocks, nb)
-			}
-
-			neededBlocks = neededBlocks[1:]
-		}
-
-		if len(requestBlocks) > 0 {
-			n := NotificationRequestGetObjects{}
-			n.Blocks = requestBlocks
-
-			if err := p.protocol.Notify(NotificationRequestGetObjectsID, n); err != nil {
-				return err
-			}
-
-			p.requestedBlocks = append(p.requestedBlocks, requestBlocks...)
-		}
-
-		p.neededBlocks = neededBlocks
-	} else if p.lastResponseHeight < (p.remoteHeight - 1) {
-		if err := p.requestChain(n.Blockchain); err != nil {
-			return err
-		}
-	} else {
-		if p.lastResponseHeight == p.remoteHeight-1 && len(p.neededBlocks) != 0 && len(p.requestedBlocks) != 0 {
-			return errors.New(fmt.Sprintf(
-				"request missing blocks final condition failed: \n"+
-					"response height: %d\n"+
-					"remote blockchain height: %d\n"+
-					"needed objects size: %d\n"+
-					"requested objects size: %d",
-				p.lastResponseHeight,
-				p.remoteHeight,
-				len(p.neededBlocks),
-				len(p.requestedBlocks),
-			))
-		}
-
-		// TODO: Request missing pool transactions
-		// src/CryptoNoteProtocol/CryptoNoteProtocolHandler.cpp:907
-
-		p.state = PeerStateNormal
-		p.logger.Debugf("[%s] syncronized", p)
-
-		// TODO: On connection synchronized
-		// src/CryptoNoteProtocol/CryptoNoteProtocolHandler.cpp:911
+func (p *Peer) ping(ctx context.Context) (*PingResponse, error) {
+	req := PingRequest{}
+	res := PingResponse{}
+
+	if err := p.protocol.Invoke(ctx, CommandPing, req, &res); err != nil {
+		return nil, err
 	}
 
-	return nil
+	return &res, nil
 }
 
 func (na *NetworkAddress) String() string {
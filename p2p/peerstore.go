@@ -2,88 +2,550 @@ package p2p
 
 import (
 	"errors"
-	"fmt"
+	"github.com/r3volut1oner/go-karbo/encoding/binary"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+	"time"
 )
 
+var (
+	ErrPeerStoreNotConnected  = errors.New("peer is not connected")
+	ErrPeerStoreDuplicatePeer = errors.New("peer with same ID is already connected")
+	ErrPeerStoreIncomingLimit = errors.New("too many incoming connections")
+	ErrPeerStoreIPLimit       = errors.New("too many incoming connections from the IP")
+	ErrPeerStoreBanned        = errors.New("IP is banned")
+)
+
+// peerStore keeps the peers known by the node.
+//
+// White list contains the peers we were connected to.
+// Grey list contains the peers learned from the other nodes and the incoming peers not verified by ping yet.
+// Both lists are limited, least recently seen peers are evicted when the list is full.
+// Connected peers are kept in the connections after successful handshake.
 type peerStore struct {
+	connections map[uint64]*Peer
+
 	white *peerList
 	grey  *peerList
+
+	// fails contains failed connection attempts by the host address
+	fails map[NetworkAddress]*hostFail
+
+	// dialing contains addresses of the outgoing connections in progress
+	dialing map[NetworkAddress]bool
+
+	// incoming contains number of the accepted incoming connections by the IP, including the connections before handshake
+	incoming map[uint32]int
+
+	// scores contains misbehavior score by the IP, IP is banned when the score reaches MisbehaviorBanScore
+	scores map[uint32]misbehavior
+
+	// bans contains unix time the IP is banned until
+	bans map[uint32]uint64
+
+	sync.RWMutex
 }
 
+// peerList is the list of the peer entries limited by the size
 type peerList struct {
-	peers map[uint64]*Peer
+	entries map[uint64]PeerEntry
+	limit   int
+}
+
+// misbehavior is the score of the IP and the time of its last offence
+type misbehavior struct {
+	score int
+	last  time.Time
+}
+
+// peerStoreState is the peer lists and the bans saved on the disk
+type peerStoreState struct {
+	White []PeerEntry `binary:"white,binary"`
+	Grey  []PeerEntry `binary:"grey,binary"`
+	Bans  []BanEntry  `binary:"bans,binary"`
+}
+
+// BanEntry is the IP banned for the misbehavior until the unix time
+type BanEntry struct {
+	IP    uint32
+	Until uint64
+}
+
+// hostFail is the backoff state of the host we failed to connect to
+type hostFail struct {
+	count int
+	until time.Time
 }
 
 func NewPeerStore() *peerStore {
 	return &peerStore{
-		white: &peerList{map[uint64]*Peer{}},
-		grey:  &peerList{map[uint64]*Peer{}},
+		connections: map[uint64]*Peer{},
+		white:       newPeerList(WhitePeerListLimit),
+		grey:        newPeerList(GreyPeerListLimit),
+		fails:       map[NetworkAddress]*hostFail{},
+		dialing:     map[NetworkAddress]bool{},
+		incoming:    map[uint32]int{},
+		scores:      map[uint32]misbehavior{},
+		bans:        map[uint32]uint64{},
 	}
 }
 
+// toPeerEntries returns the white list peers shared with the other nodes, most recently seen first.
+// Connected peers are seen right now.
 func (ps *peerStore) toPeerEntries() []PeerEntry {
+	ps.RLock()
+	defer ps.RUnlock()
+
+	now := uint64(time.Now().Unix())
+
 	var peers []PeerEntry
+	for _, entry := range ps.white.sorted() {
+		if len(peers) == PeersInHandshake {
+			break
+		}
 
-	for _, p := range ps.white.peers {
-		peers = append(peers, p.PeerEntry())
-	}
+		if _, ok := ps.connections[entry.ID]; ok {
+			entry.LastSeen = now
+		}
 
-	for _, p := range ps.grey.peers {
-		peers = append(peers, p.PeerEntry())
+		peers = append(peers, entry)
 	}
 
 	return peers
 }
 
-func (ps *peerStore) toWhite(p *Peer) error {
-	_ = ps.grey.Remove(p)
+// toWhite moves the peer verified by ping from the grey list to the white list
+func (ps *peerStore) toWhite(entry PeerEntry) {
+	ps.Lock()
+	defer ps.Unlock()
 
-	if err := ps.white.Add(p); err != nil {
-		return err
+	entry.LastSeen = uint64(time.Now().Unix())
+
+	ps.grey.Remove(entry.ID)
+	ps.white.Add(entry)
+}
+
+// addConnected adds the peer after successful handshake.
+// Outgoing peers are saved in the white list, incoming peers are saved in the grey list until they are verified by ping.
+// Returns error if peer with same ID is already connected.
+func (ps *peerStore) addConnected(p *Peer) error {
+	ps.Lock()
+	defer ps.Unlock()
+
+	if _, ok := ps.connections[p.ID]; ok {
+		return ErrPeerStoreDuplicatePeer
 	}
 
+	ps.connections[p.ID] = p
+	p.lastSeen = uint64(time.Now().Unix())
+
+	ps.save(p)
+
 	return nil
 }
 
-func (ps *peerStore) toGrey(p *Peer) error {
-	_ = ps.white.Remove(p)
+// removeConnected removes disconnected peer, its entry is kept in the peer lists with the last seen time.
+func (ps *peerStore) removeConnected(p *Peer) error {
+	ps.Lock()
+	defer ps.Unlock()
 
-	if err := ps.grey.Add(p); err != nil {
-		return err
+	if ps.connections[p.ID] != p {
+		return ErrPeerStoreNotConnected
 	}
 
+	delete(ps.connections, p.ID)
+	p.lastSeen = uint64(time.Now().Unix())
+
+	ps.save(p)
+
 	return nil
 }
 
-func (pl *peerList) Add(p *Peer) error {
-	if p.ID == 0 {
-		return errors.New("peer must have ID for save it in store")
+// save updates entry of the connected peer in the peer lists.
+// Peers not accepting connections are not saved.
+//
+// This method is NOT safe for concurrent access.
+func (ps *peerStore) save(p *Peer) {
+	if p.address.Port == 0 {
+		return
+	}
+
+	if p.isIncoming && !ps.white.Has(p.ID) {
+		ps.grey.Add(p.PeerEntry())
+		return
+	}
+
+	ps.grey.Remove(p.ID)
+	ps.white.Add(p.PeerEntry())
+}
+
+// addEntries adds peers learned from the remote peer list to the grey list.
+// White list peers and our own node are skipped, known peers are updated with the latest entry.
+func (ps *peerStore) addEntries(selfID uint64, entries []PeerEntry) int {
+	ps.Lock()
+	defer ps.Unlock()
+
+	added := 0
+	for _, entry := range entries {
+		if entry.ID == 0 || entry.ID == selfID || entry.Address.Port == 0 || ps.white.Has(entry.ID) {
+			continue
+		}
+
+		if known, ok := ps.grey.Get(entry.ID); ok {
+			if entry.LastSeen > known.LastSeen {
+				ps.grey.Add(entry)
+			}
+
+			continue
+		}
+
+		ps.grey.Add(entry)
+		added++
+	}
+
+	return added
+}
+
+// connected returns the connected peers
+func (ps *peerStore) connected() []*Peer {
+	ps.RLock()
+	defer ps.RUnlock()
+
+	peers := make([]*Peer, 0, len(ps.connections))
+	for _, p := range ps.connections {
+		peers = append(peers, p)
+	}
+
+	return peers
+}
+
+// outgoingCount returns number of the connected outgoing peers including the connections in progress
+func (ps *peerStore) outgoingCount() int {
+	ps.RLock()
+	defer ps.RUnlock()
+
+	count := len(ps.dialing)
+	for _, p := range ps.connections {
+		if !p.isIncoming {
+			count++
+		}
+	}
+
+	return count
+}
+
+// candidates filters addresses that can be dialed now.
+// Addresses of the white and grey list peers are added before the provided ones, most recently seen first.
+func (ps *peerStore) candidates(addresses []NetworkAddress, now time.Time) []NetworkAddress {
+	ps.RLock()
+	defer ps.RUnlock()
+
+	var all []NetworkAddress
+	for _, list := range []*peerList{ps.white, ps.grey} {
+		for _, entry := range list.sorted() {
+			all = append(all, entry.Address)
+		}
+	}
+	all = append(all, addresses...)
+
+	skip := map[NetworkAddress]bool{}
+	for _, p := range ps.connections {
+		skip[p.address] = true
+	}
+
+	var result []NetworkAddress
+	for _, address := range all {
+		if skip[address] || ps.dialing[address] || ps.isBackedOff(address, now) || ps.isBanned(address.IP, now) {
+			continue
+		}
+
+		skip[address] = true
+		result = append(result, address)
+	}
+
+	return result
+}
+
+// startDialing marks address as being dialed, returns false if address is dialed already
+func (ps *peerStore) startDialing(address NetworkAddress) bool {
+	ps.Lock()
+	defer ps.Unlock()
+
+	if ps.dialing[address] {
+		return false
+	}
+
+	ps.dialing[address] = true
+
+	return true
+}
+
+func (ps *peerStore) stopDialing(address NetworkAddress) {
+	ps.Lock()
+	delete(ps.dialing, address)
+	ps.Unlock()
+}
+
+// addHostFail postpones next connection to the host, delay is doubled with each next fail
+func (ps *peerStore) addHostFail(address NetworkAddress, now time.Time) time.Duration {
+	ps.Lock()
+	defer ps.Unlock()
+
+	fail, ok := ps.fails[address]
+	if !ok {
+		fail = &hostFail{}
+		ps.fails[address] = fail
+	}
+
+	delay := HostFailMaxBackoff
+	if fail.count < 32 && HostFailBackoff<<fail.count < HostFailMaxBackoff {
+		delay = HostFailBackoff << fail.count
+	}
+
+	fail.count++
+	fail.until = now.Add(delay)
+
+	return delay
+}
+
+func (ps *peerStore) resetHostFails(address NetworkAddress) {
+	ps.Lock()
+	delete(ps.fails, address)
+	ps.Unlock()
+}
+
+// isBackedOff checks if connection to the host is postponed.
+//
+// This method is NOT safe for concurrent access.
+func (ps *peerStore) isBackedOff(address NetworkAddress, now time.Time) bool {
+	fail, ok := ps.fails[address]
+
+	return ok && now.Before(fail.until)
+}
+
+// acceptIncoming counts the new incoming connection from the IP.
+// Returns error if IP is banned or the connection exceeds the total or the per IP limit.
+func (ps *peerStore) acceptIncoming(ip uint32, maxTotal, maxPerIP int, now time.Time) error {
+	ps.Lock()
+	defer ps.Unlock()
+
+	if ps.isBanned(ip, now) {
+		return ErrPeerStoreBanned
+	}
+
+	total := 0
+	for _, count := range ps.incoming {
+		total += count
 	}
 
-	if _, ok := pl.peers[p.ID]; ok {
-		return errors.New(fmt.Sprintf("peer '%v' already in the store", p.ID))
+	if total >= maxTotal {
+		return ErrPeerStoreIncomingLimit
 	}
 
-	pl.peers[p.ID] = p
+	if ps.incoming[ip] >= maxPerIP {
+		return ErrPeerStoreIPLimit
+	}
+
+	ps.incoming[ip]++
 
 	return nil
 }
 
-func (pl *peerList) Remove(p *Peer) error {
-	if _, ok := pl.peers[p.ID]; !ok {
-		return errors.New("peer id not found in store")
+// releaseIncoming counts the closed incoming connection from the IP
+func (ps *peerStore) releaseIncoming(ip uint32) {
+	ps.Lock()
+	defer ps.Unlock()
+
+	ps.incoming[ip]--
+	if ps.incoming[ip] <= 0 {
+		delete(ps.incoming, ip)
+	}
+}
+
+// misbehave raises the misbehavior score of the IP.
+// Score is forgiven when the IP did not misbehave for MisbehaviorForgiveTime.
+// IP is banned for BanDuration when the score reaches MisbehaviorBanScore, returns true if IP is banned.
+func (ps *peerStore) misbehave(ip uint32, score int, now time.Time) bool {
+	ps.Lock()
+	defer ps.Unlock()
+
+	m := ps.scores[ip]
+	if now.Sub(m.last) >= MisbehaviorForgiveTime {
+		m.score = 0
+	}
+
+	m.score += score
+	m.last = now
+
+	if m.score < MisbehaviorBanScore {
+		ps.scores[ip] = m
+		return false
+	}
+
+	delete(ps.scores, ip)
+	ps.bans[ip] = uint64(now.Add(BanDuration).Unix())
+
+	return true
+}
+
+// isBanned checks if the IP is banned now.
+//
+// This method is NOT safe for concurrent access.
+func (ps *peerStore) isBanned(ip uint32, now time.Time) bool {
+	until, ok := ps.bans[ip]
+
+	return ok && uint64(now.Unix()) < until
+}
+
+// removeExpired deletes the expired bans and the forgiven misbehavior scores.
+func (ps *peerStore) removeExpired(now time.Time) {
+	ps.Lock()
+	defer ps.Unlock()
+
+	for ip := range ps.bans {
+		if !ps.isBanned(ip, now) {
+			delete(ps.bans, ip)
+		}
+	}
+
+	for ip, m := range ps.scores {
+		if now.Sub(m.last) >= MisbehaviorForgiveTime {
+			delete(ps.scores, ip)
+		}
+	}
+}
+
+// Save writes white and grey lists and the active bans to the file.
+// File is replaced atomically, so the lists are not lost if the node is killed while saving.
+func (ps *peerStore) Save(path string) error {
+	ps.RLock()
+	state := peerStoreState{
+		White: ps.white.sorted(),
+		Grey:  ps.grey.sorted(),
+	}
+
+	for ip := range ps.bans {
+		if ps.isBanned(ip, time.Now()) {
+			state.Bans = append(state.Bans, BanEntry{IP: ip, Until: ps.bans[ip]})
+		}
+	}
+	ps.RUnlock()
+
+	sort.Slice(state.Bans, func(i, j int) bool {
+		return state.Bans[i].IP < state.Bans[j].IP
+	})
+
+	b, err := binary.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, b, 0644); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp, path)
+}
+
+// Load reads white and grey lists and the bans from the file, missing file is not an error.
+func (ps *peerStore) Load(path string) error {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	var state peerStoreState
+	if err := binary.Unmarshal(b, &state); err != nil {
+		return err
+	}
+
+	ps.Lock()
+	defer ps.Unlock()
+
+	for _, entry := range state.White {
+		ps.white.Add(entry)
+	}
+
+	for _, entry := range state.Grey {
+		if !ps.white.Has(entry.ID) {
+			ps.grey.Add(entry)
+		}
+	}
+
+	for _, ban := range state.Bans {
+		ps.bans[ban.IP] = ban.Until
 	}
 
-	delete(pl.peers, p.ID)
 	return nil
 }
 
+func newPeerList(limit int) *peerList {
+	return &peerList{
+		entries: map[uint64]PeerEntry{},
+		limit:   limit,
+	}
+}
+
+// Add saves the entry, known entry is replaced.
+// Least recently seen entry is evicted when the list is full.
+func (pl *peerList) Add(entry PeerEntry) {
+	if entry.ID == 0 {
+		return
+	}
+
+	if _, ok := pl.entries[entry.ID]; !ok && len(pl.entries) >= pl.limit {
+		var oldest PeerEntry
+		first := true
+		for _, e := range pl.entries {
+			if first || e.LastSeen < oldest.LastSeen {
+				oldest = e
+				first = false
+			}
+		}
+
+		delete(pl.entries, oldest.ID)
+	}
+
+	pl.entries[entry.ID] = entry
+}
+
+func (pl *peerList) Remove(ID uint64) {
+	delete(pl.entries, ID)
+}
+
 func (pl *peerList) Has(ID uint64) bool {
-	_, ok := pl.peers[ID]
+	_, ok := pl.entries[ID]
 
 	return ok
 }
 
-func (pl *peerList) Get(ID uint64) *Peer {
-	return pl.peers[ID]
+func (pl *peerList) Get(ID uint64) (PeerEntry, bool) {
+	entry, ok := pl.entries[ID]
+
+	return entry, ok
+}
+
+// sorted returns entries ordered by the last seen time, most recently seen first
+func (pl *peerList) sorted() []PeerEntry {
+	entries := make([]PeerEntry, 0, len(pl.entries))
+	for _, entry := range pl.entries {
+		entries = append(entries, entry)
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].LastSeen == entries[j].LastSeen {
+			return entries[i].ID < entries[j].ID
+		}
+
+		return entries[i].LastSeen > entries[j].LastSeen
+	})
+
+	return entries
 }
@@ -0,0 +1,204 @@
+package p2p
+
+import (
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestPeerStore_AddEntries(t *testing.T) {
+	ps := NewPeerStore()
+	logger := zap.NewNop().Sugar()
+
+	connected := NewPeer(logger, nil, NetworkAddress{IP: 1, Port: 1}, false)
+	connected.ID = 1
+	assert.Nil(t, ps.addConnected(connected))
+	assert.True(t, ps.white.Has(1))
+
+	added := ps.addEntries(100, []PeerEntry{
+		{ID: 1, Address: NetworkAddress{IP: 1, Port: 1}, LastSeen: 10},
+		{ID: 2, Address: NetworkAddress{IP: 2, Port: 2}, LastSeen: 10},
+		{ID: 3, Address: NetworkAddress{IP: 3, Port: 3}, LastSeen: 20},
+		{ID: 4, Address: NetworkAddress{IP: 4, Port: 0}, LastSeen: 10},
+		{ID: 100, Address: NetworkAddress{IP: 100, Port: 100}, LastSeen: 10},
+	})
+	assert.Equal(t, 2, added)
+	assert.True(t, ps.grey.Has(2))
+	assert.True(t, ps.grey.Has(3))
+
+	// Known peer is updated with the more recent entry
+	assert.Equal(t, 0, ps.addEntries(100, []PeerEntry{
+		{ID: 2, Address: NetworkAddress{IP: 2, Port: 22}, LastSeen: 30},
+	}))
+	entry, ok := ps.grey.Get(2)
+	assert.True(t, ok)
+	assert.Equal(t, uint32(22), entry.Address.Port)
+
+	// Connected peers are skipped, grey peers are ordered by last seen
+	seed := NetworkAddress{IP: 5, Port: 5}
+	assert.Equal(t, []NetworkAddress{
+		{IP: 2, Port: 22},
+		{IP: 3, Port: 3},
+		seed,
+	}, ps.candidates([]NetworkAddress{seed, {IP: 1, Port: 1}}, time.Now()))
+
+	assert.True(t, ps.startDialing(seed))
+	assert.False(t, ps.startDialing(seed))
+	assert.Equal(t, 2, ps.outgoingCount())
+	assert.NotContains(t, ps.candidates([]NetworkAddress{seed}, time.Now()), seed)
+
+	ps.stopDialing(seed)
+	assert.Equal(t, 1, ps.outgoingCount())
+
+	// Disconnected peer is kept in the white list
+	assert.Nil(t, ps.removeConnected(connected))
+	assert.Equal(t, 0, ps.outgoingCount())
+	assert.True(t, ps.white.Has(1))
+	assert.False(t, ps.grey.Has(1))
+
+	// Incoming peer is not verified, it is saved in the grey list
+	incoming := NewPeer(logger, nil, NetworkAddress{IP: 6, Port: 6}, true)
+	incoming.ID = 6
+	assert.Nil(t, ps.addConnected(incoming))
+	assert.Equal(t, ErrPeerStoreDuplicatePeer, ps.addConnected(incoming))
+	assert.True(t, ps.grey.Has(6))
+	assert.False(t, ps.white.Has(6))
+}
+
+func TestPeerStore_SaveLoad(t *testing.T) {
+	dir, err := ioutil.TempDir("", "peerstore")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "p2pstate.bin")
+
+	ps := NewPeerStore()
+	assert.Nil(t, ps.Load(path))
+	assert.Nil(t, ps.Save(path))
+
+	white := PeerEntry{ID: 1, Address: NetworkAddress{IP: 1, Port: 1}, LastSeen: 10}
+	ps.white.Add(white)
+	ps.addEntries(100, []PeerEntry{
+		{ID: 2, Address: NetworkAddress{IP: 2, Port: 2}, LastSeen: 20},
+		{ID: 3, Address: NetworkAddress{IP: 3, Port: 3}, LastSeen: 30},
+	})
+	assert.True(t, ps.misbehave(4, MisbehaviorBanScore, time.Now()))
+	assert.True(t, ps.misbehave(5, MisbehaviorBanScore, time.Now().Add(-BanDuration)))
+	assert.Nil(t, ps.Save(path))
+
+	loaded := NewPeerStore()
+	assert.Nil(t, loaded.Load(path))
+	assert.Equal(t, []PeerEntry{white}, loaded.white.sorted())
+	assert.Equal(t, ps.grey.sorted(), loaded.grey.sorted())
+
+	// Expired bans are not saved
+	assert.True(t, loaded.isBanned(4, time.Now()))
+	assert.NotContains(t, loaded.bans, uint32(5))
+
+	assert.Nil(t, ioutil.WriteFile(path, []byte{1, 2, 3}, 0644))
+	assert.NotNil(t, NewPeerStore().Load(path))
+}
+
+func TestPeerList_Limit(t *testing.T) {
+	pl := newPeerList(2)
+
+	pl.Add(PeerEntry{ID: 1, LastSeen: 20})
+	pl.Add(PeerEntry{ID: 2, LastSeen: 10})
+
+	// Known entry is updated without eviction
+	pl.Add(PeerEntry{ID: 1, LastSeen: 30})
+	assert.True(t, pl.Has(2))
+
+	// Least recently seen entry is evicted
+	pl.Add(PeerEntry{ID: 3, LastSeen: 15})
+	assert.False(t, pl.Has(2))
+	assert.Equal(t, []PeerEntry{{ID: 1, LastSeen: 30}, {ID: 3, LastSeen: 15}}, pl.sorted())
+}
+
+func TestPeerStore_HostFail(t *testing.T) {
+	ps := NewPeerStore()
+	address := NetworkAddress{IP: 1, Port: 1}
+	now := time.Now()
+
+	assert.Equal(t, HostFailBackoff, ps.addHostFail(address, now))
+	assert.Equal(t, HostFailBackoff*2, ps.addHostFail(address, now))
+	assert.Empty(t, ps.candidates([]NetworkAddress{address}, now))
+	assert.Equal(t, []NetworkAddress{address}, ps.candidates([]NetworkAddress{address}, now.Add(HostFailBackoff*2)))
+
+	for i := 0; i < 40; i++ {
+		ps.addHostFail(address, now)
+	}
+	assert.Equal(t, HostFailMaxBackoff, ps.addHostFail(address, now))
+
+	ps.resetHostFails(address)
+	assert.Equal(t, []NetworkAddress{address}, ps.candidates([]NetworkAddress{address}, now))
+}
+
+func TestPeerStore_AcceptIncoming(t *testing.T) {
+	ps := NewPeerStore()
+	now := time.Now()
+
+	assert.Nil(t, ps.acceptIncoming(1, 3, 2, now))
+	assert.Nil(t, ps.acceptIncoming(1, 3, 2, now))
+	assert.Equal(t, ErrPeerStoreIPLimit, ps.acceptIncoming(1, 3, 2, now))
+
+	assert.Nil(t, ps.acceptIncoming(2, 3, 2, now))
+	assert.Equal(t, ErrPeerStoreIncomingLimit, ps.acceptIncoming(3, 3, 2, now))
+
+	ps.releaseIncoming(1)
+	assert.Nil(t, ps.acceptIncoming(3, 3, 2, now))
+
+	ps.releaseIncoming(1)
+	ps.releaseIncoming(2)
+	ps.releaseIncoming(3)
+	assert.Empty(t, ps.incoming)
+}
+
+func TestPeerStore_Misbehave(t *testing.T) {
+	ps := NewPeerStore()
+	address := NetworkAddress{IP: 1, Port: 1}
+	now := time.Now()
+
+	assert.False(t, ps.misbehave(address.IP, MisbehaviorBanScore-1, now))
+	assert.Nil(t, ps.acceptIncoming(address.IP, 10, 10, now))
+	ps.releaseIncoming(address.IP)
+
+	assert.True(t, ps.misbehave(address.IP, 1, now))
+	assert.Equal(t, ErrPeerStoreBanned, ps.acceptIncoming(address.IP, 10, 10, now))
+	assert.Empty(t, ps.candidates([]NetworkAddress{address}, now))
+
+	// Ban expires, score starts from zero
+	later := now.Add(BanDuration)
+	assert.Nil(t, ps.acceptIncoming(address.IP, 10, 10, later))
+	assert.Equal(t, []NetworkAddress{address}, ps.candidates([]NetworkAddress{address}, later))
+	assert.False(t, ps.misbehave(address.IP, 1, later))
+}
+
+func TestPeerStore_MisbehaveForgive(t *testing.T) {
+	ps := NewPeerStore()
+	now := time.Now()
+
+	assert.False(t, ps.misbehave(1, MisbehaviorBanScore-1, now))
+	assert.False(t, ps.misbehave(1, 1, now.Add(MisbehaviorForgiveTime)))
+	assert.True(t, ps.misbehave(1, MisbehaviorBanScore-1, now.Add(MisbehaviorForgiveTime)))
+}
+
+func TestPeerStore_RemoveExpired(t *testing.T) {
+	ps := NewPeerStore()
+	now := time.Now()
+
+	assert.True(t, ps.misbehave(1, MisbehaviorBanScore, now))
+	assert.False(t, ps.misbehave(2, 1, now))
+
+	ps.removeExpired(now)
+	assert.Len(t, ps.bans, 1)
+	assert.Len(t, ps.scores, 1)
+
+	ps.removeExpired(now.Add(BanDuration))
+	assert.Empty(t, ps.bans)
+	assert.Empty(t, ps.scores)
+}
@@ -0,0 +1,193 @@
+package rpc
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"github.com/r3volut1oner/go-karbo/crypto"
+	"github.com/r3volut1oner/go-karbo/cryptonote"
+)
+
+// BlockHeaderResponse is the block header as the Karbo daemon returns it
+type BlockHeaderResponse struct {
+	MajorVersion byte   `json:"major_version"`
+	MinorVersion byte   `json:"minor_version"`
+	Timestamp    uint64 `json:"timestamp"`
+	PrevHash     string `json:"prev_hash"`
+	Nonce        uint32 `json:"nonce"`
+	OrphanStatus bool   `json:"orphan_status"`
+	Height       uint32 `json:"height"`
+	Depth        uint32 `json:"depth"`
+	Hash         string `json:"hash"`
+	Difficulty   uint64 `json:"difficulty"`
+	Reward       uint64 `json:"reward"`
+	NumTxes      uint32 `json:"num_txes"`
+	BlockSize    uint64 `json:"block_size"`
+}
+
+type GetBlockCountResponse struct {
+	Count  uint32 `json:"count"`
+	Status string `json:"status"`
+}
+
+type GetBlockHeaderByHashRequest struct {
+	Hash string `json:"hash"`
+}
+
+type GetBlockHeaderByHeightRequest struct {
+	Height uint32 `json:"height"`
+}
+
+type BlockHeaderResponseEnvelope struct {
+	BlockHeader BlockHeaderResponse `json:"block_header"`
+	Status      string              `json:"status"`
+}
+
+func handleGetBlockCount(s *Server, _ json.RawMessage) (interface{}, error) {
+	return GetBlockCountResponse{
+		Count:  s.Blockchain.Height(),
+		Status: StatusOK,
+	}, nil
+}
+
+// handleGetBlockHash returns hash of the main chain block, params is the array with the block index
+func handleGetBlockHash(s *Server, params json.RawMessage) (interface{}, error) {
+	var req []uint32
+	if err := parseParams(params, &req); err != nil {
+		return nil, err
+	}
+
+	if len(req) != 1 {
+		return nil, newError(ErrorCodeWrongParam, "Wrong parameters, expected height")
+	}
+
+	block, err := blockByIndex(s, req[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return block.Hash().String(), nil
+}
+
+func handleGetBlockHeaderByHash(s *Server, params json.RawMessage) (interface{}, error) {
+	var req GetBlockHeaderByHashRequest
+	if err := parseParams(params, &req); err != nil {
+		return nil, err
+	}
+
+	hash, err := parseHash(req.Hash)
+	if err != nil {
+		return nil, newError(ErrorCodeWrongParam, "Failed to parse hex representation of block hash. Hex = %s.", req.Hash)
+	}
+
+	block := s.Blockchain.BlockByHash(&hash)
+	if block == nil {
+		return nil, newError(ErrorCodeInternalError, "Internal error: can't get block by hash. Hash = %s.", req.Hash)
+	}
+
+	return blockHeaderEnvelope(s, block)
+}
+
+func handleGetBlockHeaderByHeight(s *Server, params json.RawMessage) (interface{}, error) {
+	var req GetBlockHeaderByHeightRequest
+	if err := parseParams(params, &req); err != nil {
+		return nil, err
+	}
+
+	block, err := blockByIndex(s, req.Height)
+	if err != nil {
+		return nil, err
+	}
+
+	return blockHeaderEnvelope(s, block)
+}
+
+func handleGetLastBlockHeader(s *Server, _ json.RawMessage) (interface{}, error) {
+	return blockHeaderEnvelope(s, s.Blockchain.TopBlock())
+}
+
+// blockByIndex returns main chain block or the error if index is higher than the top block
+func blockByIndex(s *Server, index uint32) (*cryptonote.Block, error) {
+	topIndex := s.Blockchain.TopBlock().Index()
+	if index > topIndex {
+		return nil, newError(ErrorCodeTooBigHeight, "To big height: %d, current blockchain height = %d", index, topIndex+1)
+	}
+
+	block := s.Blockchain.BlockByIndex(index)
+	if block == nil {
+		return nil, newError(ErrorCodeInternalError, "Internal error: can't get block by height. Height = %d.", index)
+	}
+
+	return block, nil
+}
+
+func blockHeaderEnvelope(s *Server, block *cryptonote.Block) (interface{}, error) {
+	header, err := newBlockHeaderResponse(s, block)
+	if err != nil {
+		return nil, err
+	}
+
+	return BlockHeaderResponseEnvelope{
+		BlockHeader: *header,
+		Status:      StatusOK,
+	}, nil
+}
+
+// newBlockHeaderResponse builds header of the main chain block
+func newBlockHeaderResponse(s *Server, block *cryptonote.Block) (*BlockHeaderResponse, error) {
+	index := block.Index()
+	topIndex := s.Blockchain.TopBlock().Index()
+
+	info := s.Blockchain.BlockInfo(index)
+	if info == nil {
+		return nil, newError(ErrorCodeInternalError, "Internal error: can't get block info. Height = %d.", index)
+	}
+
+	difficulty := info.CumulativeDifficulty
+	if index > 0 {
+		prevInfo := s.Blockchain.BlockInfo(index - 1)
+		if prevInfo == nil {
+			return nil, newError(ErrorCodeInternalError, "Internal error: can't get block info. Height = %d.", index-1)
+		}
+
+		difficulty -= prevInfo.CumulativeDifficulty
+	}
+
+	reward := uint64(0)
+	for _, output := range block.BaseTransaction.Outputs {
+		reward += output.Amount
+	}
+
+	return &BlockHeaderResponse{
+		MajorVersion: block.MajorVersion,
+		MinorVersion: block.MinorVersion,
+		Timestamp:    block.Timestamp,
+		PrevHash:     block.PreviousBlockHash.String(),
+		Nonce:        block.Nonce,
+		OrphanStatus: false,
+		Height:       index,
+		Depth:        topIndex - index,
+		Hash:         block.Hash().String(),
+		Difficulty:   difficulty,
+		Reward:       reward,
+		NumTxes:      uint32(len(block.TransactionsHashes)),
+		BlockSize:    info.Size,
+	}, nil
+}
+
+// parseHash decodes hash from its hex representation
+func parseHash(s string) (crypto.Hash, error) {
+	var hash crypto.Hash
+
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		return hash, err
+	}
+
+	if len(b) != len(hash) {
+		return hash, hex.ErrLength
+	}
+
+	copy(hash[:], b)
+
+	return hash, nil
+}
@@ -0,0 +1,31 @@
+package rpc
+
+import "fmt"
+
+// Error codes used by the Karbo daemon in addition to the JSON-RPC 2.0 ones
+const (
+	ErrorCodeWrongParam    = -1
+	ErrorCodeTooBigHeight  = -2
+	ErrorCodeInternalError = -5
+)
+
+// Error is the JSON-RPC error object
+type Error struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+var (
+	ErrParse          = &Error{Code: -32700, Message: "Parse error"}
+	ErrMethodNotFound = &Error{Code: -32601, Message: "Method not found"}
+	ErrInvalidParams  = &Error{Code: -32602, Message: "Invalid params"}
+	ErrInternal       = &Error{Code: ErrorCodeInternalError, Message: "Internal error"}
+)
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s (%d)", e.Message, e.Code)
+}
+
+func newError(code int, format string, args ...interface{}) *Error {
+	return &Error{Code: code, Message: fmt.Sprintf(format, args...)}
+}
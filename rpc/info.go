@@ -0,0 +1,43 @@
+package rpc
+
+import (
+	"encoding/json"
+)
+
+type GetInfoResponse struct {
+	Status               string `json:"status"`
+	Height               uint32 `json:"height"`
+	Difficulty           uint64 `json:"difficulty"`
+	TxCount              uint64 `json:"tx_count"`
+	TxPoolSize           int    `json:"tx_pool_size"`
+	AltBlocksCount       int    `json:"alt_blocks_count"`
+	LastKnownBlockIndex  uint32 `json:"last_known_block_index"`
+	TopBlockHash         string `json:"top_block_hash"`
+	CumulativeDifficulty uint64 `json:"cumulative_difficulty"`
+}
+
+func handleGetInfo(s *Server, _ json.RawMessage) (interface{}, error) {
+	topBlock := s.Blockchain.TopBlock()
+
+	info := s.Blockchain.BlockInfo(topBlock.Index())
+	if info == nil {
+		return nil, newError(ErrorCodeInternalError, "Internal error: can't get top block info")
+	}
+
+	difficulty, err := s.Blockchain.DifficultyForNextBlock()
+	if err != nil {
+		return nil, err
+	}
+
+	return GetInfoResponse{
+		Status:               StatusOK,
+		Height:               s.Blockchain.Height(),
+		Difficulty:           difficulty,
+		TxCount:              info.TotalGeneratedTransactions,
+		TxPoolSize:           s.TxPool.Size(),
+		AltBlocksCount:       s.Blockchain.AlternativeBlocksCount(),
+		LastKnownBlockIndex:  topBlock.Index(),
+		TopBlockHash:         topBlock.Hash().String(),
+		CumulativeDifficulty: info.CumulativeDifficulty,
+	}, nil
+}
@@ -0,0 +1,206 @@
+package rpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/r3volut1oner/go-karbo/cryptonote"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+// StatusOK is the status returned by the successful calls as the Karbo daemon does
+const StatusOK = "OK"
+
+// TransactionsRelay sends transactions accepted by the node to the network
+type TransactionsRelay interface {
+	RelayTransactions(rawTransactions [][]byte)
+}
+
+// Server is the HTTP JSON-RPC server compatible with the Karbo daemon API.
+//
+// All the methods are available through the "/json_rpc" endpoint,
+// some of them are also available by their own paths as the Karbo daemon serves them.
+type Server struct {
+	Blockchain *cryptonote.BlockChain
+	TxPool     *cryptonote.TxPool
+
+	// relay is used for sending transactions to the network, can be nil
+	relay TransactionsRelay
+
+	logger *zap.SugaredLogger
+
+	mux *http.ServeMux
+}
+
+// methodHandler handles the call with raw JSON params and returns the result to be serialized
+type methodHandler func(s *Server, params json.RawMessage) (interface{}, error)
+
+var methods = map[string]methodHandler{
+	"getinfo":                handleGetInfo,
+	"getblockcount":          handleGetBlockCount,
+	"getblockhash":           handleGetBlockHash,
+	"on_getblockhash":        handleGetBlockHash,
+	"getblockheaderbyhash":   handleGetBlockHeaderByHash,
+	"getblockheaderbyheight": handleGetBlockHeaderByHeight,
+	"getlastblockheader":     handleGetLastBlockHeader,
+	"sendrawtransaction":     handleSendRawTransaction,
+	"gettransactions":        handleGetTransactions,
+}
+
+// request is the JSON-RPC 2.0 request
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+}
+
+// response is the JSON-RPC 2.0 response
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// NewServer creates RPC server for the blockchain and the memory pool
+func NewServer(bc *cryptonote.BlockChain, pool *cryptonote.TxPool, relay TransactionsRelay, logger *zap.Logger) *Server {
+	s := &Server{
+		Blockchain: bc,
+		TxPool:     pool,
+		relay:      relay,
+		logger:     logger.Sugar(),
+		mux:        http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/json_rpc", s.handleJSONRPC)
+	s.mux.HandleFunc("/getinfo", s.handleMethod("getinfo"))
+	s.mux.HandleFunc("/sendrawtransaction", s.handleMethod("sendrawtransaction"))
+	s.mux.HandleFunc("/gettransactions", s.handleMethod("gettransactions"))
+
+	return s
+}
+
+// Run listens for the RPC requests on the address until context is done
+func (s *Server) Run(ctx context.Context, addr string) error {
+	httpServer := &http.Server{
+		Addr:    addr,
+		Handler: s,
+	}
+
+	go func() {
+		<-ctx.Done()
+
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			s.logger.Errorf("failed to shutdown rpc server: %s", err)
+		}
+	}()
+
+	s.logger.Debugf("rpc listening on %s", addr)
+
+	if err := httpServer.ListenAndServe(); err != http.ErrServerClosed {
+		return err
+	}
+
+	return nil
+}
+
+// ServeHTTP makes server usable as the http handler
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+// handleJSONRPC dispatches JSON-RPC request to the method handler
+func (s *Server) handleJSONRPC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		s.writeJSON(w, response{JSONRPC: "2.0", Error: ErrParse})
+		return
+	}
+
+	res := response{
+		JSONRPC: "2.0",
+		ID:      req.ID,
+	}
+
+	handler, ok := methods[req.Method]
+	if !ok {
+		res.Error = ErrMethodNotFound
+		s.writeJSON(w, res)
+		return
+	}
+
+	result, err := handler(s, req.Params)
+	if err != nil {
+		res.Error = s.toError(req.Method, err)
+	} else {
+		res.Result = result
+	}
+
+	s.writeJSON(w, res)
+}
+
+// handleMethod serves the method by its own path, the body is the params and the response is the result
+func (s *Server) handleMethod(method string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var params json.RawMessage
+		if r.Method == http.MethodPost {
+			if err := json.NewDecoder(r.Body).Decode(&params); err != nil {
+				http.Error(w, ErrParse.Message, http.StatusBadRequest)
+				return
+			}
+		}
+
+		result, err := methods[method](s, params)
+		if err != nil {
+			rpcErr := s.toError(method, err)
+			http.Error(w, rpcErr.Message, http.StatusInternalServerError)
+			return
+		}
+
+		s.writeJSON(w, result)
+	}
+}
+
+// toError converts handler error to the RPC error, unexpected errors are hidden behind the internal error
+func (s *Server) toError(method string, err error) *Error {
+	var rpcErr *Error
+	if errors.As(err, &rpcErr) {
+		return rpcErr
+	}
+
+	s.logger.Errorf("rpc method %s failed: %s", method, err)
+
+	return ErrInternal
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		s.logger.Errorf("failed to write rpc response: %s", err)
+	}
+}
+
+// parseParams decodes params into the struct, empty params leave the struct untouched
+func parseParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return nil
+	}
+
+	if err := json.Unmarshal(params, v); err != nil {
+		return ErrInvalidParams
+	}
+
+	return nil
+}
@@ -0,0 +1,267 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/r3volut1oner/go-karbo/config"
+	"github.com/r3volut1oner/go-karbo/crypto"
+	"github.com/r3volut1oner/go-karbo/cryptonote"
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestServer_GetBlockCount(t *testing.T) {
+	s, _ := testServer(t, 3)
+
+	var res GetBlockCountResponse
+	assert.Nil(t, testCall(t, s, "getblockcount", nil, &res))
+	assert.Equal(t, GetBlockCountResponse{Count: 4, Status: StatusOK}, res)
+}
+
+func TestServer_GetBlockHash(t *testing.T) {
+	s, bc := testServer(t, 3)
+
+	var hash string
+	assert.Nil(t, testCall(t, s, "getblockhash", []uint32{2}, &hash))
+	assert.Equal(t, bc.BlockByIndex(2).Hash().String(), hash)
+
+	err := testCall(t, s, "getblockhash", []uint32{4}, &hash)
+	assert.Equal(t, ErrorCodeTooBigHeight, err.Code)
+
+	err = testCall(t, s, "getblockhash", []uint32{}, &hash)
+	assert.Equal(t, ErrorCodeWrongParam, err.Code)
+}
+
+func TestServer_GetBlockHeader(t *testing.T) {
+	s, bc := testServer(t, 3)
+	block := bc.BlockByIndex(1)
+
+	var byHeight BlockHeaderResponseEnvelope
+	assert.Nil(t, testCall(t, s, "getblockheaderbyheight", GetBlockHeaderByHeightRequest{Height: 1}, &byHeight))
+	assert.Equal(t, StatusOK, byHeight.Status)
+
+	header := byHeight.BlockHeader
+	assert.Equal(t, block.Hash().String(), header.Hash)
+	assert.Equal(t, block.PreviousBlockHash.String(), header.PrevHash)
+	assert.Equal(t, uint32(1), header.Height)
+	assert.Equal(t, uint32(2), header.Depth)
+	assert.Equal(t, block.BaseTransaction.Outputs[0].Amount, header.Reward)
+	assert.Equal(t, block.Timestamp, header.Timestamp)
+	assert.Equal(t, config.BlockMajorVersion1, header.MajorVersion)
+	assert.Equal(t, uint64(1), header.Difficulty)
+	assert.Equal(t, bc.BlockInfo(1).Size, header.BlockSize)
+
+	var byHash BlockHeaderResponseEnvelope
+	assert.Nil(t, testCall(t, s, "getblockheaderbyhash", GetBlockHeaderByHashRequest{Hash: header.Hash}, &byHash))
+	assert.Equal(t, byHeight, byHash)
+
+	var last BlockHeaderResponseEnvelope
+	assert.Nil(t, testCall(t, s, "getlastblockheader", nil, &last))
+	assert.Equal(t, bc.TopBlock().Hash().String(), last.BlockHeader.Hash)
+	assert.Equal(t, uint32(0), last.BlockHeader.Depth)
+
+	err := testCall(t, s, "getblockheaderbyheight", GetBlockHeaderByHeightRequest{Height: 10}, &byHeight)
+	assert.Equal(t, ErrorCodeTooBigHeight, err.Code)
+
+	err = testCall(t, s, "getblockheaderbyhash", GetBlockHeaderByHashRequest{Hash: "zz"}, &byHash)
+	assert.Equal(t, ErrorCodeWrongParam, err.Code)
+
+	unknown := crypto.Hash{}
+	err = testCall(t, s, "getblockheaderbyhash", GetBlockHeaderByHashRequest{Hash: unknown.String()}, &byHash)
+	assert.Equal(t, ErrorCodeInternalError, err.Code)
+}
+
+func TestServer_GetInfo(t *testing.T) {
+	s, bc := testServer(t, 2)
+
+	var res GetInfoResponse
+	assert.Nil(t, testCall(t, s, "getinfo", nil, &res))
+	assert.Equal(t, StatusOK, res.Status)
+	assert.Equal(t, uint32(3), res.Height)
+	assert.Equal(t, uint32(2), res.LastKnownBlockIndex)
+	assert.Equal(t, bc.TopBlock().Hash().String(), res.TopBlockHash)
+	assert.Equal(t, 0, res.TxPoolSize)
+	assert.Equal(t, uint64(1), res.Difficulty)
+
+	// Same method is served by its own path
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/getinfo", nil))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var direct GetInfoResponse
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &direct))
+	assert.Equal(t, res, direct)
+}
+
+func TestServer_SendRawTransaction(t *testing.T) {
+	s, bc := testServer(t, 1)
+
+	relay := &testRelay{}
+	s.relay = relay
+
+	transaction := testTransaction(t, bc)
+	txHex := hex.EncodeToString(transaction.Serialize())
+
+	var res SendRawTransactionResponse
+	assert.Nil(t, testCall(t, s, "sendrawtransaction", SendRawTransactionRequest{TxAsHex: "zz"}, &res))
+	assert.Equal(t, StatusFailed, res.Status)
+
+	assert.Nil(t, testCall(t, s, "sendrawtransaction", SendRawTransactionRequest{TxAsHex: txHex}, &res))
+	assert.Equal(t, StatusOK, res.Status)
+	assert.Equal(t, 1, s.TxPool.Size())
+	assert.Equal(t, [][]byte{transaction.Serialize()}, relay.transactions)
+
+	// Transaction already in pool is not accepted again
+	assert.Nil(t, testCall(t, s, "sendrawtransaction", SendRawTransactionRequest{TxAsHex: txHex}, &res))
+	assert.Equal(t, StatusFailed, res.Status)
+
+	// Transactions are looked up in the pool and in the blockchain
+	coinbase := bc.TopBlock().BaseTransaction
+	missed := crypto.Hash{1}
+	var txs GetTransactionsResponse
+	assert.Nil(t, testCall(t, s, "gettransactions", GetTransactionsRequest{
+		TxsHashes: []string{transaction.Hash().String(), coinbase.Hash().String(), missed.String()},
+	}, &txs))
+	assert.Equal(t, []string{txHex, hex.EncodeToString(coinbase.Serialize())}, txs.TxsAsHex)
+	assert.Equal(t, []string{missed.String()}, txs.MissedTxs)
+}
+
+func TestServer_MethodNotFound(t *testing.T) {
+	s, _ := testServer(t, 0)
+
+	err := testCall(t, s, "unknown", nil, nil)
+	assert.Equal(t, ErrMethodNotFound, err)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/json_rpc", bytes.NewBufferString("{")))
+
+	var res response
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, ErrParse, res.Error)
+}
+
+type testRelay struct {
+	transactions [][]byte
+}
+
+func (r *testRelay) RelayTransactions(rawTransactions [][]byte) {
+	r.transactions = append(r.transactions, rawTransactions...)
+}
+
+// testServer creates RPC server for the blockchain with the count blocks mined on top of the genesis
+func testServer(t *testing.T, count int) (*Server, *cryptonote.BlockChain) {
+	logger := logrus.New()
+	logger.SetLevel(logrus.PanicLevel)
+
+	bc := cryptonote.NewBlockChain(config.MainNet(), cryptonote.NewMemoryStorage(), logger)
+	assert.Nil(t, bc.Checkpoints.AddCheckpoint(1000, crypto.Hash{}))
+	assert.Nil(t, bc.Init())
+
+	pool := cryptonote.NewTxPool(bc, logger)
+
+	for i := 0; i < count; i++ {
+		testMineBlock(t, bc)
+	}
+
+	return NewServer(bc, pool, nil, zap.NewNop()), bc
+}
+
+// testCall calls JSON-RPC method and decodes the result
+func testCall(t *testing.T, s *Server, method string, params interface{}, result interface{}) *Error {
+	body, err := json.Marshal(map[string]interface{}{
+		"jsonrpc": "2.0",
+		"id":      "0",
+		"method":  method,
+		"params":  params,
+	})
+	assert.Nil(t, err)
+
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/json_rpc", bytes.NewReader(body)))
+	assert.Equal(t, http.StatusOK, rec.Code)
+
+	var res struct {
+		ID     string          `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  *Error          `json:"error"`
+	}
+	assert.Nil(t, json.Unmarshal(rec.Body.Bytes(), &res))
+	assert.Equal(t, "0", res.ID)
+
+	if res.Error != nil {
+		return res.Error
+	}
+
+	assert.Nil(t, json.Unmarshal(res.Result, result))
+
+	return nil
+}
+
+// testMineBlock adds block without transactions on top of the chain
+func testMineBlock(t *testing.T, bc *cryptonote.BlockChain) {
+	secretKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	publicKey, err := crypto.PublicFromSecret(&secretKey)
+	assert.Nil(t, err)
+
+	prev := bc.TopBlock()
+	index := prev.Index() + 1
+
+	reward, _, err := bc.Network.GetBlockReward(config.BlockMajorVersion1, 0, 0, bc.BlockInfo(prev.Index()).TotalGeneratedCoins, 0)
+	assert.Nil(t, err)
+
+	block := &cryptonote.Block{
+		BlockHeader: cryptonote.BlockHeader{
+			MajorVersion:      config.BlockMajorVersion1,
+			MinorVersion:      config.BlockMinorVersion0,
+			Timestamp:         prev.Timestamp + config.DifficultyTarget,
+			PreviousBlockHash: *prev.Hash(),
+		},
+		BaseTransaction: cryptonote.Transaction{
+			TransactionPrefix: cryptonote.TransactionPrefix{
+				Version:      config.TransactionVersion1,
+				UnlockHeight: uint64(index + bc.Network.MinedMoneyUnlockWindow()),
+				Inputs:       []cryptonote.TransactionInput{cryptonote.InputCoinbase{BlockIndex: index}},
+				Outputs: []cryptonote.TransactionOutput{
+					{Amount: reward, Target: cryptonote.OutputKey{PublicKey: *publicKey}},
+				},
+				Extra: []byte{},
+			},
+		},
+	}
+
+	assert.Nil(t, bc.AddBlock(block, nil))
+}
+
+// testTransaction creates transaction spending the top block reward.
+// Signatures are not valid, so the transaction can be used only in the checkpoints zone.
+func testTransaction(t *testing.T, bc *cryptonote.BlockChain) cryptonote.Transaction {
+	secretKey, err := crypto.GenerateKey()
+	assert.Nil(t, err)
+	publicKey, err := crypto.PublicFromSecret(&secretKey)
+	assert.Nil(t, err)
+	image, err := crypto.GenerateKeyImage(publicKey, &secretKey)
+	assert.Nil(t, err)
+
+	amount := bc.TopBlock().BaseTransaction.Outputs[0].Amount
+	fee := bc.Network.MinimalFeeValidator(bc.TopBlock().Index() + 1)
+
+	return cryptonote.Transaction{
+		TransactionPrefix: cryptonote.TransactionPrefix{
+			Version: config.TransactionVersion1,
+			Inputs: []cryptonote.TransactionInput{
+				cryptonote.InputKey{Amount: amount, OutputIndexes: []uint32{0}, KeyImage: *image},
+			},
+			Outputs: []cryptonote.TransactionOutput{
+				{Amount: amount - fee, Target: cryptonote.OutputKey{PublicKey: *publicKey}},
+			},
+			Extra: []byte{},
+		},
+		TransactionSignatures: cryptonote.TransactionSignatures{{crypto.Signature{}}},
+	}
+}
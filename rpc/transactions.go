@@ -0,0 +1,98 @@
+package rpc
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/r3volut1oner/go-karbo/cryptonote"
+)
+
+// StatusFailed is the status of the not accepted transaction as the Karbo daemon returns it
+const StatusFailed = "Failed"
+
+type SendRawTransactionRequest struct {
+	TxAsHex string `json:"tx_as_hex"`
+}
+
+type SendRawTransactionResponse struct {
+	Status string `json:"status"`
+}
+
+type GetTransactionsRequest struct {
+	TxsHashes []string `json:"txs_hashes"`
+}
+
+type GetTransactionsResponse struct {
+	TxsAsHex  []string `json:"txs_as_hex"`
+	MissedTxs []string `json:"missed_tx"`
+	Status    string   `json:"status"`
+}
+
+// handleSendRawTransaction adds transaction to the memory pool and relays it to the network
+func handleSendRawTransaction(s *Server, params json.RawMessage) (interface{}, error) {
+	var req SendRawTransactionRequest
+	if err := parseParams(params, &req); err != nil {
+		return nil, err
+	}
+
+	rawTransaction, err := hex.DecodeString(req.TxAsHex)
+	if err != nil {
+		s.logger.Debugf("failed to parse transaction hex: %s", err)
+		return SendRawTransactionResponse{Status: StatusFailed}, nil
+	}
+
+	transaction := cryptonote.Transaction{}
+	if err := transaction.Deserialize(bytes.NewReader(rawTransaction)); err != nil {
+		s.logger.Debugf("failed to deserialize transaction: %s", err)
+		return SendRawTransactionResponse{Status: StatusFailed}, nil
+	}
+
+	if err := s.TxPool.AddTransaction(&transaction); err != nil {
+		s.logger.Debugf("transaction %s not accepted: %s", transaction.Hash(), err)
+		return SendRawTransactionResponse{Status: StatusFailed}, nil
+	}
+
+	if s.relay != nil {
+		s.relay.RelayTransactions([][]byte{rawTransaction})
+	}
+
+	return SendRawTransactionResponse{Status: StatusOK}, nil
+}
+
+// handleGetTransactions returns transactions by their hashes from the memory pool or the blockchain
+func handleGetTransactions(s *Server, params json.RawMessage) (interface{}, error) {
+	var req GetTransactionsRequest
+	if err := parseParams(params, &req); err != nil {
+		return nil, err
+	}
+
+	res := GetTransactionsResponse{
+		TxsAsHex:  []string{},
+		MissedTxs: []string{},
+		Status:    StatusOK,
+	}
+
+	for _, txHash := range req.TxsHashes {
+		hash, err := parseHash(txHash)
+		if err != nil {
+			return nil, newError(ErrorCodeWrongParam, "Failed to parse hex representation of transaction hash. Hex = %s.", txHash)
+		}
+
+		transaction := s.TxPool.GetTransaction(&hash)
+		if transaction == nil {
+			transaction, _, err = s.Blockchain.GetTransaction(&hash)
+			if err == cryptonote.ErrStorageTransactionNotFound {
+				res.MissedTxs = append(res.MissedTxs, txHash)
+				continue
+			}
+
+			if err != nil {
+				return nil, err
+			}
+		}
+
+		res.TxsAsHex = append(res.TxsAsHex, hex.EncodeToString(transaction.Serialize()))
+	}
+
+	return res, nil
+}